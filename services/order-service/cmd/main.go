@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"order-service/internal/handler"
+	"os"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	// Try to load .env file for local development only
+	if _, err := os.Stat("../../.env"); err == nil {
+		err := godotenv.Load("../../.env")
+		if err != nil {
+			fmt.Printf("Error loading .env: %v\n", err)
+		}
+	}
+
+	h := handler.NewLambdaHandler()
+	lambda.Start(h.HandleRequest)
+}
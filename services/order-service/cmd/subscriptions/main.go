@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"order-service/internal/client"
+	"order-service/internal/repository"
+	"order-service/internal/service"
+	"os"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambda"
+	"github.com/joho/godotenv"
+)
+
+// subscriptions is the scheduled Lambda that turns due subscriptions into orders
+func main() {
+	// Try to load .env file for local development only
+	if _, err := os.Stat("../../.env"); err == nil {
+		err := godotenv.Load("../../.env")
+		if err != nil {
+			fmt.Printf("Error loading .env: %v\n", err)
+		}
+	}
+
+	repo, err := repository.NewPostgresRepository()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize database: %v", err))
+	}
+
+	products, err := client.NewHTTPProductClient()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize product client: %v", err))
+	}
+
+	orderService := service.NewOrderService(repo, products, service.DefaultPricingConfig())
+
+	lambda.Start(func(ctx context.Context) error {
+		runs, err := orderService.GenerateSubscriptionOrders(ctx, time.Now().UTC())
+		for _, run := range runs {
+			fmt.Printf("Subscription %s (%s): %s %s\n", run.SubscriptionID, run.ScheduledFor.Format("2006-01-02"), run.Status, run.Details)
+		}
+		return err
+	})
+}
@@ -6,7 +6,33 @@ require (
 	github.com/aws/aws-lambda-go v1.41.0
 	github.com/go-playground/validator/v10 v10.16.0
 	github.com/google/uuid v1.4.0
+	github.com/joho/godotenv v1.5.1
+	gorm.io/gorm v1.25.4
+	shared/apperr v0.0.0
+	shared/auth v0.0.0
 	shared/db v0.0.0
 )
 
-replace shared/db => ../../shared/db
\ No newline at end of file
+require (
+	github.com/gabriel-vasile/mimetype v1.4.2 // indirect
+	github.com/go-playground/locales v0.14.1 // indirect
+	github.com/go-playground/universal-translator v0.18.1 // indirect
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a // indirect
+	github.com/jackc/pgx/v5 v5.3.1 // indirect
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	github.com/leodido/go-urn v1.2.4 // indirect
+	github.com/lib/pq v1.10.9 // indirect
+	golang.org/x/crypto v0.8.0 // indirect
+	golang.org/x/net v0.9.0 // indirect
+	golang.org/x/sys v0.7.0 // indirect
+	golang.org/x/text v0.9.0 // indirect
+	gorm.io/driver/postgres v1.5.2 // indirect
+)
+
+replace shared/db => ../../shared/db
+
+replace shared/apperr => ../../shared/apperr
+
+replace shared/auth => ../../shared/auth
@@ -0,0 +1,165 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"shared/apperr"
+	"shared/auth"
+	"strings"
+	"time"
+)
+
+// Product is the subset of product-service's product that orders are priced from
+type Product struct {
+	ID            string   `json:"id"`
+	SKU           string   `json:"sku"`
+	Name          string   `json:"name"`
+	Price         float64  `json:"price"`
+	OriginalPrice *float64 `json:"original_price"`
+	IsOnSale      bool     `json:"is_on_sale"`
+	IsActive      bool     `json:"is_active"`
+	Available     int      `json:"available"`
+}
+
+// ProductClient is the order-service view of product-service
+type ProductClient interface {
+	GetProduct(ctx context.Context, id string) (*Product, error)
+	ReserveStock(ctx context.Context, productID string, orderID string, quantity int) (string, error)
+	ConfirmReservation(ctx context.Context, reservationID string) error
+	ReleaseReservation(ctx context.Context, reservationID string) error
+	ReturnStock(ctx context.Context, productID string, orderID string, quantity int) error
+}
+
+type HTTPProductClient struct {
+	baseURL    string
+	httpClient *http.Client
+	signer     *auth.Signer
+}
+
+// NewHTTPProductClient creates a client for the product-service at
+// PRODUCT_SERVICE_URL, calling it as order-service with the key in
+// SERVICE_SIGNING_KEY
+func NewHTTPProductClient() (*HTTPProductClient, error) {
+	baseURL := os.Getenv("PRODUCT_SERVICE_URL")
+	if baseURL == "" {
+		return nil, errors.New("PRODUCT_SERVICE_URL is not set")
+	}
+
+	signer, err := auth.NewServiceSignerFromEnv("order-service")
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize service credential: %w", err)
+	}
+
+	return &HTTPProductClient{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		signer:     signer,
+	}, nil
+}
+
+func (c *HTTPProductClient) GetProduct(ctx context.Context, id string) (*Product, error) {
+	var product Product
+	if err := c.do(ctx, http.MethodGet, "/products/"+id, nil, &product); err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return nil, apperr.NotFound("product %s not found", id)
+		}
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+func (c *HTTPProductClient) ReserveStock(ctx context.Context, productID string, orderID string, quantity int) (string, error) {
+	request := map[string]interface{}{
+		"product_id": productID,
+		"order_id":   orderID,
+		"quantity":   quantity,
+	}
+
+	var reservation struct {
+		ID string `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/reservations", request, &reservation); err != nil {
+		// Other 400s, like an invalid quantity, stay validation errors
+		switch {
+		case errors.Is(err, apperr.ErrNotFound):
+			return "", apperr.NotFound("product %s not found", productID)
+		case errors.Is(err, apperr.ErrInsufficientStock):
+			return "", apperr.InsufficientStock("product %s cannot reserve %d units", productID, quantity)
+		}
+		return "", err
+	}
+
+	return reservation.ID, nil
+}
+
+func (c *HTTPProductClient) ConfirmReservation(ctx context.Context, reservationID string) error {
+	return c.do(ctx, http.MethodPost, "/reservations/"+reservationID+"/confirm", nil, nil)
+}
+
+func (c *HTTPProductClient) ReleaseReservation(ctx context.Context, reservationID string) error {
+	return c.do(ctx, http.MethodPost, "/reservations/"+reservationID+"/release", nil, nil)
+}
+
+func (c *HTTPProductClient) ReturnStock(ctx context.Context, productID string, orderID string, quantity int) error {
+	request := map[string]interface{}{
+		"order_id": orderID,
+		"quantity": quantity,
+	}
+
+	return c.do(ctx, http.MethodPost, "/products/"+productID+"/returns", request, nil)
+}
+
+// do sends a JSON request to product-service and decodes a successful response
+// into out. Non-2xx responses are returned as the apperr errors their bodies
+// describe, so callers match them by code.
+func (c *HTTPProductClient) do(ctx context.Context, method string, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal product-service request: %w", err)
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to build product-service request: %w", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	// Calls are made as order-service, whether for a user or for the
+	// subscription generator, which has no user token to pass on
+	token, err := c.signer.SignService()
+	if err != nil {
+		return fmt.Errorf("failed to sign product-service request: %w", err)
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("product-service request failed: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		var errorBody apperr.Body
+		_ = json.NewDecoder(response.Body).Decode(&errorBody)
+		appErr := &apperr.Error{Code: errorBody.Code, Message: errorBody.Error, Fields: errorBody.Fields}
+		return fmt.Errorf("product-service %s %s returned %d: %w", method, path, response.StatusCode, appErr)
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(response.Body).Decode(out); err != nil {
+			return fmt.Errorf("failed to decode product-service response: %w", err)
+		}
+	}
+
+	return nil
+}
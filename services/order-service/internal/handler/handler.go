@@ -0,0 +1,389 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"order-service/internal/client"
+	"order-service/internal/models"
+	"order-service/internal/repository"
+	"order-service/internal/service"
+	"shared/apperr"
+	"shared/auth"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/go-playground/validator/v10"
+)
+
+type LambdaHandler struct {
+	orderService *service.OrderService
+	validator    *validator.Validate
+	verifier     *auth.Verifier
+}
+
+func NewLambdaHandler() *LambdaHandler {
+	repo, err := repository.NewPostgresRepository()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize database: %v", err))
+	}
+
+	products, err := client.NewHTTPProductClient()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize product client: %v", err))
+	}
+
+	verifier, err := auth.NewVerifierFromEnv()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize token verifier: %v", err))
+	}
+
+	orderService := service.NewOrderService(repo, products, service.DefaultPricingConfig())
+	validator := validator.New()
+
+	return &LambdaHandler{
+		orderService: orderService,
+		validator:    validator,
+		verifier:     verifier,
+	}
+}
+
+func (h *LambdaHandler) HandleRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Enable CORS
+	headers := map[string]string{
+		"Content-Type":                 "application/json",
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Methods": "GET, POST, PUT, DELETE, OPTIONS",
+		"Access-Control-Allow-Headers": "Content-Type, Authorization",
+	}
+
+	// Handle preflight OPTIONS request
+	if request.HTTPMethod == "OPTIONS" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusOK,
+			Headers:    headers,
+		}, nil
+	}
+
+	switch {
+	case request.HTTPMethod == "GET" && request.Path == "/orders":
+		return h.listOrders(ctx, request, headers)
+	case request.HTTPMethod == "POST" && request.Path == "/orders":
+		return h.createOrder(ctx, request, headers)
+	case request.HTTPMethod == "GET" && strings.HasPrefix(request.Path, "/orders/user/"):
+		return h.getUserOrders(ctx, request, headers)
+	case request.HTTPMethod == "GET" && strings.HasPrefix(request.Path, "/orders/"):
+		return h.getOrder(ctx, request, headers)
+	case request.HTTPMethod == "POST" && strings.HasPrefix(request.Path, "/orders/") && strings.HasSuffix(request.Path, "/cancel"):
+		return h.cancelOrder(ctx, request, headers)
+	case request.HTTPMethod == "PUT" && strings.HasPrefix(request.Path, "/orders/") && strings.HasSuffix(request.Path, "/status"):
+		return h.updateOrderStatus(ctx, request, headers)
+	case request.HTTPMethod == "PUT" && strings.HasPrefix(request.Path, "/orders/"):
+		return h.updateOrder(ctx, request, headers)
+	case request.HTTPMethod == "GET" && request.Path == "/subscriptions":
+		return h.listSubscriptions(ctx, request, headers)
+	case request.HTTPMethod == "POST" && request.Path == "/subscriptions":
+		return h.createSubscription(ctx, request, headers)
+	case request.HTTPMethod == "POST" && request.Path == "/subscriptions/generate":
+		return h.generateSubscriptionOrders(ctx, request, headers)
+	case request.HTTPMethod == "GET" && strings.HasPrefix(request.Path, "/subscriptions/") && strings.HasSuffix(request.Path, "/runs"):
+		return h.getSubscriptionRuns(ctx, request, headers)
+	case request.HTTPMethod == "GET" && strings.HasPrefix(request.Path, "/subscriptions/"):
+		return h.getSubscription(ctx, request, headers)
+	case request.HTTPMethod == "PUT" && strings.HasPrefix(request.Path, "/subscriptions/"):
+		return h.updateSubscription(ctx, request, headers)
+	case request.HTTPMethod == "POST" && strings.HasPrefix(request.Path, "/subscriptions/") && strings.HasSuffix(request.Path, "/pause"):
+		return h.pauseSubscription(ctx, request, headers)
+	case request.HTTPMethod == "POST" && strings.HasPrefix(request.Path, "/subscriptions/") && strings.HasSuffix(request.Path, "/resume"):
+		return h.resumeSubscription(ctx, request, headers)
+	case request.HTTPMethod == "POST" && strings.HasPrefix(request.Path, "/subscriptions/") && strings.HasSuffix(request.Path, "/skip"):
+		return h.skipSubscriptionRun(ctx, request, headers)
+	case request.HTTPMethod == "DELETE" && strings.HasPrefix(request.Path, "/subscriptions/"):
+		return h.cancelSubscription(ctx, request, headers)
+	default:
+		return h.errorResponse(ctx, apperr.NotFound("Route not found"), headers), nil
+	}
+}
+
+func (h *LambdaHandler) listOrders(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	claims, err := h.authenticate(request)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	// Customers list their own orders; staff may list anyone's
+	filter := parseOrderFilter(request)
+	filter.UserID = claims.Subject
+	if isStaff(claims) {
+		filter.UserID = request.QueryStringParameters["user_id"]
+	}
+
+	response, err := h.orderService.ListOrders(ctx, filter)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, response, headers), nil
+}
+
+func (h *LambdaHandler) getUserOrders(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	userID := extractUserIDFromPath(request.Path)
+	if userID == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("user_id", "User ID is required"), headers), nil
+	}
+
+	claims, err := h.authenticate(request)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+	if userID != claims.Subject && !isStaff(claims) {
+		return h.errorResponse(ctx, apperr.Forbidden("orders of other users cannot be listed"), headers), nil
+	}
+
+	response, err := h.orderService.GetUserOrders(ctx, userID, parseOrderFilter(request))
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, response, headers), nil
+}
+
+func (h *LambdaHandler) getOrder(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	id := extractIDFromPath(request.Path)
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Order ID is required"), headers), nil
+	}
+
+	claims, err := h.authenticate(request)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	order, err := h.callerOrder(ctx, claims, id)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, order, headers), nil
+}
+
+func (h *LambdaHandler) createOrder(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	// Orders are placed for the caller, never a body field
+	claims, err := h.authenticate(request)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	var createRequest models.CreateOrderRequest
+
+	if err := json.Unmarshal([]byte(request.Body), &createRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
+	}
+
+	if err := h.validator.Struct(&createRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation(fmt.Sprintf("Validation error: %s", err.Error())), headers), nil
+	}
+
+	order, err := h.orderService.CreateOrder(ctx, claims.Subject, &createRequest)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusCreated, order, headers), nil
+}
+
+func (h *LambdaHandler) updateOrder(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	id := extractIDFromPath(request.Path)
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Order ID is required"), headers), nil
+	}
+
+	claims, err := h.authenticate(request)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+	if _, err := h.callerOrder(ctx, claims, id); err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	var updateRequest models.UpdateOrderRequest
+
+	if err := json.Unmarshal([]byte(request.Body), &updateRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
+	}
+
+	if err := h.validator.Struct(&updateRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation(fmt.Sprintf("Validation error: %s", err.Error())), headers), nil
+	}
+
+	order, err := h.orderService.UpdateOrder(ctx, id, &updateRequest)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, order, headers), nil
+}
+
+func (h *LambdaHandler) updateOrderStatus(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	// Path format: /orders/{id}/status
+	id := extractIDFromPath(strings.TrimSuffix(request.Path, "/status"))
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Order ID is required"), headers), nil
+	}
+
+	// Orders are moved through fulfilment by staff; customers cancel theirs
+	// through cancelOrder
+	claims, err := h.authenticate(request)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+	if !isStaff(claims) {
+		return h.errorResponse(ctx, apperr.Forbidden("requires the %s or %s role", auth.RoleStaff, auth.RoleAdmin), headers), nil
+	}
+
+	var statusRequest models.UpdateOrderStatusRequest
+
+	if err := json.Unmarshal([]byte(request.Body), &statusRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
+	}
+
+	if err := h.validator.Struct(&statusRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation(fmt.Sprintf("Validation error: %s", err.Error())), headers), nil
+	}
+
+	order, err := h.orderService.UpdateOrderStatus(ctx, id, claims.Subject, &statusRequest)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, order, headers), nil
+}
+
+func (h *LambdaHandler) cancelOrder(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	// Path format: /orders/{id}/cancel
+	id := extractIDFromPath(strings.TrimSuffix(request.Path, "/cancel"))
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Order ID is required"), headers), nil
+	}
+
+	claims, err := h.authenticate(request)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+	if _, err := h.callerOrder(ctx, claims, id); err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	// The body is optional; it only carries why the order was cancelled
+	var cancelRequest models.CancelOrderRequest
+
+	if request.Body != "" {
+		if err := json.Unmarshal([]byte(request.Body), &cancelRequest); err != nil {
+			return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
+		}
+
+		if err := h.validator.Struct(&cancelRequest); err != nil {
+			return h.errorResponse(ctx, apperr.Validation(fmt.Sprintf("Validation error: %s", err.Error())), headers), nil
+		}
+	}
+
+	order, err := h.orderService.CancelOrder(ctx, id, claims.Subject, &cancelRequest)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, order, headers), nil
+}
+
+// authenticate returns the claims of the user whose bearer token the request
+// carries
+func (h *LambdaHandler) authenticate(request events.APIGatewayProxyRequest) (*auth.Claims, error) {
+	claims, err := h.verifier.Verify(auth.BearerToken(request.Headers))
+	if err != nil {
+		return nil, apperr.Unauthorized("%v", err)
+	}
+	return claims, nil
+}
+
+// callerOrder returns the order id names if the caller placed it or is staff.
+// Other callers are told it does not exist, so order IDs cannot be probed.
+func (h *LambdaHandler) callerOrder(ctx context.Context, claims *auth.Claims, id string) (*models.Order, error) {
+	order, err := h.orderService.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if order.UserID != claims.Subject && !isStaff(claims) {
+		return nil, apperr.NotFound("order not found")
+	}
+	return order, nil
+}
+
+// isStaff reports whether the caller may act on every user's orders
+func isStaff(claims *auth.Claims) bool {
+	return claims.HasRole(auth.RoleStaff, auth.RoleAdmin)
+}
+
+func (h *LambdaHandler) successResponse(statusCode int, data interface{}, headers map[string]string) events.APIGatewayProxyResponse {
+	var body string
+	if data != nil {
+		bodyBytes, _ := json.Marshal(data)
+		body = string(bodyBytes)
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers:    headers,
+		Body:       body,
+	}
+}
+
+// errorResponse answers with the status and JSON error body apperr maps err to
+func (h *LambdaHandler) errorResponse(ctx context.Context, err error, headers map[string]string) events.APIGatewayProxyResponse {
+	statusCode, errorBody := apperr.HTTPResponse(apperr.FromContext(ctx, err))
+	bodyBytes, _ := json.Marshal(errorBody)
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers:    headers,
+		Body:       string(bodyBytes),
+	}
+}
+
+func parseOrderFilter(request events.APIGatewayProxyRequest) models.OrderFilter {
+	filter := models.OrderFilter{}
+
+	if status := request.QueryStringParameters["status"]; status != "" {
+		filter.Status = status
+	}
+	if limitStr := request.QueryStringParameters["limit"]; limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil {
+			filter.Limit = limit
+		}
+	}
+	if offsetStr := request.QueryStringParameters["offset"]; offsetStr != "" {
+		if offset, err := strconv.Atoi(offsetStr); err == nil {
+			filter.Offset = offset
+		}
+	}
+
+	return filter
+}
+
+func extractIDFromPath(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) >= 2 {
+		return parts[1]
+	}
+	return ""
+}
+
+func extractUserIDFromPath(path string) string {
+	// Path format: /orders/user/{userID}
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) >= 3 && parts[1] == "user" {
+		return parts[2]
+	}
+	return ""
+}
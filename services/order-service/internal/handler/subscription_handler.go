@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"order-service/internal/models"
+	"shared/apperr"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func (h *LambdaHandler) listSubscriptions(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	filter := models.SubscriptionFilter{}
+
+	if userID := request.QueryStringParameters["user_id"]; userID != "" {
+		filter.UserID = userID
+	}
+	if status := request.QueryStringParameters["status"]; status != "" {
+		filter.Status = status
+	}
+	if limitStr := request.QueryStringParameters["limit"]; limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil {
+			filter.Limit = limit
+		}
+	}
+	if offsetStr := request.QueryStringParameters["offset"]; offsetStr != "" {
+		if offset, err := strconv.Atoi(offsetStr); err == nil {
+			filter.Offset = offset
+		}
+	}
+
+	response, err := h.orderService.ListSubscriptions(ctx, filter)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, response, headers), nil
+}
+
+func (h *LambdaHandler) getSubscription(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	id := extractIDFromPath(request.Path)
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Subscription ID is required"), headers), nil
+	}
+
+	subscription, err := h.orderService.GetSubscription(ctx, id)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, subscription, headers), nil
+}
+
+func (h *LambdaHandler) getSubscriptionRuns(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	// Path format: /subscriptions/{id}/runs
+	id := extractIDFromPath(strings.TrimSuffix(request.Path, "/runs"))
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Subscription ID is required"), headers), nil
+	}
+
+	runs, err := h.orderService.GetSubscriptionRuns(ctx, id)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, runs, headers), nil
+}
+
+func (h *LambdaHandler) createSubscription(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	var createRequest models.CreateSubscriptionRequest
+
+	if err := json.Unmarshal([]byte(request.Body), &createRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
+	}
+
+	if err := h.validator.Struct(&createRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation(fmt.Sprintf("Validation error: %s", err.Error())), headers), nil
+	}
+
+	subscription, err := h.orderService.CreateSubscription(ctx, &createRequest)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusCreated, subscription, headers), nil
+}
+
+func (h *LambdaHandler) updateSubscription(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	id := extractIDFromPath(request.Path)
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Subscription ID is required"), headers), nil
+	}
+
+	var updateRequest models.UpdateSubscriptionRequest
+
+	if err := json.Unmarshal([]byte(request.Body), &updateRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
+	}
+
+	if err := h.validator.Struct(&updateRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation(fmt.Sprintf("Validation error: %s", err.Error())), headers), nil
+	}
+
+	subscription, err := h.orderService.UpdateSubscription(ctx, id, &updateRequest)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, subscription, headers), nil
+}
+
+func (h *LambdaHandler) pauseSubscription(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	// Path format: /subscriptions/{id}/pause
+	id := extractIDFromPath(strings.TrimSuffix(request.Path, "/pause"))
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Subscription ID is required"), headers), nil
+	}
+
+	subscription, err := h.orderService.PauseSubscription(ctx, id)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, subscription, headers), nil
+}
+
+func (h *LambdaHandler) resumeSubscription(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	// Path format: /subscriptions/{id}/resume
+	id := extractIDFromPath(strings.TrimSuffix(request.Path, "/resume"))
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Subscription ID is required"), headers), nil
+	}
+
+	subscription, err := h.orderService.ResumeSubscription(ctx, id)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, subscription, headers), nil
+}
+
+func (h *LambdaHandler) skipSubscriptionRun(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	// Path format: /subscriptions/{id}/skip
+	id := extractIDFromPath(strings.TrimSuffix(request.Path, "/skip"))
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Subscription ID is required"), headers), nil
+	}
+
+	subscription, err := h.orderService.SkipNextSubscriptionRun(ctx, id)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, subscription, headers), nil
+}
+
+func (h *LambdaHandler) cancelSubscription(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	id := extractIDFromPath(request.Path)
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Subscription ID is required"), headers), nil
+	}
+
+	err := h.orderService.CancelSubscription(ctx, id)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusNoContent, nil, headers), nil
+}
+
+func (h *LambdaHandler) generateSubscriptionOrders(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	runs, err := h.orderService.GenerateSubscriptionOrders(ctx, time.Now().UTC())
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, map[string]interface{}{"runs": runs}, headers), nil
+}
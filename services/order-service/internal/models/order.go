@@ -0,0 +1,120 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	OrderStatusPending    = "pending"
+	OrderStatusConfirmed  = "confirmed"
+	OrderStatusProcessing = "processing"
+	OrderStatusShipped    = "shipped"
+	OrderStatusDelivered  = "delivered"
+	OrderStatusCancelled  = "cancelled"
+)
+
+const (
+	PaymentStatusPending  = "pending"
+	PaymentStatusPaid     = "paid"
+	PaymentStatusFailed   = "failed"
+	PaymentStatusRefunded = "refunded"
+)
+
+type Order struct {
+	ID                string               `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID            string               `json:"user_id" gorm:"type:uuid;not null;index"`
+	OrderNumber       string               `json:"order_number" gorm:"type:varchar(50);uniqueIndex;not null"`
+	Status            string               `json:"status" gorm:"type:varchar(20);not null;index;default:pending"`
+	TotalAmount       float64              `json:"total_amount" gorm:"type:decimal(10,2);not null"`
+	TaxAmount         float64              `json:"tax_amount" gorm:"type:decimal(10,2);default:0"`
+	ShippingAmount    float64              `json:"shipping_amount" gorm:"type:decimal(10,2);default:0"`
+	DiscountAmount    float64              `json:"discount_amount" gorm:"type:decimal(10,2);default:0"`
+	ShippingAddressID *string              `json:"shipping_address_id" gorm:"type:uuid"`
+	BillingAddressID  *string              `json:"billing_address_id" gorm:"type:uuid"`
+	PaymentStatus     string               `json:"payment_status" gorm:"type:varchar(20);default:pending"`
+	PaymentMethod     string               `json:"payment_method" gorm:"type:varchar(50)"`
+	Notes             string               `json:"notes" gorm:"type:text"`
+	SubscriptionID    *string              `json:"subscription_id,omitempty" gorm:"type:uuid;index"`
+	Items             []OrderItem          `json:"items" gorm:"foreignKey:OrderID"`
+	History           []OrderStatusHistory `json:"history,omitempty" gorm:"foreignKey:OrderID"`
+	CreatedAt         time.Time            `json:"created_at" gorm:"type:timestamp;autoCreateTime;index"`
+	UpdatedAt         time.Time            `json:"updated_at" gorm:"type:timestamp;autoUpdateTime"`
+}
+
+// OrderItem is a line of an order. UnitPrice is the product's list price when
+// the order was placed; sale savings are carried in the order's DiscountAmount.
+type OrderItem struct {
+	ID          string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	OrderID     string    `json:"order_id" gorm:"type:uuid;not null;index"`
+	ProductID   string    `json:"product_id" gorm:"type:uuid;not null;index"`
+	SKU         string    `json:"sku" gorm:"type:varchar(100)"`
+	ProductName string    `json:"product_name" gorm:"type:varchar(255)"`
+	Quantity    int       `json:"quantity" gorm:"type:integer;not null"`
+	UnitPrice   float64   `json:"unit_price" gorm:"type:decimal(10,2);not null"`
+	TotalPrice  float64   `json:"total_price" gorm:"type:decimal(10,2);not null"`
+	CreatedAt   time.Time `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+}
+
+// OrderStatusHistory records a single status transition of an order.
+// FromStatus is empty for the entry written when the order is placed.
+type OrderStatusHistory struct {
+	ID         string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	OrderID    string    `json:"order_id" gorm:"type:uuid;not null;index"`
+	FromStatus string    `json:"from_status" gorm:"type:varchar(20)"`
+	ToStatus   string    `json:"to_status" gorm:"type:varchar(20);not null"`
+	Actor      string    `json:"actor" gorm:"type:varchar(100)"`
+	Reason     string    `json:"reason" gorm:"type:text"`
+	CreatedAt  time.Time `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+}
+
+func (OrderStatusHistory) TableName() string {
+	return "order_status_history"
+}
+
+type OrderFilter struct {
+	UserID string `json:"user_id"`
+	Status string `json:"status"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+type OrderListResponse struct {
+	Orders     []Order `json:"orders"`
+	TotalCount int     `json:"total_count"`
+	Limit      int     `json:"limit"`
+	Offset     int     `json:"offset"`
+}
+
+type OrderItemRequest struct {
+	ProductID string `json:"product_id" validate:"required"`
+	Quantity  int    `json:"quantity" validate:"required,min=1,max=1000"`
+}
+
+// CreateOrderRequest places an order for the authenticated caller
+type CreateOrderRequest struct {
+	Items             []OrderItemRequest `json:"items" validate:"required,min=1,max=100,dive"`
+	ShippingAddressID *string            `json:"shipping_address_id" validate:"omitempty,uuid"`
+	BillingAddressID  *string            `json:"billing_address_id" validate:"omitempty,uuid"`
+	PaymentMethod     string             `json:"payment_method" validate:"omitempty,max=50"`
+	Notes             string             `json:"notes" validate:"omitempty,max=1000"`
+}
+
+type UpdateOrderRequest struct {
+	ShippingAddressID *string `json:"shipping_address_id,omitempty" validate:"omitempty,uuid"`
+	BillingAddressID  *string `json:"billing_address_id,omitempty" validate:"omitempty,uuid"`
+	PaymentStatus     *string `json:"payment_status,omitempty" validate:"omitempty,oneof=pending paid failed refunded"`
+	PaymentMethod     *string `json:"payment_method,omitempty" validate:"omitempty,max=50"`
+	Notes             *string `json:"notes,omitempty" validate:"omitempty,max=1000"`
+}
+
+// UpdateOrderStatusRequest moves an order to Status. The transition is
+// recorded with the authenticated caller as its actor.
+type UpdateOrderStatusRequest struct {
+	Status string `json:"status" validate:"required,oneof=pending confirmed processing shipped delivered cancelled"`
+	Reason string `json:"reason" validate:"omitempty,max=500"`
+}
+
+// CancelOrderRequest cancels an order on behalf of the authenticated caller
+type CancelOrderRequest struct {
+	Reason string `json:"reason" validate:"omitempty,max=500"`
+}
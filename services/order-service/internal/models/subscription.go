@@ -0,0 +1,124 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	SubscriptionStatusActive    = "active"
+	SubscriptionStatusPaused    = "paused"
+	SubscriptionStatusCancelled = "cancelled"
+)
+
+const (
+	CadenceWeekly   = "weekly"
+	CadenceBiweekly = "biweekly"
+	CadenceMonthly  = "monthly"
+	CadenceWeekday  = "weekday"
+)
+
+const (
+	SubscriptionRunOrdered = "ordered"
+	SubscriptionRunSkipped = "skipped"
+	SubscriptionRunFailed  = "failed"
+)
+
+const (
+	AdjustmentOutOfStock        = "out_of_stock"
+	AdjustmentPartialStock      = "partial_stock"
+	AdjustmentUnavailable       = "unavailable"
+	AdjustmentPriceChanged      = "price_changed"
+	AdjustmentPriceLimitReached = "price_limit_reached"
+)
+
+// Subscription is a recurring basket that the generator turns into an order
+// every time NextRunAt comes due. With the weekday cadence, Weekday (0 is
+// Sunday) picks the delivery day; the other cadences repeat from StartDate.
+type Subscription struct {
+	ID                string             `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID            string             `json:"user_id" gorm:"type:uuid;not null;index"`
+	Name              string             `json:"name" gorm:"type:varchar(100)"`
+	Status            string             `json:"status" gorm:"type:varchar(20);not null;index;default:active"`
+	Cadence           string             `json:"cadence" gorm:"type:varchar(20);not null"`
+	Weekday           *int               `json:"weekday" gorm:"type:integer"`
+	StartDate         time.Time          `json:"start_date" gorm:"type:timestamp;not null"`
+	NextRunAt         time.Time          `json:"next_run_at" gorm:"type:timestamp;not null;index"`
+	ShippingAddressID string             `json:"shipping_address_id" gorm:"type:uuid;not null"`
+	PaymentMethod     string             `json:"payment_method" gorm:"type:varchar(50)"`
+	MaxPriceIncrease  *float64           `json:"max_price_increase" gorm:"type:decimal(5,2)"` // percent over the subscribed price
+	LastOrderID       *string            `json:"last_order_id" gorm:"type:uuid"`
+	Items             []SubscriptionItem `json:"items" gorm:"foreignKey:SubscriptionID"`
+	CreatedAt         time.Time          `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+	UpdatedAt         time.Time          `json:"updated_at" gorm:"type:timestamp;autoUpdateTime"`
+}
+
+// SubscriptionItem is a product in a subscription basket. UnitPrice is the
+// price when the product was subscribed, used to detect price changes.
+type SubscriptionItem struct {
+	ID             string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	SubscriptionID string    `json:"subscription_id" gorm:"type:uuid;not null;index"`
+	ProductID      string    `json:"product_id" gorm:"type:uuid;not null"`
+	Quantity       int       `json:"quantity" gorm:"type:integer;not null"`
+	UnitPrice      float64   `json:"unit_price" gorm:"type:decimal(10,2);not null"`
+	CreatedAt      time.Time `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+}
+
+// SubscriptionRun records one scheduled occurrence of a subscription and
+// what the generator had to change in the basket to place it
+type SubscriptionRun struct {
+	ID             string                   `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	SubscriptionID string                   `json:"subscription_id" gorm:"type:uuid;not null;index"`
+	ScheduledFor   time.Time                `json:"scheduled_for" gorm:"type:timestamp;not null"`
+	Status         string                   `json:"status" gorm:"type:varchar(20);not null"`
+	OrderID        *string                  `json:"order_id" gorm:"type:uuid"`
+	Adjustments    []SubscriptionAdjustment `json:"adjustments" gorm:"type:jsonb;serializer:json"`
+	Details        string                   `json:"details,omitempty" gorm:"type:text"` // why the run was skipped or failed
+	CreatedAt      time.Time                `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+}
+
+// SubscriptionAdjustment describes how a basket line differed from what was
+// subscribed when its order was generated
+type SubscriptionAdjustment struct {
+	ProductID         string  `json:"product_id"`
+	Type              string  `json:"type"`
+	RequestedQuantity int     `json:"requested_quantity"`
+	Quantity          int     `json:"quantity"`
+	SubscribedPrice   float64 `json:"subscribed_price,omitempty"`
+	CurrentPrice      float64 `json:"current_price,omitempty"`
+}
+
+type SubscriptionFilter struct {
+	UserID string `json:"user_id"`
+	Status string `json:"status"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+}
+
+type SubscriptionListResponse struct {
+	Subscriptions []Subscription `json:"subscriptions"`
+	TotalCount    int            `json:"total_count"`
+	Limit         int            `json:"limit"`
+	Offset        int            `json:"offset"`
+}
+
+type CreateSubscriptionRequest struct {
+	UserID            string             `json:"user_id" validate:"required,uuid"`
+	Name              string             `json:"name" validate:"omitempty,max=100"`
+	Items             []OrderItemRequest `json:"items" validate:"required,min=1,max=100,dive"`
+	Cadence           string             `json:"cadence" validate:"required,oneof=weekly biweekly monthly weekday"`
+	Weekday           *int               `json:"weekday" validate:"omitempty,min=0,max=6"`
+	StartDate         *time.Time         `json:"start_date"`
+	ShippingAddressID string             `json:"shipping_address_id" validate:"required,uuid"`
+	PaymentMethod     string             `json:"payment_method" validate:"omitempty,max=50"`
+	MaxPriceIncrease  *float64           `json:"max_price_increase" validate:"omitempty,min=0,max=100"`
+}
+
+type UpdateSubscriptionRequest struct {
+	Name              *string            `json:"name,omitempty" validate:"omitempty,max=100"`
+	Items             []OrderItemRequest `json:"items,omitempty" validate:"omitempty,min=1,max=100,dive"`
+	Cadence           *string            `json:"cadence,omitempty" validate:"omitempty,oneof=weekly biweekly monthly weekday"`
+	Weekday           *int               `json:"weekday,omitempty" validate:"omitempty,min=0,max=6"`
+	ShippingAddressID *string            `json:"shipping_address_id,omitempty" validate:"omitempty,uuid"`
+	PaymentMethod     *string            `json:"payment_method,omitempty" validate:"omitempty,max=50"`
+	MaxPriceIncrease  *float64           `json:"max_price_increase,omitempty" validate:"omitempty,min=0,max=100"`
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// orderNumberAlphabet leaves out characters that are easy to misread over the
+// phone or in a chat (0/O, 1/I/L)
+const orderNumberAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+const orderNumberSuffixLength = 6
+
+// generateOrderNumber returns a customer-facing number such as
+// ORD-20240131-7KQ2XM. Uniqueness is enforced by the orders table.
+func generateOrderNumber(now time.Time) (string, error) {
+	suffix := make([]byte, orderNumberSuffixLength)
+	max := big.NewInt(int64(len(orderNumberAlphabet)))
+
+	for i := range suffix {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate order number: %w", err)
+		}
+		suffix[i] = orderNumberAlphabet[n.Int64()]
+	}
+
+	return fmt.Sprintf("ORD-%s-%s", now.Format("20060102"), suffix), nil
+}
@@ -0,0 +1,296 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"order-service/internal/models"
+	"order-service/schema"
+	"shared/apperr"
+	"shared/db"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxOrderNumberAttempts bounds retries when a generated order number collides
+const maxOrderNumberAttempts = 5
+
+// orderNumberConstraints are the unique constraints on order_number, as the
+// migrations and GORM name them
+var orderNumberConstraints = []string{"orders_order_number_key", "idx_orders_order_number"}
+
+type OrderRepository interface {
+	CreateOrder(ctx context.Context, order *models.Order) error
+	GetOrder(ctx context.Context, id string) (*models.Order, error)
+	ListOrders(ctx context.Context, filter models.OrderFilter) (*models.OrderListResponse, error)
+	UpdateOrder(ctx context.Context, id string, updates *models.UpdateOrderRequest) (*models.Order, error)
+	TransitionOrderStatus(ctx context.Context, id string, transition *models.OrderStatusHistory, beforeCommit func(order *models.Order) error) (*models.Order, error)
+
+	// Subscription operations
+	CreateSubscription(ctx context.Context, subscription *models.Subscription) error
+	GetSubscription(ctx context.Context, id string) (*models.Subscription, error)
+	ListSubscriptions(ctx context.Context, filter models.SubscriptionFilter) (*models.SubscriptionListResponse, error)
+	SaveSubscription(ctx context.Context, subscription *models.Subscription, replaceItems bool) error
+	ListDueSubscriptions(ctx context.Context, now time.Time, limit int) ([]models.Subscription, error)
+	ClaimSubscriptionRun(ctx context.Context, id string, scheduledFor time.Time, nextRunAt time.Time) (bool, error)
+	RecordSubscriptionRun(ctx context.Context, run *models.SubscriptionRun) error
+	ListSubscriptionRuns(ctx context.Context, subscriptionID string, limit int) ([]models.SubscriptionRun, error)
+	AddressBelongsToUser(ctx context.Context, addressID string, userID string) (bool, error)
+}
+
+type PostgresRepository struct {
+	*db.BaseRepository
+	subscriptions    *db.Repository[models.Subscription]
+	subscriptionRuns *db.Repository[models.SubscriptionRun]
+}
+
+func NewPostgresRepository() (*PostgresRepository, error) {
+	database, err := db.NewPostgresConnectionFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	// Migrate or verify the schema, as DB_SCHEMA_MODE says
+	err = db.PrepareSchema(database, schema.Models()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare database schema: %w", err)
+	}
+
+	return &PostgresRepository{
+		BaseRepository:   db.NewBaseRepository(database),
+		subscriptions:    db.NewRepository[models.Subscription](database),
+		subscriptionRuns: db.NewRepository[models.SubscriptionRun](database),
+	}, nil
+}
+
+func (r *PostgresRepository) CreateOrder(ctx context.Context, order *models.Order) error {
+	if order.ID == "" {
+		order.ID = uuid.New().String()
+	}
+	if order.Status == "" {
+		order.Status = models.OrderStatusPending
+	}
+	if order.PaymentStatus == "" {
+		order.PaymentStatus = models.PaymentStatusPending
+	}
+	for i := range order.Items {
+		order.Items[i].ID = uuid.New().String()
+		order.Items[i].OrderID = order.ID
+	}
+	for i := range order.History {
+		order.History[i].ID = uuid.New().String()
+		order.History[i].OrderID = order.ID
+	}
+
+	// The unique index on order_number is the final arbiter; a collision just
+	// draws a new number
+	for attempt := 1; ; attempt++ {
+		number, err := generateOrderNumber(time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		order.OrderNumber = number
+
+		err = r.Transaction(ctx, func(tx *gorm.DB) error {
+			if err := checkOrderAddresses(tx, order); err != nil {
+				return err
+			}
+			return tx.Create(order).Error
+		})
+		if err == nil {
+			return nil
+		}
+
+		if !db.IsUniqueViolation(err, orderNumberConstraints...) || attempt == maxOrderNumberAttempts {
+			return fmt.Errorf("failed to create order: %w", err)
+		}
+	}
+}
+
+// checkOrderAddresses re-checks that the order's addresses are still active
+// under the lock user-service takes on the user's row to change their address
+// book. An address removed since the service checked it fails the order, and
+// one removed afterwards sees the order and stays in use.
+func checkOrderAddresses(tx *gorm.DB, order *models.Order) error {
+	if order.ShippingAddressID == nil && order.BillingAddressID == nil {
+		return nil
+	}
+
+	var locked []string
+	result := tx.Table("users").
+		Clauses(clause.Locking{Strength: "SHARE"}).
+		Where("id = ?", order.UserID).
+		Pluck("id", &locked)
+	if result.Error != nil {
+		return fmt.Errorf("failed to lock address book: %w", result.Error)
+	}
+
+	addresses := []struct {
+		field string
+		id    *string
+	}{
+		{"shipping_address_id", order.ShippingAddressID},
+		{"billing_address_id", order.BillingAddressID},
+	}
+	for _, address := range addresses {
+		if address.id == nil {
+			continue
+		}
+		var count int64
+		result := tx.Table("user_addresses").
+			Where("id = ? AND user_id = ? AND is_active = ?", *address.id, order.UserID, true).
+			Count(&count)
+		if result.Error != nil {
+			return fmt.Errorf("failed to check address: %w", result.Error)
+		}
+		if count == 0 {
+			return apperr.InvalidField(address.field, fmt.Sprintf("address %s is not in the user's address book", *address.id))
+		}
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) GetOrder(ctx context.Context, id string) (*models.Order, error) {
+	var order models.Order
+	result := r.DB.WithContext(ctx).Preload("Items").
+		Preload("History", func(query *gorm.DB) *gorm.DB {
+			return query.Order("created_at, id")
+		}).
+		Where("id = ?", id).
+		First(&order)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, apperr.NotFound("order not found")
+	}
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get order: %w", result.Error)
+	}
+
+	return &order, nil
+}
+
+func (r *PostgresRepository) ListOrders(ctx context.Context, filter models.OrderFilter) (*models.OrderListResponse, error) {
+	query := r.DB.WithContext(ctx).Model(&models.Order{})
+
+	// Apply filters
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	// Get total count
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count orders: %w", err)
+	}
+
+	// Apply pagination
+	var orders []models.Order
+	result := query.Preload("Items").
+		Order("created_at DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&orders)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list orders: %w", result.Error)
+	}
+
+	return &models.OrderListResponse{
+		Orders:     orders,
+		TotalCount: int(totalCount),
+		Limit:      filter.Limit,
+		Offset:     filter.Offset,
+	}, nil
+}
+
+func (r *PostgresRepository) UpdateOrder(ctx context.Context, id string, updates *models.UpdateOrderRequest) (*models.Order, error) {
+	// Apply updates
+	updateFields := make(map[string]interface{})
+
+	if updates.ShippingAddressID != nil {
+		updateFields["shipping_address_id"] = *updates.ShippingAddressID
+	}
+	if updates.BillingAddressID != nil {
+		updateFields["billing_address_id"] = *updates.BillingAddressID
+	}
+	if updates.PaymentStatus != nil {
+		updateFields["payment_status"] = *updates.PaymentStatus
+	}
+	if updates.PaymentMethod != nil {
+		updateFields["payment_method"] = *updates.PaymentMethod
+	}
+	if updates.Notes != nil {
+		updateFields["notes"] = *updates.Notes
+	}
+
+	if len(updateFields) == 0 {
+		return nil, apperr.Validation("no fields to update")
+	}
+
+	if err := r.Update(ctx, &models.Order{ID: id}, updateFields); err != nil {
+		if errors.Is(err, apperr.ErrNotFound) {
+			return nil, apperr.NotFound("order not found")
+		}
+		return nil, fmt.Errorf("failed to update order: %w", err)
+	}
+
+	return r.GetOrder(ctx, id)
+}
+
+// TransitionOrderStatus moves the order from transition.FromStatus to
+// transition.ToStatus and records the transition, failing with
+// a conflict if the order is no longer in FromStatus. A non-nil
+// beforeCommit runs while the order is still locked by the change; if it
+// fails, the change is rolled back.
+func (r *PostgresRepository) TransitionOrderStatus(ctx context.Context, id string, transition *models.OrderStatusHistory, beforeCommit func(order *models.Order) error) (*models.Order, error) {
+	transition.ID = uuid.New().String()
+	transition.OrderID = id
+
+	err := r.Transaction(ctx, func(tx *gorm.DB) error {
+		result := tx.Model(&models.Order{}).
+			Where("id = ? AND status = ?", id, transition.FromStatus).
+			Update("status", transition.ToStatus)
+		if result.Error != nil {
+			return fmt.Errorf("failed to update order status: %w", result.Error)
+		}
+
+		if result.RowsAffected == 0 {
+			var count int64
+			if err := tx.Model(&models.Order{}).Where("id = ?", id).Count(&count).Error; err != nil {
+				return fmt.Errorf("failed to update order status: %w", err)
+			}
+			if count == 0 {
+				return apperr.NotFound("order not found")
+			}
+			return apperr.Conflict("order %s is no longer %s", id, transition.FromStatus)
+		}
+
+		if err := tx.Create(transition).Error; err != nil {
+			return fmt.Errorf("failed to record order status history: %w", err)
+		}
+
+		if beforeCommit == nil {
+			return nil
+		}
+
+		var order models.Order
+		if err := tx.Preload("Items").Where("id = ?", id).First(&order).Error; err != nil {
+			return fmt.Errorf("failed to get order: %w", err)
+		}
+		return beforeCommit(&order)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.GetOrder(ctx, id)
+}
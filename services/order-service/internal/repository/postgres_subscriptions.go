@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"order-service/internal/models"
+	"shared/apperr"
+	"shared/db"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (r *PostgresRepository) CreateSubscription(ctx context.Context, subscription *models.Subscription) error {
+	subscription.ID = uuid.New().String()
+	for i := range subscription.Items {
+		subscription.Items[i].ID = uuid.New().String()
+		subscription.Items[i].SubscriptionID = subscription.ID
+	}
+
+	err := r.Transaction(ctx, func(tx *gorm.DB) error {
+		return tx.Create(subscription).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create subscription: %w", err)
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) GetSubscription(ctx context.Context, id string) (*models.Subscription, error) {
+	var subscription models.Subscription
+	result := r.DB.WithContext(ctx).Preload("Items").Where("id = ?", id).First(&subscription)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, apperr.NotFound("subscription not found")
+	}
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get subscription: %w", result.Error)
+	}
+
+	return &subscription, nil
+}
+
+func (r *PostgresRepository) ListSubscriptions(ctx context.Context, filter models.SubscriptionFilter) (*models.SubscriptionListResponse, error) {
+	spec := db.NewSpec()
+
+	// Apply filters
+	if filter.UserID != "" {
+		spec = spec.Where(db.Eq("user_id", filter.UserID))
+	}
+
+	if filter.Status != "" {
+		spec = spec.Where(db.Eq("status", filter.Status))
+	}
+
+	spec = spec.Preload("Items").
+		OrderBy(db.Desc("created_at")).
+		Page(filter.Limit, filter.Offset)
+
+	subscriptions, totalCount, err := r.subscriptions.ListPage(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SubscriptionListResponse{
+		Subscriptions: subscriptions,
+		TotalCount:    int(totalCount),
+		Limit:         filter.Limit,
+		Offset:        filter.Offset,
+	}, nil
+}
+
+// SaveSubscription writes every field of the subscription. With replaceItems
+// the stored basket is replaced by subscription.Items.
+func (r *PostgresRepository) SaveSubscription(ctx context.Context, subscription *models.Subscription, replaceItems bool) error {
+	return r.Transaction(ctx, func(tx *gorm.DB) error {
+		result := tx.Omit("Items").Save(subscription)
+		if result.Error != nil {
+			return fmt.Errorf("failed to update subscription: %w", result.Error)
+		}
+
+		if !replaceItems {
+			return nil
+		}
+
+		if err := tx.Where("subscription_id = ?", subscription.ID).Delete(&models.SubscriptionItem{}).Error; err != nil {
+			return fmt.Errorf("failed to replace subscription items: %w", err)
+		}
+
+		for i := range subscription.Items {
+			subscription.Items[i].ID = uuid.New().String()
+			subscription.Items[i].SubscriptionID = subscription.ID
+		}
+
+		if err := tx.Create(&subscription.Items).Error; err != nil {
+			return fmt.Errorf("failed to replace subscription items: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (r *PostgresRepository) ListDueSubscriptions(ctx context.Context, now time.Time, limit int) ([]models.Subscription, error) {
+	var subscriptions []models.Subscription
+
+	result := r.DB.WithContext(ctx).Preload("Items").
+		Where("status = ? AND next_run_at <= ?", models.SubscriptionStatusActive, now).
+		Order("next_run_at").
+		Limit(limit).
+		Find(&subscriptions)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list due subscriptions: %w", result.Error)
+	}
+
+	return subscriptions, nil
+}
+
+// ClaimSubscriptionRun advances a subscription past the occurrence scheduled
+// for scheduledFor. Only one caller can claim an occurrence, so concurrent
+// generators never order it twice.
+func (r *PostgresRepository) ClaimSubscriptionRun(ctx context.Context, id string, scheduledFor time.Time, nextRunAt time.Time) (bool, error) {
+	result := r.DB.WithContext(ctx).Model(&models.Subscription{}).
+		Where("id = ? AND status = ? AND next_run_at = ?", id, models.SubscriptionStatusActive, scheduledFor).
+		Update("next_run_at", nextRunAt)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to claim subscription run: %w", result.Error)
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+func (r *PostgresRepository) RecordSubscriptionRun(ctx context.Context, run *models.SubscriptionRun) error {
+	run.ID = uuid.New().String()
+
+	return r.Transaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.Create(run).Error; err != nil {
+			return fmt.Errorf("failed to record subscription run: %w", err)
+		}
+
+		if run.OrderID == nil {
+			return nil
+		}
+
+		result := tx.Model(&models.Subscription{}).
+			Where("id = ?", run.SubscriptionID).
+			Update("last_order_id", *run.OrderID)
+		if result.Error != nil {
+			return fmt.Errorf("failed to record subscription run: %w", result.Error)
+		}
+
+		return nil
+	})
+}
+
+func (r *PostgresRepository) ListSubscriptionRuns(ctx context.Context, subscriptionID string, limit int) ([]models.SubscriptionRun, error) {
+	return r.subscriptionRuns.List(ctx, db.NewSpec().
+		Where(db.Eq("subscription_id", subscriptionID)).
+		OrderBy(db.Desc("scheduled_for")).
+		Page(limit, 0))
+}
+
+// AddressBelongsToUser checks the address book that user-service keeps in the
+// shared database
+func (r *PostgresRepository) AddressBelongsToUser(ctx context.Context, addressID string, userID string) (bool, error) {
+	var count int64
+	result := r.DB.WithContext(ctx).Table("user_addresses").
+		Where("id = ? AND user_id = ? AND is_active = ?", addressID, userID, true).
+		Limit(1).
+		Count(&count)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to check address: %w", result.Error)
+	}
+
+	return count > 0, nil
+}
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"order-service/internal/client"
+	"order-service/internal/models"
+	"order-service/internal/repository"
+	"shared/apperr"
+
+	"github.com/google/uuid"
+)
+
+type OrderService struct {
+	repo     repository.OrderRepository
+	products client.ProductClient
+	pricing  *PricingConfig
+}
+
+func NewOrderService(repo repository.OrderRepository, products client.ProductClient, pricing *PricingConfig) *OrderService {
+	if pricing == nil {
+		pricing = DefaultPricingConfig()
+	}
+
+	return &OrderService{
+		repo:     repo,
+		products: products,
+		pricing:  pricing,
+	}
+}
+
+func (s *OrderService) GetOrder(ctx context.Context, id string) (*models.Order, error) {
+	if id == "" {
+		return nil, apperr.InvalidField("id", "order ID is required")
+	}
+
+	order, err := s.repo.GetOrder(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order: %w", err)
+	}
+
+	return order, nil
+}
+
+func (s *OrderService) ListOrders(ctx context.Context, filter models.OrderFilter) (*models.OrderListResponse, error) {
+	// Set default pagination values
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	response, err := s.repo.ListOrders(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list orders: %w", err)
+	}
+
+	return response, nil
+}
+
+func (s *OrderService) GetUserOrders(ctx context.Context, userID string, filter models.OrderFilter) (*models.OrderListResponse, error) {
+	if userID == "" {
+		return nil, apperr.InvalidField("user_id", "user ID is required")
+	}
+
+	filter.UserID = userID
+	return s.ListOrders(ctx, filter)
+}
+
+// CreateOrder prices the requested items from product-service and places the
+// order for userID. Stock for every line is reserved before any of it is
+// committed, so an order is either fully stocked or not created at all.
+func (s *OrderService) CreateOrder(ctx context.Context, userID string, request *models.CreateOrderRequest) (*models.Order, error) {
+	if err := s.validateOrderAddresses(ctx, userID, request.ShippingAddressID, request.BillingAddressID); err != nil {
+		return nil, err
+	}
+
+	order := &models.Order{
+		ID:                uuid.New().String(),
+		UserID:            userID,
+		Status:            models.OrderStatusPending,
+		ShippingAddressID: request.ShippingAddressID,
+		BillingAddressID:  request.BillingAddressID,
+		PaymentStatus:     models.PaymentStatusPending,
+		PaymentMethod:     request.PaymentMethod,
+		Notes:             request.Notes,
+		History: []models.OrderStatusHistory{
+			{ToStatus: models.OrderStatusPending, Actor: userID, Reason: "order placed"},
+		},
+	}
+
+	discount := 0.0
+	for _, line := range mergeOrderItems(request.Items) {
+		product, err := s.products.GetProduct(ctx, line.ProductID)
+		if err != nil {
+			// A missing product is a problem with the request, not a missing order
+			if errors.Is(err, apperr.ErrNotFound) {
+				return nil, apperr.InvalidField("items", fmt.Sprintf("product %s not found", line.ProductID))
+			}
+			return nil, fmt.Errorf("failed to price order: %w", err)
+		}
+
+		item, itemDiscount := priceItem(product, line.Quantity)
+		order.Items = append(order.Items, item)
+		discount += itemDiscount
+	}
+	s.pricing.applyTotals(order, discount)
+
+	if err := s.placeOrder(ctx, order); err != nil {
+		return nil, fmt.Errorf("failed to create order: %w", err)
+	}
+
+	return order, nil
+}
+
+func (s *OrderService) UpdateOrder(ctx context.Context, id string, request *models.UpdateOrderRequest) (*models.Order, error) {
+	order, err := s.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Addresses and payment details are fixed once the order is confirmed
+	if order.Status != models.OrderStatusPending {
+		return nil, apperr.Conflict("order %s cannot be changed while %s", order.OrderNumber, order.Status)
+	}
+
+	if err := s.validateOrderAddresses(ctx, order.UserID, request.ShippingAddressID, request.BillingAddressID); err != nil {
+		return nil, err
+	}
+
+	order, err = s.repo.UpdateOrder(ctx, id, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update order: %w", err)
+	}
+
+	return order, nil
+}
+
+// UpdateOrderStatus moves an order to the requested status on behalf of
+// actor, the authenticated caller the transition is recorded against
+func (s *OrderService) UpdateOrderStatus(ctx context.Context, id string, actor string, request *models.UpdateOrderStatusRequest) (*models.Order, error) {
+	order, err := s.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.transition(ctx, order, request.Status, actor, request.Reason)
+}
+
+// CancelOrder cancels an order on behalf of actor, the authenticated caller
+// the cancellation is recorded against
+func (s *OrderService) CancelOrder(ctx context.Context, id string, actor string, request *models.CancelOrderRequest) (*models.Order, error) {
+	order, err := s.GetOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.transition(ctx, order, models.OrderStatusCancelled, actor, request.Reason)
+}
+
+// transition validates and applies a status change. Cancelling returns the
+// order's stock to product-service before the change commits, and the
+// cancellation fails if the stock cannot be returned. product-service returns
+// each product of an order once, so retrying a cancellation that failed
+// partway returns the rest without returning any product twice.
+func (s *OrderService) transition(ctx context.Context, order *models.Order, status, actor, reason string) (*models.Order, error) {
+	if err := validateTransition(order.Status, status); err != nil {
+		return nil, fmt.Errorf("order %s: %w", order.OrderNumber, err)
+	}
+
+	var beforeCommit func(order *models.Order) error
+	if status == models.OrderStatusCancelled {
+		beforeCommit = func(order *models.Order) error {
+			return s.returnStock(ctx, order.ID, order.Items)
+		}
+	}
+
+	updated, err := s.repo.TransitionOrderStatus(ctx, order.ID, &models.OrderStatusHistory{
+		FromStatus: order.Status,
+		ToStatus:   status,
+		Actor:      actor,
+		Reason:     reason,
+	}, beforeCommit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update order status: %w", err)
+	}
+
+	return updated, nil
+}
+
+// placeOrder takes the stock for a priced order and stores it
+func (s *OrderService) placeOrder(ctx context.Context, order *models.Order) error {
+	if err := s.takeStock(ctx, order.ID, order.Items); err != nil {
+		return err
+	}
+
+	if err := s.repo.CreateOrder(ctx, order); err != nil {
+		return errors.Join(err, s.returnStock(ctx, order.ID, order.Items))
+	}
+
+	return nil
+}
+
+// takeStock reserves every item of an order and then confirms the
+// reservations. If any item cannot be reserved, the holds already taken are
+// released and nothing is decremented. Errors undoing a partial take are
+// returned along with the error that caused it.
+func (s *OrderService) takeStock(ctx context.Context, orderID string, items []models.OrderItem) error {
+	reservations := make([]string, 0, len(items))
+	for _, item := range items {
+		reservationID, err := s.products.ReserveStock(ctx, item.ProductID, orderID, item.Quantity)
+		if err != nil {
+			return errors.Join(err, s.releaseReservations(ctx, reservations))
+		}
+		reservations = append(reservations, reservationID)
+	}
+
+	for i, reservationID := range reservations {
+		if err := s.products.ConfirmReservation(ctx, reservationID); err != nil {
+			return errors.Join(err, s.releaseReservations(ctx, reservations[i:]), s.returnStock(ctx, orderID, items[:i]))
+		}
+	}
+
+	return nil
+}
+
+// releaseReservations releases every hold, returning the errors of those
+// that could not be released
+func (s *OrderService) releaseReservations(ctx context.Context, reservations []string) error {
+	var errs []error
+	for _, reservationID := range reservations {
+		if err := s.products.ReleaseReservation(ctx, reservationID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to release reservation %s: %w", reservationID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// returnStock puts the quantities of items back into product-service stock,
+// stopping at the first item that cannot be returned. Returns are made once
+// per order and product, so calling it again returns only the rest.
+func (s *OrderService) returnStock(ctx context.Context, orderID string, items []models.OrderItem) error {
+	for _, item := range items {
+		if err := s.products.ReturnStock(ctx, item.ProductID, orderID, item.Quantity); err != nil {
+			return fmt.Errorf("failed to return %d units of product %s for order %s: %w", item.Quantity, item.ProductID, orderID, err)
+		}
+	}
+	return nil
+}
+
+// validateOrderAddresses checks that the shipping and billing addresses an
+// order is given, where set, are in its user's address book
+func (s *OrderService) validateOrderAddresses(ctx context.Context, userID string, shippingAddressID, billingAddressID *string) error {
+	addresses := []struct {
+		field string
+		id    *string
+	}{
+		{"shipping_address_id", shippingAddressID},
+		{"billing_address_id", billingAddressID},
+	}
+	for _, address := range addresses {
+		if address.id == nil {
+			continue
+		}
+		belongs, err := s.repo.AddressBelongsToUser(ctx, *address.id, userID)
+		if err != nil {
+			return err
+		}
+		if !belongs {
+			return apperr.InvalidField(address.field, fmt.Sprintf("address %s is not in the user's address book", *address.id))
+		}
+	}
+	return nil
+}
+
+// mergeOrderItems combines repeated products into a single line, keeping the
+// order in which products first appear
+func mergeOrderItems(items []models.OrderItemRequest) []models.OrderItemRequest {
+	merged := make([]models.OrderItemRequest, 0, len(items))
+	index := make(map[string]int, len(items))
+
+	for _, item := range items {
+		if i, ok := index[item.ProductID]; ok {
+			merged[i].Quantity += item.Quantity
+			continue
+		}
+		index[item.ProductID] = len(merged)
+		merged = append(merged, item)
+	}
+
+	return merged
+}
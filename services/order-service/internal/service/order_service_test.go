@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"order-service/internal/client"
+	"order-service/internal/models"
+	"order-service/internal/repository"
+)
+
+// fakeOrderRepository holds a single order. Methods the tests do not use
+// panic through the nil embedded interface.
+type fakeOrderRepository struct {
+	repository.OrderRepository
+	order *models.Order
+}
+
+func (r *fakeOrderRepository) GetOrder(ctx context.Context, id string) (*models.Order, error) {
+	order := *r.order
+	return &order, nil
+}
+
+func (r *fakeOrderRepository) TransitionOrderStatus(ctx context.Context, id string, transition *models.OrderStatusHistory, beforeCommit func(order *models.Order) error) (*models.Order, error) {
+	order := *r.order
+	order.Status = transition.ToStatus
+	if beforeCommit != nil {
+		if err := beforeCommit(&order); err != nil {
+			return nil, err
+		}
+	}
+	r.order = &order
+	return &order, nil
+}
+
+// fakeProductClient tracks the stock of each product and fails returns of
+// the products in failReturns. Like product-service, it returns each product
+// of an order once.
+type fakeProductClient struct {
+	client.ProductClient
+	stock        map[string]int
+	reservations map[string]models.OrderItem
+	failReturns  map[string]bool
+	returned     map[string]bool
+}
+
+func (c *fakeProductClient) ReserveStock(ctx context.Context, productID string, orderID string, quantity int) (string, error) {
+	id := productID + "-reservation"
+	c.reservations[id] = models.OrderItem{ProductID: productID, Quantity: quantity}
+	return id, nil
+}
+
+func (c *fakeProductClient) ConfirmReservation(ctx context.Context, reservationID string) error {
+	item := c.reservations[reservationID]
+	c.stock[item.ProductID] -= item.Quantity
+	delete(c.reservations, reservationID)
+	return nil
+}
+
+func (c *fakeProductClient) ReleaseReservation(ctx context.Context, reservationID string) error {
+	delete(c.reservations, reservationID)
+	return nil
+}
+
+func (c *fakeProductClient) ReturnStock(ctx context.Context, productID string, orderID string, quantity int) error {
+	if c.failReturns[productID] {
+		return errors.New("product-service unavailable")
+	}
+	if c.returned[orderID+"/"+productID] {
+		return nil
+	}
+	c.returned[orderID+"/"+productID] = true
+	c.stock[productID] += quantity
+	return nil
+}
+
+func newCancellableOrder() *models.Order {
+	return &models.Order{
+		ID:          "order-1",
+		OrderNumber: "ORD-1",
+		Status:      models.OrderStatusPending,
+		Items: []models.OrderItem{
+			{ProductID: "apples", Quantity: 2},
+			{ProductID: "pears", Quantity: 3},
+		},
+	}
+}
+
+func TestCancelOrderReturnsStock(t *testing.T) {
+	repo := &fakeOrderRepository{order: newCancellableOrder()}
+	products := &fakeProductClient{
+		stock:        map[string]int{"apples": 0, "pears": 0},
+		reservations: map[string]models.OrderItem{},
+		returned:     map[string]bool{},
+	}
+	service := NewOrderService(repo, products, nil)
+
+	order, err := service.CancelOrder(context.Background(), "order-1", "user-1", &models.CancelOrderRequest{})
+	if err != nil {
+		t.Fatalf("CancelOrder failed: %v", err)
+	}
+	if order.Status != models.OrderStatusCancelled {
+		t.Errorf("order is %s, want %s", order.Status, models.OrderStatusCancelled)
+	}
+	if products.stock["apples"] != 2 || products.stock["pears"] != 3 {
+		t.Errorf("stock is %v after cancelling, want apples 2 and pears 3", products.stock)
+	}
+}
+
+func TestCancelOrderFailsWhenStockCannotBeReturned(t *testing.T) {
+	repo := &fakeOrderRepository{order: newCancellableOrder()}
+	products := &fakeProductClient{
+		stock:        map[string]int{"apples": 0, "pears": 0},
+		reservations: map[string]models.OrderItem{},
+		failReturns:  map[string]bool{"pears": true},
+		returned:     map[string]bool{},
+	}
+	service := NewOrderService(repo, products, nil)
+
+	if _, err := service.CancelOrder(context.Background(), "order-1", "user-1", &models.CancelOrderRequest{}); err == nil {
+		t.Fatal("CancelOrder succeeded although the stock of pears could not be returned")
+	}
+	if repo.order.Status != models.OrderStatusPending {
+		t.Errorf("order is %s after the failed cancellation, want %s", repo.order.Status, models.OrderStatusPending)
+	}
+	if products.stock["apples"] != 2 || products.stock["pears"] != 0 {
+		t.Errorf("stock is %v after the failed cancellation, want apples 2 and pears 0", products.stock)
+	}
+
+	// Retrying returns the pears without returning the apples again
+	products.failReturns = nil
+	if _, err := service.CancelOrder(context.Background(), "order-1", "user-1", &models.CancelOrderRequest{}); err != nil {
+		t.Fatalf("retried CancelOrder failed: %v", err)
+	}
+	if products.stock["apples"] != 2 || products.stock["pears"] != 3 {
+		t.Errorf("stock is %v after the retried cancellation, want apples 2 and pears 3", products.stock)
+	}
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"order-service/internal/models"
+	"shared/apperr"
+)
+
+// orderTransitions lists the statuses each status may move to. Delivered and
+// cancelled orders are final.
+var orderTransitions = map[string][]string{
+	models.OrderStatusPending:    {models.OrderStatusConfirmed, models.OrderStatusCancelled},
+	models.OrderStatusConfirmed:  {models.OrderStatusProcessing, models.OrderStatusCancelled},
+	models.OrderStatusProcessing: {models.OrderStatusShipped},
+	models.OrderStatusShipped:    {models.OrderStatusDelivered},
+	models.OrderStatusDelivered:  {},
+	models.OrderStatusCancelled:  {},
+}
+
+// validateTransition checks that an order may move from one status to
+// another, reporting a conflict with its current status if not
+func validateTransition(from, to string) error {
+	for _, allowed := range orderTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+
+	return apperr.Conflict("invalid order status transition %s -> %s", from, to)
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"order-service/internal/models"
+	"shared/apperr"
+)
+
+func TestValidateTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		allowed  bool
+	}{
+		{models.OrderStatusPending, models.OrderStatusConfirmed, true},
+		{models.OrderStatusPending, models.OrderStatusCancelled, true},
+		{models.OrderStatusConfirmed, models.OrderStatusProcessing, true},
+		{models.OrderStatusConfirmed, models.OrderStatusCancelled, true},
+		{models.OrderStatusProcessing, models.OrderStatusShipped, true},
+		{models.OrderStatusShipped, models.OrderStatusDelivered, true},
+
+		{models.OrderStatusPending, models.OrderStatusShipped, false},
+		{models.OrderStatusPending, models.OrderStatusPending, false},
+		{models.OrderStatusProcessing, models.OrderStatusCancelled, false},
+		{models.OrderStatusShipped, models.OrderStatusCancelled, false},
+		{models.OrderStatusShipped, models.OrderStatusProcessing, false},
+		{models.OrderStatusDelivered, models.OrderStatusPending, false},
+		{models.OrderStatusDelivered, models.OrderStatusCancelled, false},
+		{models.OrderStatusCancelled, models.OrderStatusPending, false},
+		{models.OrderStatusCancelled, models.OrderStatusConfirmed, false},
+		{"unknown", models.OrderStatusConfirmed, false},
+	}
+
+	for _, test := range tests {
+		err := validateTransition(test.from, test.to)
+		switch {
+		case test.allowed && err != nil:
+			t.Errorf("%s -> %s failed with %v, want it allowed", test.from, test.to, err)
+		case !test.allowed && !errors.Is(err, apperr.ErrConflict):
+			t.Errorf("%s -> %s returned %v, want a conflict", test.from, test.to, err)
+		}
+	}
+}
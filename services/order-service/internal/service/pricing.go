@@ -0,0 +1,80 @@
+package service
+
+import (
+	"math"
+	"os"
+	"strconv"
+
+	"order-service/internal/client"
+	"order-service/internal/models"
+)
+
+type PricingConfig struct {
+	TaxRate               float64
+	ShippingFee           float64
+	FreeShippingThreshold float64
+}
+
+// DefaultPricingConfig returns the pricing configuration from environment
+// variables, falling back to the store defaults
+func DefaultPricingConfig() *PricingConfig {
+	return &PricingConfig{
+		TaxRate:               getEnvFloatOrDefault("ORDER_TAX_RATE", 0.16),
+		ShippingFee:           getEnvFloatOrDefault("ORDER_SHIPPING_FEE", 49.00),
+		FreeShippingThreshold: getEnvFloatOrDefault("ORDER_FREE_SHIPPING_THRESHOLD", 500.00),
+	}
+}
+
+// priceItem builds an order line from current product data. Lines are priced
+// at the list price; for products on sale the difference to the sale price is
+// returned as the line discount.
+func priceItem(product *client.Product, quantity int) (models.OrderItem, float64) {
+	listPrice := product.Price
+	if product.IsOnSale && product.OriginalPrice != nil && *product.OriginalPrice > product.Price {
+		listPrice = *product.OriginalPrice
+	}
+
+	item := models.OrderItem{
+		ProductID:   product.ID,
+		SKU:         product.SKU,
+		ProductName: product.Name,
+		Quantity:    quantity,
+		UnitPrice:   roundMoney(listPrice),
+		TotalPrice:  roundMoney(listPrice * float64(quantity)),
+	}
+
+	return item, roundMoney((listPrice - product.Price) * float64(quantity))
+}
+
+// applyTotals sets the order amounts from its items and the accumulated
+// discount. Tax is charged on the discounted subtotal and shipping is free
+// from FreeShippingThreshold on.
+func (c *PricingConfig) applyTotals(order *models.Order, discount float64) {
+	subtotal := 0.0
+	for _, item := range order.Items {
+		subtotal += item.TotalPrice
+	}
+
+	taxable := math.Max(subtotal-discount, 0)
+
+	shipping := c.ShippingFee
+	if c.FreeShippingThreshold > 0 && taxable >= c.FreeShippingThreshold {
+		shipping = 0
+	}
+
+	order.DiscountAmount = roundMoney(discount)
+	order.TaxAmount = roundMoney(taxable * c.TaxRate)
+	order.ShippingAmount = roundMoney(shipping)
+	order.TotalAmount = roundMoney(taxable + order.TaxAmount + order.ShippingAmount)
+}
+
+func roundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func getEnvFloatOrDefault(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"order-service/internal/models"
+	"shared/apperr"
+
+	"github.com/google/uuid"
+)
+
+// subscriptionBatchSize bounds how many due subscriptions one generator run
+// processes; the rest are picked up by the next run
+const subscriptionBatchSize = 100
+
+const subscriptionGeneratorActor = "subscription-generator"
+
+// GenerateSubscriptionOrders materializes an order for every active
+// subscription that is due at now and returns what happened to each
+func (s *OrderService) GenerateSubscriptionOrders(ctx context.Context, now time.Time) ([]models.SubscriptionRun, error) {
+	due, err := s.repo.ListDueSubscriptions(ctx, now, subscriptionBatchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate subscription orders: %w", err)
+	}
+
+	runs := make([]models.SubscriptionRun, 0, len(due))
+	for i := range due {
+		run, err := s.runSubscription(ctx, &due[i], now)
+		if err != nil {
+			return runs, fmt.Errorf("failed to generate subscription orders: %w", err)
+		}
+		if run != nil {
+			runs = append(runs, *run)
+		}
+	}
+
+	return runs, nil
+}
+
+// runSubscription claims the due occurrence of a subscription and orders it.
+// It returns nil when another generator already claimed the occurrence.
+func (s *OrderService) runSubscription(ctx context.Context, subscription *models.Subscription, now time.Time) (*models.SubscriptionRun, error) {
+	scheduledFor := subscription.NextRunAt
+
+	claimed, err := s.repo.ClaimSubscriptionRun(ctx, subscription.ID, scheduledFor, nextRunAfter(subscription, scheduledFor, now))
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, nil
+	}
+
+	run := &models.SubscriptionRun{
+		SubscriptionID: subscription.ID,
+		ScheduledFor:   scheduledFor,
+		Status:         models.SubscriptionRunOrdered,
+	}
+
+	order, adjustments, err := s.orderFromSubscription(ctx, subscription)
+	run.Adjustments = adjustments
+
+	switch {
+	case err != nil:
+		run.Status = models.SubscriptionRunFailed
+		run.Details = err.Error()
+	case order == nil:
+		run.Status = models.SubscriptionRunSkipped
+		run.Details = "no subscribed product could be ordered"
+	default:
+		run.OrderID = &order.ID
+	}
+
+	if err := s.repo.RecordSubscriptionRun(ctx, run); err != nil {
+		return nil, err
+	}
+
+	return run, nil
+}
+
+// orderFromSubscription prices the subscription basket at current prices and
+// places it. Products that are gone, out of stock or past the subscription's
+// price limit are left out, lines are cut to the stock available, and every
+// such change is returned as an adjustment. A nil order means nothing could
+// be ordered.
+func (s *OrderService) orderFromSubscription(ctx context.Context, subscription *models.Subscription) (*models.Order, []models.SubscriptionAdjustment, error) {
+	order := &models.Order{
+		ID:                uuid.New().String(),
+		UserID:            subscription.UserID,
+		Status:            models.OrderStatusPending,
+		ShippingAddressID: &subscription.ShippingAddressID,
+		PaymentStatus:     models.PaymentStatusPending,
+		PaymentMethod:     subscription.PaymentMethod,
+		SubscriptionID:    &subscription.ID,
+		History: []models.OrderStatusHistory{
+			{ToStatus: models.OrderStatusPending, Actor: subscriptionGeneratorActor, Reason: fmt.Sprintf("subscription %s", subscription.ID)},
+		},
+	}
+
+	var adjustments []models.SubscriptionAdjustment
+	discount := 0.0
+
+	for _, line := range subscription.Items {
+		adjustment := models.SubscriptionAdjustment{
+			ProductID:         line.ProductID,
+			RequestedQuantity: line.Quantity,
+			SubscribedPrice:   line.UnitPrice,
+		}
+
+		product, err := s.products.GetProduct(ctx, line.ProductID)
+		if err != nil {
+			if !errors.Is(err, apperr.ErrNotFound) {
+				return nil, adjustments, err
+			}
+			adjustment.Type = models.AdjustmentUnavailable
+			adjustments = append(adjustments, adjustment)
+			continue
+		}
+		adjustment.CurrentPrice = roundMoney(product.Price)
+
+		if subscription.MaxPriceIncrease != nil && adjustment.CurrentPrice > roundMoney(line.UnitPrice*(1+*subscription.MaxPriceIncrease/100)) {
+			adjustment.Type = models.AdjustmentPriceLimitReached
+			adjustments = append(adjustments, adjustment)
+			continue
+		}
+
+		quantity := line.Quantity
+		switch {
+		case product.Available <= 0:
+			adjustment.Type = models.AdjustmentOutOfStock
+			adjustments = append(adjustments, adjustment)
+			continue
+		case product.Available < quantity:
+			quantity = product.Available
+			adjustment.Type = models.AdjustmentPartialStock
+		case adjustment.CurrentPrice != line.UnitPrice:
+			adjustment.Type = models.AdjustmentPriceChanged
+		}
+
+		if adjustment.Type != "" {
+			adjustment.Quantity = quantity
+			adjustments = append(adjustments, adjustment)
+		}
+
+		item, itemDiscount := priceItem(product, quantity)
+		order.Items = append(order.Items, item)
+		discount += itemDiscount
+	}
+
+	if len(order.Items) == 0 {
+		return nil, adjustments, nil
+	}
+	s.pricing.applyTotals(order, discount)
+
+	if err := s.placeOrder(ctx, order); err != nil {
+		return nil, adjustments, err
+	}
+
+	return order, adjustments, nil
+}
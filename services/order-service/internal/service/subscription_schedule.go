@@ -0,0 +1,64 @@
+package service
+
+import (
+	"time"
+
+	"order-service/internal/models"
+)
+
+// Subscription runs are scheduled on whole UTC days
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// firstRun returns the first occurrence of the subscription on or after from
+func firstRun(subscription *models.Subscription, from time.Time) time.Time {
+	from = startOfDay(from)
+	if subscription.Cadence == models.CadenceWeekday {
+		return nextWeekday(from, time.Weekday(*subscription.Weekday))
+	}
+	return from
+}
+
+// nextRun returns the occurrence that follows previous
+func nextRun(subscription *models.Subscription, previous time.Time) time.Time {
+	switch subscription.Cadence {
+	case models.CadenceBiweekly:
+		return previous.AddDate(0, 0, 14)
+	case models.CadenceMonthly:
+		return addMonthOnDay(previous, subscription.StartDate.Day())
+	case models.CadenceWeekday:
+		return nextWeekday(previous.AddDate(0, 0, 1), time.Weekday(*subscription.Weekday))
+	default:
+		return previous.AddDate(0, 0, 7)
+	}
+}
+
+// nextRunAfter returns the first occurrence following previous that is later
+// than now; occurrences missed while the subscription was due are not replayed
+func nextRunAfter(subscription *models.Subscription, previous time.Time, now time.Time) time.Time {
+	next := nextRun(subscription, previous)
+	for !next.After(now) {
+		next = nextRun(subscription, next)
+	}
+	return next
+}
+
+// nextWeekday returns the first day on or after from that falls on weekday
+func nextWeekday(from time.Time, weekday time.Weekday) time.Time {
+	days := (int(weekday) - int(from.Weekday()) + 7) % 7
+	return from.AddDate(0, 0, days)
+}
+
+// addMonthOnDay returns the given day of the month after t, clamped to the
+// last day of shorter months so a subscription on the 31st stays monthly
+func addMonthOnDay(t time.Time, day int) time.Time {
+	firstOfNext := time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	lastDay := firstOfNext.AddDate(0, 1, -1).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return firstOfNext.AddDate(0, 0, day-1)
+}
@@ -0,0 +1,305 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"order-service/internal/models"
+	"shared/apperr"
+)
+
+// subscriptionRunHistoryLimit bounds the runs returned for a subscription
+const subscriptionRunHistoryLimit = 50
+
+func (s *OrderService) GetSubscription(ctx context.Context, id string) (*models.Subscription, error) {
+	if id == "" {
+		return nil, apperr.InvalidField("id", "subscription ID is required")
+	}
+
+	subscription, err := s.repo.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription: %w", err)
+	}
+
+	return subscription, nil
+}
+
+func (s *OrderService) ListSubscriptions(ctx context.Context, filter models.SubscriptionFilter) (*models.SubscriptionListResponse, error) {
+	// Set default pagination values
+	if filter.Limit <= 0 {
+		filter.Limit = 20
+	}
+	if filter.Limit > 100 {
+		filter.Limit = 100
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	response, err := s.repo.ListSubscriptions(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list subscriptions: %w", err)
+	}
+
+	return response, nil
+}
+
+func (s *OrderService) GetSubscriptionRuns(ctx context.Context, id string) ([]models.SubscriptionRun, error) {
+	if _, err := s.GetSubscription(ctx, id); err != nil {
+		return nil, err
+	}
+
+	runs, err := s.repo.ListSubscriptionRuns(ctx, id, subscriptionRunHistoryLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+func (s *OrderService) CreateSubscription(ctx context.Context, request *models.CreateSubscriptionRequest) (*models.Subscription, error) {
+	today := startOfDay(time.Now())
+
+	// Deliveries start tomorrow unless the customer picks a later day
+	startDate := today.AddDate(0, 0, 1)
+	if request.StartDate != nil {
+		startDate = startOfDay(*request.StartDate)
+		if startDate.Before(today) {
+			return nil, apperr.InvalidField("start_date", "start date cannot be in the past")
+		}
+	}
+
+	subscription := &models.Subscription{
+		UserID:            request.UserID,
+		Name:              request.Name,
+		Status:            models.SubscriptionStatusActive,
+		Cadence:           request.Cadence,
+		Weekday:           request.Weekday,
+		StartDate:         startDate,
+		ShippingAddressID: request.ShippingAddressID,
+		PaymentMethod:     request.PaymentMethod,
+		MaxPriceIncrease:  request.MaxPriceIncrease,
+	}
+
+	if err := validateCadence(subscription); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateSubscriptionAddress(ctx, subscription.ShippingAddressID, subscription.UserID); err != nil {
+		return nil, err
+	}
+
+	items, err := s.subscriptionItems(ctx, request.Items)
+	if err != nil {
+		return nil, err
+	}
+	subscription.Items = items
+	subscription.NextRunAt = firstRun(subscription, startDate)
+
+	if err := s.repo.CreateSubscription(ctx, subscription); err != nil {
+		return nil, fmt.Errorf("failed to create subscription: %w", err)
+	}
+
+	return subscription, nil
+}
+
+func (s *OrderService) UpdateSubscription(ctx context.Context, id string, request *models.UpdateSubscriptionRequest) (*models.Subscription, error) {
+	subscription, err := s.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if subscription.Status == models.SubscriptionStatusCancelled {
+		return nil, apperr.Conflict("subscription %s cannot be changed while %s", id, subscription.Status)
+	}
+
+	if request.Name != nil {
+		subscription.Name = *request.Name
+	}
+	if request.PaymentMethod != nil {
+		subscription.PaymentMethod = *request.PaymentMethod
+	}
+	if request.MaxPriceIncrease != nil {
+		subscription.MaxPriceIncrease = request.MaxPriceIncrease
+	}
+	if request.ShippingAddressID != nil {
+		if err := s.validateSubscriptionAddress(ctx, *request.ShippingAddressID, subscription.UserID); err != nil {
+			return nil, err
+		}
+		subscription.ShippingAddressID = *request.ShippingAddressID
+	}
+
+	// A new cadence restarts the schedule from the next possible day
+	if request.Cadence != nil || request.Weekday != nil {
+		if request.Cadence != nil {
+			subscription.Cadence = *request.Cadence
+		}
+		if request.Weekday != nil {
+			subscription.Weekday = request.Weekday
+		}
+		if err := validateCadence(subscription); err != nil {
+			return nil, err
+		}
+
+		subscription.StartDate = startOfDay(time.Now()).AddDate(0, 0, 1)
+		subscription.NextRunAt = firstRun(subscription, subscription.StartDate)
+	}
+
+	replaceItems := request.Items != nil
+	if replaceItems {
+		items, err := s.subscriptionItems(ctx, request.Items)
+		if err != nil {
+			return nil, err
+		}
+		subscription.Items = items
+	}
+
+	if err := s.repo.SaveSubscription(ctx, subscription, replaceItems); err != nil {
+		return nil, fmt.Errorf("failed to update subscription: %w", err)
+	}
+
+	return subscription, nil
+}
+
+func (s *OrderService) PauseSubscription(ctx context.Context, id string) (*models.Subscription, error) {
+	subscription, err := s.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if subscription.Status != models.SubscriptionStatusActive {
+		return nil, apperr.Conflict("subscription %s cannot be changed while %s", id, subscription.Status)
+	}
+
+	subscription.Status = models.SubscriptionStatusPaused
+	if err := s.repo.SaveSubscription(ctx, subscription, false); err != nil {
+		return nil, fmt.Errorf("failed to pause subscription: %w", err)
+	}
+
+	return subscription, nil
+}
+
+// ResumeSubscription reactivates a paused subscription. Occurrences that fell
+// inside the pause are dropped rather than ordered late.
+func (s *OrderService) ResumeSubscription(ctx context.Context, id string) (*models.Subscription, error) {
+	subscription, err := s.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if subscription.Status != models.SubscriptionStatusPaused {
+		return nil, apperr.Conflict("subscription %s cannot be changed while %s", id, subscription.Status)
+	}
+
+	today := startOfDay(time.Now())
+	for subscription.NextRunAt.Before(today) {
+		subscription.NextRunAt = nextRun(subscription, subscription.NextRunAt)
+	}
+
+	subscription.Status = models.SubscriptionStatusActive
+	if err := s.repo.SaveSubscription(ctx, subscription, false); err != nil {
+		return nil, fmt.Errorf("failed to resume subscription: %w", err)
+	}
+
+	return subscription, nil
+}
+
+// SkipNextSubscriptionRun moves an active subscription past its next
+// occurrence and records the skip
+func (s *OrderService) SkipNextSubscriptionRun(ctx context.Context, id string) (*models.Subscription, error) {
+	subscription, err := s.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if subscription.Status != models.SubscriptionStatusActive {
+		return nil, apperr.Conflict("subscription %s cannot be changed while %s", id, subscription.Status)
+	}
+
+	skipped := subscription.NextRunAt
+	next := nextRunAfter(subscription, skipped, startOfDay(time.Now()))
+
+	claimed, err := s.repo.ClaimSubscriptionRun(ctx, id, skipped, next)
+	if err != nil {
+		return nil, fmt.Errorf("failed to skip subscription run: %w", err)
+	}
+	if !claimed {
+		return nil, apperr.Conflict("the %s run of subscription %s is already being ordered", skipped.Format("2006-01-02"), id)
+	}
+	subscription.NextRunAt = next
+
+	err = s.repo.RecordSubscriptionRun(ctx, &models.SubscriptionRun{
+		SubscriptionID: id,
+		ScheduledFor:   skipped,
+		Status:         models.SubscriptionRunSkipped,
+		Details:        "skipped by customer",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to skip subscription run: %w", err)
+	}
+
+	return subscription, nil
+}
+
+func (s *OrderService) CancelSubscription(ctx context.Context, id string) error {
+	subscription, err := s.GetSubscription(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if subscription.Status == models.SubscriptionStatusCancelled {
+		return apperr.Conflict("subscription %s cannot be changed while %s", id, subscription.Status)
+	}
+
+	subscription.Status = models.SubscriptionStatusCancelled
+	if err := s.repo.SaveSubscription(ctx, subscription, false); err != nil {
+		return fmt.Errorf("failed to cancel subscription: %w", err)
+	}
+
+	return nil
+}
+
+// subscriptionItems builds a basket from requested lines, recording each
+// product's current price as the subscribed price
+func (s *OrderService) subscriptionItems(ctx context.Context, lines []models.OrderItemRequest) ([]models.SubscriptionItem, error) {
+	lines = mergeOrderItems(lines)
+	items := make([]models.SubscriptionItem, 0, len(lines))
+
+	for _, line := range lines {
+		product, err := s.products.GetProduct(ctx, line.ProductID)
+		if err != nil {
+			if errors.Is(err, apperr.ErrNotFound) {
+				return nil, apperr.InvalidField("items", fmt.Sprintf("product %s not found", line.ProductID))
+			}
+			return nil, fmt.Errorf("failed to get product: %w", err)
+		}
+
+		items = append(items, models.SubscriptionItem{
+			ProductID: product.ID,
+			Quantity:  line.Quantity,
+			UnitPrice: roundMoney(product.Price),
+		})
+	}
+
+	return items, nil
+}
+
+func (s *OrderService) validateSubscriptionAddress(ctx context.Context, addressID string, userID string) error {
+	belongs, err := s.repo.AddressBelongsToUser(ctx, addressID, userID)
+	if err != nil {
+		return err
+	}
+	if !belongs {
+		return apperr.InvalidField("shipping_address_id", fmt.Sprintf("address %s is not in the user's address book", addressID))
+	}
+	return nil
+}
+
+func validateCadence(subscription *models.Subscription) error {
+	if subscription.Cadence == models.CadenceWeekday && subscription.Weekday == nil {
+		return apperr.InvalidField("weekday", "weekday is required for the weekday cadence")
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+// Package schema lists the models order-service keeps in Postgres, for the
+// startup schema check and the schema diff of tools/migrate
+package schema
+
+import "order-service/internal/models"
+
+// Models returns a model of every table the service reads and writes
+func Models() []interface{} {
+	return []interface{}{
+		&models.Order{},
+		&models.OrderItem{},
+		&models.OrderStatusHistory{},
+		&models.Subscription{},
+		&models.SubscriptionItem{},
+		&models.SubscriptionRun{},
+	}
+}
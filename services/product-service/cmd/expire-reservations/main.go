@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"product-service/internal/repository"
+	"product-service/internal/service"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// expire-reservations releases every active stock reservation past its
+// expiry, returning the held units to sale. Reserving also expires the
+// reservations of the product reserved, but products nobody reserves again
+// would hold their units until this runs. Run it from a scheduled job, or
+// with -interval to keep sweeping.
+func main() {
+	var (
+		interval = flag.Duration("interval", 0, "Sweep every interval until interrupted, e.g. 1m; 0 sweeps once")
+	)
+	flag.Parse()
+
+	// Try to load .env file for local development only
+	if _, err := os.Stat("../../.env"); err == nil {
+		if err := godotenv.Load("../../.env"); err != nil {
+			fmt.Printf("Error loading .env: %v\n", err)
+		}
+	}
+
+	repo, err := repository.NewPostgresRepository()
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize database: %v", err)
+	}
+
+	// Expiring lists no pages, so it needs no cursor codec
+	productService := service.NewProductService(repo, nil)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *interval <= 0 {
+		if err := sweep(ctx, productService); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		return
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		// A failed sweep is retried on the next tick
+		if err := sweep(ctx, productService); err != nil {
+			log.Printf("❌ %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func sweep(ctx context.Context, productService *service.ProductService) error {
+	expired, err := productService.ExpireReservations(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Expired %d reservations\n", expired)
+	return nil
+}
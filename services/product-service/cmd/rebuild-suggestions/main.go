@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"product-service/internal/repository"
+	"product-service/internal/service"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// rebuild-suggestions recomputes the search box suggestions, with how many
+// units of each product, brand and category were sold, and stores them for
+// GET /products/suggest. New products and sales show up in suggestions on
+// the next rebuild. Run it from a scheduled job, or with -interval to keep
+// rebuilding.
+func main() {
+	var (
+		interval = flag.Duration("interval", 0, "Rebuild every interval until interrupted, e.g. 15m; 0 rebuilds once")
+	)
+	flag.Parse()
+
+	// Try to load .env file for local development only
+	if _, err := os.Stat("../../.env"); err == nil {
+		if err := godotenv.Load("../../.env"); err != nil {
+			fmt.Printf("Error loading .env: %v\n", err)
+		}
+	}
+
+	repo, err := repository.NewPostgresRepository()
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize database: %v", err)
+	}
+
+	// Rebuilding lists no pages, so it needs no cursor codec
+	productService := service.NewProductService(repo, nil)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *interval <= 0 {
+		if err := rebuild(ctx, productService); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		return
+	}
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		// A failed rebuild keeps the last suggestions and is retried on the
+		// next tick
+		if err := rebuild(ctx, productService); err != nil {
+			log.Printf("❌ %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func rebuild(ctx context.Context, productService *service.ProductService) error {
+	count, err := productService.RebuildSuggestions(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✅ Stored %d suggestions\n", count)
+	return nil
+}
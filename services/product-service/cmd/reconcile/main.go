@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"product-service/internal/repository"
+	"product-service/internal/service"
+
+	"github.com/joho/godotenv"
+)
+
+// reconcile recomputes every product's stock from the stock_movements ledger
+// and reports products whose stored stock has drifted. It exits with status 2
+// when drift is found so it can alert from a scheduled job.
+func main() {
+	var (
+		format = flag.String("format", "text", "Output format: text, json")
+	)
+	flag.Parse()
+
+	// Try to load .env file for local development only
+	if _, err := os.Stat("../../.env"); err == nil {
+		if err := godotenv.Load("../../.env"); err != nil {
+			fmt.Printf("Error loading .env: %v\n", err)
+		}
+	}
+
+	repo, err := repository.NewPostgresRepository()
+	if err != nil {
+		log.Fatalf("❌ Failed to initialize database: %v", err)
+	}
+
+	// Reconciling lists no pages, so it needs no cursor codec
+	drifts, err := service.NewProductService(repo, nil).ReconcileStock(context.Background())
+	if err != nil {
+		log.Fatalf("❌ Reconciliation failed: %v", err)
+	}
+
+	switch *format {
+	case "json":
+		output, _ := json.MarshalIndent(drifts, "", "  ")
+		fmt.Println(string(output))
+	default:
+		if len(drifts) == 0 {
+			fmt.Println("✅ Stock matches the ledger for every product")
+		}
+		for _, drift := range drifts {
+			fmt.Printf("⚠️  %s (%s): stock=%d ledger=%d drift=%+d\n",
+				drift.SKU, drift.ProductID, drift.Stock, drift.LedgerStock, drift.Drift)
+		}
+	}
+
+	if len(drifts) > 0 {
+		os.Exit(2)
+	}
+}
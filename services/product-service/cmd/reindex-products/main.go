@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"product-service/internal/repository"
+
+	"github.com/joho/godotenv"
+)
+
+// reindex-products sets the listing attributes DynamoDB listings are paged
+// through on every product of a table. Products written since the listing
+// indexes were added carry them already; run it once after adding the
+// indexes to the table.
+func main() {
+	var (
+		table = flag.String("table", "", "DynamoDB products table")
+	)
+	flag.Parse()
+
+	if *table == "" {
+		log.Fatal("❌ -table is required")
+	}
+
+	// Try to load .env file for local development only
+	if _, err := os.Stat("../../.env"); err == nil {
+		if err := godotenv.Load("../../.env"); err != nil {
+			fmt.Printf("Error loading .env: %v\n", err)
+		}
+	}
+
+	reindexed, err := repository.NewDynamoDBRepository(*table).ReindexProducts(context.Background())
+	if err != nil {
+		log.Fatalf("❌ Reindexing failed after %d products: %v", reindexed, err)
+	}
+
+	fmt.Printf("✅ Reindexed %d products\n", reindexed)
+}
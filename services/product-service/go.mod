@@ -8,12 +8,20 @@ require (
 	github.com/go-playground/validator/v10 v10.16.0
 	github.com/google/uuid v1.4.0
 	github.com/joho/godotenv v1.5.1
+	github.com/lib/pq v1.10.9
+	gorm.io/driver/postgres v1.5.2
 	gorm.io/gorm v1.30.3
+	shared/apperr v0.0.0
+	shared/auth v0.0.0
 	shared/db v0.0.0
 )
 
 replace shared/db => ../../shared/db
 
+replace shared/apperr => ../../shared/apperr
+
+replace shared/auth => ../../shared/auth
+
 require (
 	github.com/gabriel-vasile/mimetype v1.4.2 // indirect
 	github.com/go-playground/locales v0.14.1 // indirect
@@ -25,10 +33,8 @@ require (
 	github.com/jinzhu/now v1.1.5 // indirect
 	github.com/jmespath/go-jmespath v0.4.0 // indirect
 	github.com/leodido/go-urn v1.2.4 // indirect
-	github.com/lib/pq v1.10.9 // indirect
 	golang.org/x/crypto v0.8.0 // indirect
 	golang.org/x/net v0.9.0 // indirect
 	golang.org/x/sys v0.7.0 // indirect
 	golang.org/x/text v0.20.0 // indirect
-	gorm.io/driver/postgres v1.5.2 // indirect
 )
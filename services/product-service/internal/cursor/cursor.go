@@ -0,0 +1,116 @@
+// Package cursor issues the opaque tokens that page through product listings.
+// Tokens are signed, so clients cannot forge a position or reuse a token
+// with a different query.
+package cursor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"product-service/internal/models"
+)
+
+// ErrInvalidCursor is returned for tokens that are malformed, were not signed
+// with the key or belong to another query
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// minKeySize is the shortest signing key accepted, in bytes
+const minKeySize = 32
+
+// Cursor marks a place in a product listing by the sort values of the product
+// at the edge of a page
+type Cursor struct {
+	Query    string            `json:"q"`
+	Backward bool              `json:"b,omitempty"`
+	Values   []json.RawMessage `json:"v"`
+}
+
+// Codec signs and verifies cursor tokens
+type Codec struct {
+	key []byte
+}
+
+// NewCodec creates a codec signing with key
+func NewCodec(key []byte) (*Codec, error) {
+	if len(key) < minKeySize {
+		return nil, fmt.Errorf("cursor signing key must be at least %d bytes, got %d", minKeySize, len(key))
+	}
+	return &Codec{key: key}, nil
+}
+
+// NewCodecFromEnv creates a codec from CURSOR_SIGNING_KEY (base64), which
+// every instance must share so that a cursor works on any of them
+func NewCodecFromEnv() (*Codec, error) {
+	encoded := os.Getenv("CURSOR_SIGNING_KEY")
+	if encoded == "" {
+		return nil, errors.New("CURSOR_SIGNING_KEY is not set")
+	}
+
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode CURSOR_SIGNING_KEY: %w", err)
+	}
+	return NewCodec(key)
+}
+
+// Encode signs a cursor into a token
+func (c *Codec) Encode(cursor Cursor) (string, error) {
+	payload, err := json.Marshal(cursor)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(c.sign(encoded)), nil
+}
+
+// Decode verifies a token and returns its cursor, which must belong to query
+func (c *Codec) Decode(token string, query string) (*Cursor, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	expected, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, c.sign(encoded)) {
+		return nil, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(payload, &cursor); err != nil || cursor.Query != query {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+func (c *Codec) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
+
+// Query fingerprints what a listing selects and how it is sorted, leaving
+// out paging, so a cursor only continues the listing that issued it
+func Query(filter models.ProductFilter) string {
+	filter.CategoryIDs = nil
+	filter.Limit = 0
+	filter.Offset = 0
+	filter.Cursor = ""
+	filter.Seek = nil
+
+	payload, _ := json.Marshal(filter)
+	sum := sha256.Sum256(payload)
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
@@ -0,0 +1,19 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestNewCodecFromEnvRequiresKey(t *testing.T) {
+	t.Setenv("CURSOR_SIGNING_KEY", "")
+	if _, err := NewCodecFromEnv(); err == nil {
+		t.Fatal("NewCodecFromEnv succeeded without CURSOR_SIGNING_KEY")
+	}
+
+	t.Setenv("CURSOR_SIGNING_KEY", base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", minKeySize))))
+	if _, err := NewCodecFromEnv(); err != nil {
+		t.Fatalf("NewCodecFromEnv failed: %v", err)
+	}
+}
@@ -0,0 +1,263 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// maxInOperands is the most operands DynamoDB accepts in one IN
+const maxInOperands = 100
+
+// DynamoExpression is a filter expression for a Scan or Query of products
+type DynamoExpression struct {
+	Expression string
+	Names      map[string]*string
+	Values     map[string]*dynamodb.AttributeValue
+}
+
+// Apply sets the expression as the filter of a Scan
+func (d *DynamoExpression) Apply(input *dynamodb.ScanInput) {
+	if d == nil || d.Expression == "" {
+		return
+	}
+	input.FilterExpression = aws.String(d.Expression)
+	input.ExpressionAttributeNames = d.Names
+	input.ExpressionAttributeValues = d.Values
+}
+
+// ApplyQuery sets the expression as the filter of a Query, alongside the
+// names and values of its key condition
+func (d *DynamoExpression) ApplyQuery(input *dynamodb.QueryInput) {
+	if d == nil || d.Expression == "" {
+		return
+	}
+	input.FilterExpression = aws.String(d.Expression)
+	if input.ExpressionAttributeNames == nil {
+		input.ExpressionAttributeNames = make(map[string]*string)
+	}
+	for placeholder, name := range d.Names {
+		input.ExpressionAttributeNames[placeholder] = name
+	}
+	if input.ExpressionAttributeValues == nil {
+		input.ExpressionAttributeValues = make(map[string]*dynamodb.AttributeValue)
+	}
+	for placeholder, value := range d.Values {
+		input.ExpressionAttributeValues[placeholder] = value
+	}
+}
+
+// DynamoFilter splits expr into a filter expression for DynamoDB and a
+// residual to Match in memory, for what DynamoDB cannot express, like a
+// search that ignores case. Products must pass both.
+func DynamoFilter(expr Expr) (*DynamoExpression, Expr, error) {
+	pushed, residual := splitDynamo(expr)
+
+	builder := &dynamoBuilder{
+		names:  make(map[string]*string),
+		values: make(map[string]*dynamodb.AttributeValue),
+	}
+	expression, err := builder.build(pushed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &DynamoExpression{Expression: expression, Names: builder.names, Values: builder.values}, residual, nil
+}
+
+// splitDynamo separates the operands of a top-level And that DynamoDB can
+// evaluate from those it cannot
+func splitDynamo(expr Expr) (Expr, Expr) {
+	operands, ok := expr.(And)
+	if !ok {
+		if dynamoSupports(expr) {
+			return expr, And{}
+		}
+		return And{}, expr
+	}
+
+	var pushed, residual And
+	for _, operand := range operands {
+		if dynamoSupports(operand) {
+			pushed = append(pushed, operand)
+		} else {
+			residual = append(residual, operand)
+		}
+	}
+	return pushed, residual
+}
+
+func dynamoSupports(expr Expr) bool {
+	switch e := expr.(type) {
+	case And:
+		for _, operand := range e {
+			if !dynamoSupports(operand) {
+				return false
+			}
+		}
+		return true
+	case Or:
+		for _, operand := range e {
+			if !dynamoSupports(operand) {
+				return false
+			}
+		}
+		return true
+	case Cond:
+		return true
+	default:
+		return false
+	}
+}
+
+type dynamoBuilder struct {
+	names  map[string]*string
+	values map[string]*dynamodb.AttributeValue
+}
+
+func (b *dynamoBuilder) name(field Field) string {
+	placeholder := "#" + string(field)
+	b.names[placeholder] = aws.String(string(field))
+	return placeholder
+}
+
+func (b *dynamoBuilder) value(value *dynamodb.AttributeValue) string {
+	placeholder := fmt.Sprintf(":v%d", len(b.values))
+	b.values[placeholder] = value
+	return placeholder
+}
+
+// build returns the expression of expr, empty when it always holds
+func (b *dynamoBuilder) build(expr Expr) (string, error) {
+	switch e := expr.(type) {
+	case And:
+		return b.join(e, " AND ")
+	case Or:
+		return b.join(e, " OR ")
+	case Cond:
+		return b.cond(e)
+	default:
+		return "", fmt.Errorf("filter expression %T cannot run in DynamoDB", expr)
+	}
+}
+
+func (b *dynamoBuilder) join(exprs []Expr, separator string) (string, error) {
+	parts := make([]string, 0, len(exprs))
+	for _, expr := range exprs {
+		part, err := b.build(expr)
+		if err != nil {
+			return "", err
+		}
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	switch len(parts) {
+	case 0:
+		return "", nil
+	case 1:
+		return parts[0], nil
+	default:
+		return "(" + strings.Join(parts, separator) + ")", nil
+	}
+}
+
+func (b *dynamoBuilder) cond(cond Cond) (string, error) {
+	switch cond.Field {
+	case FieldCategoryID, FieldDepartmentID, FieldBrand:
+		return b.stringCond(cond)
+	case FieldPrice, FieldRating:
+		return b.numberCond(b.name(cond.Field), cond)
+	case FieldAvailable:
+		// Items written before reservations existed have no available attribute
+		available, err := b.numberCond(b.name(FieldAvailable), cond)
+		if err != nil {
+			return "", err
+		}
+		stock, err := b.numberCond(b.name("stock"), cond)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(%s OR (attribute_not_exists(%s) AND %s))", available, b.name(FieldAvailable), stock), nil
+	case FieldIsOnSale, FieldIsActive:
+		value, ok := cond.Value.(bool)
+		if !ok {
+			return "", invalidValue(cond)
+		}
+		if cond.Op != OpEq {
+			return "", invalidOp(cond)
+		}
+		return fmt.Sprintf("%s = %s", b.name(cond.Field), b.value(&dynamodb.AttributeValue{BOOL: aws.Bool(value)})), nil
+	case FieldTags:
+		values, ok := cond.Value.([]string)
+		if !ok || len(values) == 0 {
+			return "", invalidValue(cond)
+		}
+		separator, ok := map[Op]string{OpContainsAll: " AND ", OpContainsAny: " OR "}[cond.Op]
+		if !ok {
+			return "", invalidOp(cond)
+		}
+		parts := make([]string, len(values))
+		for i, value := range values {
+			parts[i] = fmt.Sprintf("contains(%s, %s)", b.name(FieldTags), b.value(&dynamodb.AttributeValue{S: aws.String(value)}))
+		}
+		return "(" + strings.Join(parts, separator) + ")", nil
+	default:
+		return "", fmt.Errorf("unknown filter field %q", cond.Field)
+	}
+}
+
+func (b *dynamoBuilder) stringCond(cond Cond) (string, error) {
+	name := b.name(cond.Field)
+
+	switch cond.Op {
+	case OpEq:
+		value, ok := cond.Value.(string)
+		if !ok {
+			return "", invalidValue(cond)
+		}
+		return fmt.Sprintf("%s = %s", name, b.value(&dynamodb.AttributeValue{S: aws.String(value)})), nil
+	case OpIn:
+		values, ok := cond.Value.([]string)
+		if !ok || len(values) == 0 {
+			return "", invalidValue(cond)
+		}
+
+		// Split into OR'ed groups of at most maxInOperands
+		var groups []string
+		for start := 0; start < len(values); start += maxInOperands {
+			end := start + maxInOperands
+			if end > len(values) {
+				end = len(values)
+			}
+
+			operands := make([]string, 0, end-start)
+			for _, value := range values[start:end] {
+				operands = append(operands, b.value(&dynamodb.AttributeValue{S: aws.String(value)}))
+			}
+			groups = append(groups, fmt.Sprintf("%s IN (%s)", name, strings.Join(operands, ", ")))
+		}
+		return "(" + strings.Join(groups, " OR ") + ")", nil
+	default:
+		return "", invalidOp(cond)
+	}
+}
+
+func (b *dynamoBuilder) numberCond(name string, cond Cond) (string, error) {
+	value, ok := number(cond.Value)
+	if !ok {
+		return "", invalidValue(cond)
+	}
+
+	operator, ok := map[Op]string{OpEq: "=", OpGt: ">", OpGte: ">=", OpLte: "<="}[cond.Op]
+	if !ok {
+		return "", invalidOp(cond)
+	}
+
+	placeholder := b.value(&dynamodb.AttributeValue{N: aws.String(strconv.FormatFloat(value, 'f', -1, 64))})
+	return fmt.Sprintf("%s %s %s", name, operator, placeholder), nil
+}
@@ -0,0 +1,283 @@
+package filter_test
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"product-service/internal/filter"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+)
+
+// evaluateDynamo evaluates a filter expression against an item as DynamoDB
+// does, for the subset of the expression syntax DynamoFilter writes:
+// comparisons, IN, contains, attribute_not_exists, AND, OR and parentheses
+func evaluateDynamo(condition *filter.DynamoExpression, item map[string]*dynamodb.AttributeValue) (bool, error) {
+	if condition == nil || condition.Expression == "" {
+		return true, nil
+	}
+
+	e := &dynamoEvaluator{tokens: dynamoTokens(condition.Expression), condition: condition, item: item}
+	ok, err := e.or()
+	if err != nil {
+		return false, err
+	}
+	if e.pos != len(e.tokens) {
+		return false, fmt.Errorf("unexpected %q", e.tokens[e.pos])
+	}
+	return ok, nil
+}
+
+// dynamoTokens splits an expression into names, values, keywords, operators
+// and punctuation
+func dynamoTokens(expression string) []string {
+	var tokens []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+
+	for _, r := range expression {
+		switch {
+		case r == ' ':
+			flush()
+		case r == '(' || r == ')' || r == ',':
+			flush()
+			tokens = append(tokens, string(r))
+		default:
+			word.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+type dynamoEvaluator struct {
+	tokens    []string
+	pos       int
+	condition *filter.DynamoExpression
+	item      map[string]*dynamodb.AttributeValue
+}
+
+func (e *dynamoEvaluator) peek() string {
+	if e.pos < len(e.tokens) {
+		return e.tokens[e.pos]
+	}
+	return ""
+}
+
+func (e *dynamoEvaluator) next() string {
+	token := e.peek()
+	e.pos++
+	return token
+}
+
+func (e *dynamoEvaluator) expect(token string) error {
+	if got := e.next(); got != token {
+		return fmt.Errorf("expected %q, got %q", token, got)
+	}
+	return nil
+}
+
+// or and and evaluate every operand, so that the whole expression is checked
+func (e *dynamoEvaluator) or() (bool, error) {
+	result, err := e.and()
+	for err == nil && e.peek() == "OR" {
+		e.next()
+		var ok bool
+		ok, err = e.and()
+		result = result || ok
+	}
+	return result, err
+}
+
+func (e *dynamoEvaluator) and() (bool, error) {
+	result, err := e.unary()
+	for err == nil && e.peek() == "AND" {
+		e.next()
+		var ok bool
+		ok, err = e.unary()
+		result = result && ok
+	}
+	return result, err
+}
+
+func (e *dynamoEvaluator) unary() (bool, error) {
+	switch token := e.peek(); token {
+	case "(":
+		e.next()
+		ok, err := e.or()
+		if err != nil {
+			return false, err
+		}
+		return ok, e.expect(")")
+	case "contains", "attribute_not_exists":
+		return e.function()
+	default:
+		return e.comparison()
+	}
+}
+
+func (e *dynamoEvaluator) function() (bool, error) {
+	name := e.next()
+	if err := e.expect("("); err != nil {
+		return false, err
+	}
+	path, err := e.operand()
+	if err != nil {
+		return false, err
+	}
+
+	var ok bool
+	switch name {
+	case "attribute_not_exists":
+		ok = path == nil
+	case "contains":
+		if err := e.expect(","); err != nil {
+			return false, err
+		}
+		value, err := e.operand()
+		if err != nil {
+			return false, err
+		}
+		ok = dynamoContains(path, value)
+	}
+	return ok, e.expect(")")
+}
+
+func (e *dynamoEvaluator) comparison() (bool, error) {
+	left, err := e.operand()
+	if err != nil {
+		return false, err
+	}
+
+	operator := e.next()
+	if operator == "IN" {
+		if err := e.expect("("); err != nil {
+			return false, err
+		}
+		found := false
+		for {
+			value, err := e.operand()
+			if err != nil {
+				return false, err
+			}
+			if c, ok := dynamoCompare(left, value); ok && c == 0 {
+				found = true
+			}
+			if e.peek() != "," {
+				break
+			}
+			e.next()
+		}
+		return found, e.expect(")")
+	}
+
+	right, err := e.operand()
+	if err != nil {
+		return false, err
+	}
+	c, ok := dynamoCompare(left, right)
+	if !ok {
+		// Comparisons with missing attributes or of other types never hold
+		return false, nil
+	}
+
+	switch operator {
+	case "=":
+		return c == 0, nil
+	case "<>":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	default:
+		return false, fmt.Errorf("unknown operator %q", operator)
+	}
+}
+
+// operand resolves a name to the attribute of the item it names, nil when
+// missing, and a value to itself
+func (e *dynamoEvaluator) operand() (*dynamodb.AttributeValue, error) {
+	token := e.next()
+	switch {
+	case strings.HasPrefix(token, "#"):
+		name, ok := e.condition.Names[token]
+		if !ok {
+			return nil, fmt.Errorf("undefined name %s", token)
+		}
+		return e.item[*name], nil
+	case strings.HasPrefix(token, ":"):
+		value, ok := e.condition.Values[token]
+		if !ok {
+			return nil, fmt.Errorf("undefined value %s", token)
+		}
+		return value, nil
+	default:
+		return nil, fmt.Errorf("expected a name or value, got %q", token)
+	}
+}
+
+// dynamoCompare orders two values of the same type, telling whether they are
+func dynamoCompare(a, b *dynamodb.AttributeValue) (int, bool) {
+	switch {
+	case a == nil || b == nil:
+		return 0, false
+	case a.N != nil && b.N != nil:
+		x, errX := strconv.ParseFloat(*a.N, 64)
+		y, errY := strconv.ParseFloat(*b.N, 64)
+		if errX != nil || errY != nil {
+			return 0, false
+		}
+		switch {
+		case x < y:
+			return -1, true
+		case x > y:
+			return 1, true
+		}
+		return 0, true
+	case a.S != nil && b.S != nil:
+		return strings.Compare(*a.S, *b.S), true
+	case a.BOOL != nil && b.BOOL != nil:
+		if *a.BOOL == *b.BOOL {
+			return 0, true
+		}
+		return 1, true
+	default:
+		return 0, false
+	}
+}
+
+// dynamoContains tells whether a string holds a substring, or a list or
+// string set holds a string
+func dynamoContains(path, value *dynamodb.AttributeValue) bool {
+	if path == nil || value == nil || value.S == nil {
+		return false
+	}
+	switch {
+	case path.S != nil:
+		return strings.Contains(*path.S, *value.S)
+	case path.SS != nil:
+		for _, s := range path.SS {
+			if *s == *value.S {
+				return true
+			}
+		}
+	case path.L != nil:
+		for _, element := range path.L {
+			if element.S != nil && *element.S == *value.S {
+				return true
+			}
+		}
+	}
+	return false
+}
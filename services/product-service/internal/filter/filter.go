@@ -0,0 +1,284 @@
+// Package filter describes product filters as a tree that each repository
+// backend translates, so that a filter selects the same products on all of them
+package filter
+
+import (
+	"fmt"
+
+	"product-service/internal/models"
+)
+
+// Field names a product attribute a filter can test
+type Field string
+
+const (
+	FieldCategoryID   Field = "category_id"
+	FieldDepartmentID Field = "department_id"
+	FieldBrand        Field = "brand"
+	FieldPrice        Field = "price"
+	FieldRating       Field = "rating"
+	FieldIsOnSale     Field = "is_on_sale"
+	FieldIsActive     Field = "is_active"
+	FieldTags         Field = "tags"
+	FieldAvailable    Field = "available" // stock less reservations
+)
+
+// Op compares a field with the value of a condition
+type Op string
+
+const (
+	OpEq          Op = "eq"
+	OpIn          Op = "in"           // value is a []string
+	OpGt          Op = "gt"           // value is a float64 or int
+	OpGte         Op = "gte"          // value is a float64 or int
+	OpLte         Op = "lte"          // value is a float64 or int
+	OpContainsAll Op = "contains_all" // tags holds every value of a []string
+	OpContainsAny Op = "contains_any" // tags holds some value of a []string
+)
+
+// Expr is a node of a filter tree: a Cond, an And, an Or or a Search
+type Expr interface {
+	isExpr()
+}
+
+// Cond tests one field
+type Cond struct {
+	Field Field
+	Op    Op
+	Value interface{}
+}
+
+// And holds when all of its expressions do; an empty And always holds
+type And []Expr
+
+// Or holds when any of its expressions does
+type Or []Expr
+
+// Search matches products holding every word of Text, or a word starting
+// with it, in the SKU, name, brand, tags or description, ignoring case. A word
+// also matches through its synonyms, and a misspelled search matches the
+// names it looks like.
+type Search struct {
+	Text     string
+	Synonyms map[string][]string // other terms for lowercased words of Text
+}
+
+func (Cond) isExpr()   {}
+func (And) isExpr()    {}
+func (Or) isExpr()     {}
+func (Search) isExpr() {}
+
+// FromProductFilter builds the tree of a product filter, always limited to
+// active products. Paging is left to the caller.
+func FromProductFilter(filter models.ProductFilter) Expr {
+	expr := And{Cond{FieldIsActive, OpEq, true}}
+
+	if len(filter.CategoryIDs) > 0 {
+		expr = append(expr, Cond{FieldCategoryID, OpIn, filter.CategoryIDs})
+	} else if filter.CategoryID != "" {
+		expr = append(expr, Cond{FieldCategoryID, OpEq, filter.CategoryID})
+	}
+
+	if filter.DepartmentID != "" {
+		expr = append(expr, Cond{FieldDepartmentID, OpEq, filter.DepartmentID})
+	}
+
+	if filter.Brand != "" {
+		expr = append(expr, Cond{FieldBrand, OpEq, filter.Brand})
+	}
+
+	if filter.MinPrice != nil {
+		expr = append(expr, Cond{FieldPrice, OpGte, *filter.MinPrice})
+	}
+
+	if filter.MaxPrice != nil {
+		expr = append(expr, Cond{FieldPrice, OpLte, *filter.MaxPrice})
+	}
+
+	if filter.InStock != nil && *filter.InStock {
+		expr = append(expr, Cond{FieldAvailable, OpGt, 0})
+	}
+
+	if filter.IsOnSale != nil && *filter.IsOnSale {
+		expr = append(expr, Cond{FieldIsOnSale, OpEq, true})
+	}
+
+	if filter.MinRating != nil {
+		expr = append(expr, Cond{FieldRating, OpGte, *filter.MinRating})
+	}
+
+	if len(filter.Tags) > 0 {
+		op := OpContainsAll
+		if filter.TagMatch == models.TagMatchAny {
+			op = OpContainsAny
+		}
+		expr = append(expr, Cond{FieldTags, op, filter.Tags})
+	}
+
+	if filter.Search != "" {
+		expr = append(expr, SearchOf(filter))
+	}
+
+	return expr
+}
+
+// SearchOf returns the search of a product filter, with its synonyms
+func SearchOf(filter models.ProductFilter) Search {
+	return Search{Text: filter.Search, Synonyms: filter.Synonyms}
+}
+
+// Match evaluates expr against a product in memory, as the backends do in
+// their queries
+func Match(expr Expr, product *models.Product) (bool, error) {
+	switch e := expr.(type) {
+	case And:
+		for _, operand := range e {
+			ok, err := Match(operand, product)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case Or:
+		for _, operand := range e {
+			ok, err := Match(operand, product)
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	case Search:
+		_, ok := matchSearch(e, product)
+		return ok, nil
+	case Cond:
+		return matchCond(e, product)
+	default:
+		return false, fmt.Errorf("unknown filter expression %T", expr)
+	}
+}
+
+func matchCond(cond Cond, product *models.Product) (bool, error) {
+	switch cond.Field {
+	case FieldCategoryID:
+		return matchString(cond, product.CategoryID)
+	case FieldDepartmentID:
+		return matchString(cond, product.DepartmentID)
+	case FieldBrand:
+		return matchString(cond, product.Brand)
+	case FieldPrice:
+		return matchNumber(cond, product.Price)
+	case FieldRating:
+		return matchNumber(cond, product.Rating)
+	case FieldAvailable:
+		return matchNumber(cond, float64(product.Stock-product.Reserved))
+	case FieldIsOnSale:
+		return matchBool(cond, product.IsOnSale)
+	case FieldIsActive:
+		return matchBool(cond, product.IsActive)
+	case FieldTags:
+		return matchTags(cond, product.Tags)
+	default:
+		return false, fmt.Errorf("unknown filter field %q", cond.Field)
+	}
+}
+
+func matchString(cond Cond, value string) (bool, error) {
+	switch cond.Op {
+	case OpEq:
+		return value == cond.Value, nil
+	case OpIn:
+		values, ok := cond.Value.([]string)
+		if !ok {
+			return false, invalidValue(cond)
+		}
+		for _, v := range values {
+			if v == value {
+				return true, nil
+			}
+		}
+		return false, nil
+	default:
+		return false, invalidOp(cond)
+	}
+}
+
+func matchNumber(cond Cond, value float64) (bool, error) {
+	operand, ok := number(cond.Value)
+	if !ok {
+		return false, invalidValue(cond)
+	}
+
+	switch cond.Op {
+	case OpEq:
+		return value == operand, nil
+	case OpGt:
+		return value > operand, nil
+	case OpGte:
+		return value >= operand, nil
+	case OpLte:
+		return value <= operand, nil
+	default:
+		return false, invalidOp(cond)
+	}
+}
+
+func matchBool(cond Cond, value bool) (bool, error) {
+	operand, ok := cond.Value.(bool)
+	if !ok {
+		return false, invalidValue(cond)
+	}
+	if cond.Op != OpEq {
+		return false, invalidOp(cond)
+	}
+	return value == operand, nil
+}
+
+func matchTags(cond Cond, tags []string) (bool, error) {
+	values, ok := cond.Value.([]string)
+	if !ok {
+		return false, invalidValue(cond)
+	}
+	held := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		held[tag] = true
+	}
+
+	switch cond.Op {
+	case OpContainsAll:
+		for _, value := range values {
+			if !held[value] {
+				return false, nil
+			}
+		}
+		return true, nil
+	case OpContainsAny:
+		for _, value := range values {
+			if held[value] {
+				return true, nil
+			}
+		}
+		return false, nil
+	default:
+		return false, invalidOp(cond)
+	}
+}
+
+// number reads the numeric value of a condition
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+func invalidOp(cond Cond) error {
+	return fmt.Errorf("operator %q does not apply to %s", cond.Op, cond.Field)
+}
+
+func invalidValue(cond Cond) error {
+	return fmt.Errorf("invalid value %v for %s %s", cond.Value, cond.Field, cond.Op)
+}
@@ -0,0 +1,308 @@
+package filter_test
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"product-service/internal/filter"
+	"product-service/internal/models"
+	"product-service/internal/repository"
+
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/uuid"
+)
+
+// fixture holds the IDs of the categories and departments the fixture
+// products are in, as each backend stores them
+type fixture struct {
+	fruit, dairy    string // categories
+	produce, fridge string // departments
+}
+
+// filterCase is a filter and the fixture products it selects, by SKU. Every
+// translation of filters runs the same cases, so that they select the same
+// products on every backend.
+type filterCase struct {
+	name string
+	expr func(f fixture) filter.Expr
+	want []string
+}
+
+func ptr[T any](value T) *T {
+	return &value
+}
+
+// fixtureProducts are the products the cases filter, by SKU. Pears are all
+// reserved, cheese is out of stock, milk was written before reservations
+// existed and bread is inactive.
+func fixtureProducts(f fixture) []models.Product {
+	return []models.Product{
+		{SKU: "apple", Name: "Red Apple", Description: "Crisp and sweet", Brand: "Orchard", CategoryID: f.fruit, DepartmentID: f.produce,
+			Price: 10, Rating: 4.5, Stock: 5, IsOnSale: true, Discount: ptr(10.0), IsActive: true, Tags: []string{"fruit", "organic"}},
+		{SKU: "pear", Name: "Green Pear", Description: "Juicy", Brand: "Orchard", CategoryID: f.fruit, DepartmentID: f.produce,
+			Price: 20, Rating: 3, Stock: 3, Reserved: 3, IsActive: true, Tags: []string{"fruit"}},
+		{SKU: "milk", Name: "Whole Milk", Description: "Fresh", Brand: "Vaca", CategoryID: f.dairy, DepartmentID: f.fridge,
+			Price: 15, Rating: 4, Stock: 10, IsActive: true, Tags: []string{"dairy", "organic"}},
+		{SKU: "cheese", Name: "Aged Cheese", Description: "Sharp", Brand: "Vaca", CategoryID: f.dairy, DepartmentID: f.fridge,
+			Price: 50, Rating: 5, Stock: 0, IsOnSale: true, Discount: ptr(5.0), IsActive: true, Tags: []string{"dairy"}},
+		{SKU: "bread", Name: "Rye Bread", Description: "Baked daily", Brand: "Vaca", CategoryID: f.dairy, DepartmentID: f.fridge,
+			Price: 5, Rating: 4, Stock: 10, IsActive: false, Tags: []string{"organic"}},
+	}
+}
+
+// legacySKU is the fixture product stored without an available attribute
+const legacySKU = "milk"
+
+var filterCases = []filterCase{
+	{
+		name: "active",
+		expr: func(f fixture) filter.Expr { return filter.FromProductFilter(models.ProductFilter{}) },
+		want: []string{"apple", "cheese", "milk", "pear"},
+	},
+	{
+		name: "category",
+		expr: func(f fixture) filter.Expr {
+			return filter.FromProductFilter(models.ProductFilter{CategoryID: f.fruit})
+		},
+		want: []string{"apple", "pear"},
+	},
+	{
+		name: "categories",
+		expr: func(f fixture) filter.Expr {
+			return filter.FromProductFilter(models.ProductFilter{CategoryIDs: []string{f.fruit, f.dairy}})
+		},
+		want: []string{"apple", "cheese", "milk", "pear"},
+	},
+	{
+		name: "department",
+		expr: func(f fixture) filter.Expr {
+			return filter.FromProductFilter(models.ProductFilter{DepartmentID: f.fridge})
+		},
+		want: []string{"cheese", "milk"},
+	},
+	{
+		name: "brand",
+		expr: func(f fixture) filter.Expr { return filter.FromProductFilter(models.ProductFilter{Brand: "Orchard"}) },
+		want: []string{"apple", "pear"},
+	},
+	{
+		name: "price range",
+		expr: func(f fixture) filter.Expr {
+			return filter.FromProductFilter(models.ProductFilter{MinPrice: ptr(15.0), MaxPrice: ptr(20.0)})
+		},
+		want: []string{"milk", "pear"},
+	},
+	{
+		name: "in stock",
+		expr: func(f fixture) filter.Expr { return filter.FromProductFilter(models.ProductFilter{InStock: ptr(true)}) },
+		want: []string{"apple", "milk"},
+	},
+	{
+		name: "on sale",
+		expr: func(f fixture) filter.Expr {
+			return filter.FromProductFilter(models.ProductFilter{IsOnSale: ptr(true)})
+		},
+		want: []string{"apple", "cheese"},
+	},
+	{
+		name: "min rating",
+		expr: func(f fixture) filter.Expr {
+			return filter.FromProductFilter(models.ProductFilter{MinRating: ptr(4.0)})
+		},
+		want: []string{"apple", "cheese", "milk"},
+	},
+	{
+		name: "all tags",
+		expr: func(f fixture) filter.Expr {
+			return filter.FromProductFilter(models.ProductFilter{Tags: []string{"fruit", "organic"}})
+		},
+		want: []string{"apple"},
+	},
+	{
+		name: "any tag",
+		expr: func(f fixture) filter.Expr {
+			return filter.FromProductFilter(models.ProductFilter{Tags: []string{"dairy", "organic"}, TagMatch: models.TagMatchAny})
+		},
+		want: []string{"apple", "cheese", "milk"},
+	},
+	{
+		name: "or",
+		expr: func(f fixture) filter.Expr {
+			return filter.And{
+				filter.Cond{Field: filter.FieldIsActive, Op: filter.OpEq, Value: true},
+				filter.Or{
+					filter.And{
+						filter.Cond{Field: filter.FieldBrand, Op: filter.OpEq, Value: "Orchard"},
+						filter.Cond{Field: filter.FieldPrice, Op: filter.OpLte, Value: 10},
+					},
+					filter.Cond{Field: filter.FieldRating, Op: filter.OpGte, Value: 5},
+				},
+			}
+		},
+		want: []string{"apple", "cheese"},
+	},
+	{
+		name: "search",
+		expr: func(f fixture) filter.Expr { return filter.FromProductFilter(models.ProductFilter{Search: "appl"}) },
+		want: []string{"apple"},
+	},
+	{
+		name: "search with a filter",
+		expr: func(f fixture) filter.Expr {
+			return filter.FromProductFilter(models.ProductFilter{Search: "vaca", InStock: ptr(true)})
+		},
+		want: []string{"milk"},
+	},
+	{
+		name: "nothing",
+		expr: func(f fixture) filter.Expr {
+			return filter.FromProductFilter(models.ProductFilter{MinPrice: ptr(1000.0)})
+		},
+		want: nil,
+	},
+}
+
+// memoryFixture names categories and departments after themselves, as
+// nothing stores them
+var memoryFixture = fixture{fruit: "fruit", dairy: "dairy", produce: "produce", fridge: "fridge"}
+
+// assertSelects compares the SKUs a case selected with those it wants
+func assertSelects(t *testing.T, c filterCase, got []string) {
+	t.Helper()
+	sort.Strings(got)
+	if strings.Join(got, ",") != strings.Join(c.want, ",") {
+		t.Errorf("%s selected %v, want %v", c.name, got, c.want)
+	}
+}
+
+func TestFilterCasesMatch(t *testing.T) {
+	products := fixtureProducts(memoryFixture)
+
+	for _, c := range filterCases {
+		expr := c.expr(memoryFixture)
+
+		var got []string
+		for i := range products {
+			ok, err := filter.Match(expr, &products[i])
+			if err != nil {
+				t.Fatalf("%s: %v", c.name, err)
+			}
+			if ok {
+				got = append(got, products[i].SKU)
+			}
+		}
+		assertSelects(t, c, got)
+	}
+}
+
+func TestFilterCasesDynamoFilter(t *testing.T) {
+	products := fixtureProducts(memoryFixture)
+
+	for _, c := range filterCases {
+		condition, residual, err := filter.DynamoFilter(c.expr(memoryFixture))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+
+		var got []string
+		for i := range products {
+			// Items are marshalled as the DynamoDB repository writes them
+			products[i].Available = products[i].Stock - products[i].Reserved
+			item, err := dynamodbattribute.MarshalMap(products[i])
+			if err != nil {
+				t.Fatalf("failed to marshal product: %v", err)
+			}
+			if products[i].SKU == legacySKU {
+				delete(item, "available")
+			}
+
+			ok, err := evaluateDynamo(condition, item)
+			if err != nil {
+				t.Fatalf("%s: %s: %v", c.name, condition.Expression, err)
+			}
+			if !ok {
+				continue
+			}
+
+			ok, err = filter.Match(residual, &products[i])
+			if err != nil {
+				t.Fatalf("%s: %v", c.name, err)
+			}
+			if ok {
+				got = append(got, products[i].SKU)
+			}
+		}
+		assertSelects(t, c, got)
+	}
+}
+
+// TestFilterCasesGormExpression runs the cases against the database the DB_*
+// variables name when TEST_POSTGRES is set
+func TestFilterCasesGormExpression(t *testing.T) {
+	if os.Getenv("TEST_POSTGRES") == "" {
+		t.Skip("TEST_POSTGRES is not set")
+	}
+	repo, err := repository.NewPostgresRepository()
+	if err != nil {
+		t.Fatalf("failed to connect to postgres: %v", err)
+	}
+	ctx := context.Background()
+
+	// SKUs and slugs are unique, so those of this run carry a suffix
+	suffix := "-" + uuid.New().String()[:8]
+	var f fixture
+	for _, id := range []*string{&f.fruit, &f.dairy} {
+		category := &models.Category{Name: "Test" + suffix, Slug: "test-" + uuid.New().String()[:8]}
+		if err := repo.CreateCategory(ctx, category); err != nil {
+			t.Fatalf("failed to create category: %v", err)
+		}
+		*id = category.ID
+	}
+	for _, id := range []*string{&f.produce, &f.fridge} {
+		department := &models.Department{Name: "Test" + suffix, Slug: "test-" + uuid.New().String()[:8]}
+		if err := repo.CreateDepartment(ctx, department); err != nil {
+			t.Fatalf("failed to create department: %v", err)
+		}
+		*id = department.ID
+	}
+
+	var ids []string
+	for _, product := range fixtureProducts(f) {
+		product := product
+		isActive := product.IsActive
+		product.Slug = product.SKU + suffix
+		product.SKU += suffix
+		if err := repo.CreateProduct(ctx, &product); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+		// CreateProduct makes products active
+		if !isActive {
+			if err := repo.DB.Model(&models.Product{}).Where("id = ?", product.ID).Update("is_active", false).Error; err != nil {
+				t.Fatalf("failed to deactivate product: %v", err)
+			}
+		}
+		ids = append(ids, product.ID)
+	}
+
+	for _, c := range filterCases {
+		condition, err := filter.GormExpression(c.expr(f))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+
+		var skus []string
+		result := repo.DB.Model(&models.Product{}).Where(condition).Where("id IN ?", ids).Pluck("sku", &skus)
+		if result.Error != nil {
+			t.Fatalf("%s: %v", c.name, result.Error)
+		}
+
+		got := make([]string, len(skus))
+		for i, sku := range skus {
+			got[i] = strings.TrimSuffix(sku, suffix)
+		}
+		assertSelects(t, c, got)
+	}
+}
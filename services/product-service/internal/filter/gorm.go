@@ -0,0 +1,92 @@
+package filter
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm/clause"
+)
+
+// gormColumns are the SQL expressions of the fields of the products table
+var gormColumns = map[Field]string{
+	FieldCategoryID:   "category_id",
+	FieldDepartmentID: "department_id",
+	FieldBrand:        "brand",
+	FieldPrice:        "price",
+	FieldRating:       "rating",
+	FieldIsOnSale:     "is_on_sale",
+	FieldIsActive:     "is_active",
+	FieldTags:         "tags",
+	FieldAvailable:    "(stock - reserved)",
+}
+
+// GormExpression translates expr into a condition on the products table
+func GormExpression(expr Expr) (clause.Expression, error) {
+	switch e := expr.(type) {
+	case And:
+		operands, err := gormOperands(e)
+		if err != nil {
+			return nil, err
+		}
+		return clause.And(operands...), nil
+	case Or:
+		operands, err := gormOperands(e)
+		if err != nil {
+			return nil, err
+		}
+		return clause.Or(operands...), nil
+	case Search:
+		return gormSearch(e), nil
+	case Cond:
+		return gormCond(e)
+	default:
+		return nil, fmt.Errorf("unknown filter expression %T", expr)
+	}
+}
+
+func gormOperands(exprs []Expr) ([]clause.Expression, error) {
+	operands := make([]clause.Expression, 0, len(exprs))
+	for _, expr := range exprs {
+		operand, err := GormExpression(expr)
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+	}
+	return operands, nil
+}
+
+func gormCond(cond Cond) (clause.Expression, error) {
+	column, ok := gormColumns[cond.Field]
+	if !ok {
+		return nil, fmt.Errorf("unknown filter field %q", cond.Field)
+	}
+
+	switch cond.Op {
+	case OpEq:
+		return clause.Expr{SQL: column + " = ?", Vars: []interface{}{cond.Value}}, nil
+	case OpIn:
+		values, ok := cond.Value.([]string)
+		if !ok {
+			return nil, invalidValue(cond)
+		}
+		return clause.Expr{SQL: column + " IN ?", Vars: []interface{}{values}}, nil
+	case OpGt, OpGte, OpLte:
+		value, ok := number(cond.Value)
+		if !ok {
+			return nil, invalidValue(cond)
+		}
+		operator := map[Op]string{OpGt: ">", OpGte: ">=", OpLte: "<="}[cond.Op]
+		return clause.Expr{SQL: fmt.Sprintf("%s %s ?", column, operator), Vars: []interface{}{value}}, nil
+	case OpContainsAll, OpContainsAny:
+		values, ok := cond.Value.([]string)
+		if !ok {
+			return nil, invalidValue(cond)
+		}
+		// Both operators can use the GIN index on tags
+		operator := map[Op]string{OpContainsAll: "@>", OpContainsAny: "&&"}[cond.Op]
+		return clause.Expr{SQL: fmt.Sprintf("%s %s ?::text[]", column, operator), Vars: []interface{}{pq.StringArray(values)}}, nil
+	default:
+		return nil, invalidOp(cond)
+	}
+}
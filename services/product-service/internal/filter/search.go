@@ -0,0 +1,271 @@
+package filter
+
+import (
+	"strings"
+	"unicode"
+
+	"product-service/internal/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// searchVector is the indexed full-text vector of a product, built by the
+// products_search_vector function of the 000005_products_search migration
+const searchVector = "products_search_vector(sku, name, brand, tags, description)"
+
+// searchQuery is the full-text query of a search, stemmed as Spanish or as
+// English. Its two placeholders take the same prefixQuery.
+const searchQuery = "(to_tsquery('spanish', ?) || to_tsquery('english', ?))"
+
+// searchRank is the weighted rank of a product for a search, as a double so
+// that cursors compare it exactly
+const searchRank = "CAST(ts_rank(" + searchVector + ", " + searchQuery + ") AS double precision)"
+
+// fuzzyName matches names holding words that look like those of a search,
+// by pg_trgm word similarity, using the trigram index of the
+// 000006_search_synonyms migration
+const fuzzyName = "? <% lower(name)"
+
+// snippetOptions highlight the matching words of up to two short fragments
+const snippetOptions = "StartSel=<mark>, StopSel=</mark>, MaxWords=20, MinWords=8, MaxFragments=2, FragmentDelimiter=\" … \""
+
+// searchWeights are the weights ts_rank gives by default to the weights the
+// search vector sets on each field, for ranking in memory
+var searchWeights = struct{ a, b, c float64 }{a: 1.0, b: 0.4, c: 0.2}
+
+const (
+	// fuzzySimilarity is how similar, by trigrams, a misspelled word must be
+	// to a word of a name to match it in memory
+	fuzzySimilarity = 0.5
+
+	// spellingSimilarity is the least trigram similarity of a spelling
+	// suggestion, the default similarity threshold of pg_trgm
+	spellingSimilarity = 0.3
+)
+
+// SearchWords splits a search into lowercased words, dropping punctuation
+func SearchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// alternatives returns the terms a word of a search matches, as words: the
+// word itself, then its synonyms
+func (s Search) alternatives(word string) [][]string {
+	alternatives := [][]string{{word}}
+	for _, synonym := range s.Synonyms[word] {
+		if words := SearchWords(synonym); len(words) > 0 {
+			alternatives = append(alternatives, words)
+		}
+	}
+	return alternatives
+}
+
+// prefixQuery writes a search as a tsquery matching every word, or one of
+// its synonyms, as a prefix, so that a partly typed last word still matches.
+// Words hold only letters and digits, so they need no quoting.
+func prefixQuery(search Search) string {
+	words := SearchWords(search.Text)
+	groups := make([]string, len(words))
+	for i, word := range words {
+		alternatives := search.alternatives(word)
+		terms := make([]string, len(alternatives))
+		for j, alternative := range alternatives {
+			for k := range alternative {
+				alternative[k] += ":*"
+			}
+			terms[j] = strings.Join(alternative, " & ")
+			if len(alternative) > 1 {
+				terms[j] = "(" + terms[j] + ")"
+			}
+		}
+
+		groups[i] = strings.Join(terms, " | ")
+		if len(terms) > 1 {
+			groups[i] = "(" + groups[i] + ")"
+		}
+	}
+	return strings.Join(groups, " & ")
+}
+
+// gormSearch matches the products holding every word of a search, and those
+// whose name looks like it
+func gormSearch(search Search) clause.Expression {
+	query := prefixQuery(search)
+	if query == "" {
+		return clause.Expr{SQL: "FALSE"}
+	}
+	text := strings.Join(SearchWords(search.Text), " ")
+	return clause.Expr{
+		SQL:  "(" + searchVector + " @@ " + searchQuery + " OR " + fuzzyName + ")",
+		Vars: []interface{}{query, query, text},
+	}
+}
+
+// GormSearchColumns selects the columns of products along with their rank
+// for a search and a snippet of their description with the matching words
+// highlighted, into Product.SearchRank and Product.Snippet
+func GormSearchColumns(search Search) (string, []interface{}) {
+	query := prefixQuery(search)
+	columns := "products.*, " + searchRank + " AS search_rank, " +
+		"ts_headline('spanish', coalesce(description, ''), " + searchQuery + ", ?) AS snippet"
+	return columns, []interface{}{query, query, query, query, snippetOptions}
+}
+
+// searchField is a field a search looks in, with the weight of its words
+type searchField struct {
+	text   string
+	weight float64
+}
+
+func searchFields(product *models.Product) []searchField {
+	return []searchField{
+		{product.SKU, searchWeights.a},
+		{product.Name, searchWeights.a},
+		{product.Brand, searchWeights.b},
+		{strings.Join(product.Tags, " "), searchWeights.b},
+		{product.Description, searchWeights.c},
+	}
+}
+
+// matchSearch tells whether every word of a search, or one of its synonyms,
+// starts a word of the product, as the full-text query does without
+// stemming, and ranks the product by the weights of the fields the words
+// were found in. Failing that, it matches names that look like the search,
+// ranked last.
+func matchSearch(search Search, product *models.Product) (float64, bool) {
+	words := SearchWords(search.Text)
+	if len(words) == 0 {
+		return 0, false
+	}
+
+	fields := searchFields(product)
+	fieldWords := make([][]string, len(fields))
+	for i, field := range fields {
+		fieldWords[i] = SearchWords(field.text)
+	}
+
+	rank := 0.0
+	for _, word := range words {
+		best := 0.0
+		for _, alternative := range search.alternatives(word) {
+			if weight := alternativeWeight(alternative, fields, fieldWords); weight > best {
+				best = weight
+			}
+		}
+		if best == 0 {
+			return 0, looksLike(words, SearchWords(product.Name))
+		}
+		rank += best
+	}
+
+	return rank, true
+}
+
+// alternativeWeight is the weight of the words of a term in a product: that
+// of the lightest field one of them is found in, or 0 when one is missing
+func alternativeWeight(words []string, fields []searchField, fieldWords [][]string) float64 {
+	weight := 0.0
+	for i, word := range words {
+		best := 0.0
+		for j, field := range fields {
+			for _, fieldWord := range fieldWords[j] {
+				if strings.HasPrefix(fieldWord, word) && field.weight > best {
+					best = field.weight
+				}
+			}
+		}
+		if i == 0 || best < weight {
+			weight = best
+		}
+	}
+	return weight
+}
+
+// looksLike tells whether every word is similar to some word of a name
+func looksLike(words []string, nameWords []string) bool {
+	for _, word := range words {
+		found := false
+		for _, nameWord := range nameWords {
+			if similarity(word, nameWord) >= fuzzySimilarity {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return len(nameWords) > 0
+}
+
+// Rank scores how well a product matches a search in memory, for backends
+// without full-text search. Higher ranks match better.
+func Rank(product *models.Product, search Search) float64 {
+	rank, _ := matchSearch(search, product)
+	return rank
+}
+
+// SpellingSuggestions maps each word no product name or brand holds to the
+// most similar word they do hold, as the Postgres repository does with
+// pg_trgm. Words without a similar enough one are left out.
+func SpellingSuggestions(products []models.Product, words []string) map[string]string {
+	vocabulary := make(map[string]bool)
+	for i := range products {
+		for _, word := range SearchWords(products[i].Name + " " + products[i].Brand) {
+			vocabulary[word] = true
+		}
+	}
+
+	suggestions := make(map[string]string)
+	for _, word := range words {
+		if vocabulary[word] {
+			continue
+		}
+
+		best, bestScore := "", 0.0
+		for candidate := range vocabulary {
+			score := similarity(word, candidate)
+			if score < spellingSimilarity {
+				continue
+			}
+			if score > bestScore || (score == bestScore && candidate < best) {
+				best, bestScore = candidate, score
+			}
+		}
+		if best != "" {
+			suggestions[word] = best
+		}
+	}
+
+	return suggestions
+}
+
+// similarity is the trigram similarity of two words, as pg_trgm computes it:
+// the trigrams they share over all of their trigrams
+func similarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for trigram := range ta {
+		if tb[trigram] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+// trigrams returns the trigrams of a word padded as pg_trgm pads it, with two
+// spaces before and one after
+func trigrams(word string) map[string]bool {
+	runes := []rune("  " + word + " ")
+	set := make(map[string]bool, len(runes))
+	for i := 0; i+3 <= len(runes); i++ {
+		set[string(runes[i:i+3])] = true
+	}
+	return set
+}
@@ -0,0 +1,341 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"product-service/internal/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// sortKey is one key of a product order: an SQL expression and the value it
+// takes for a product in memory, which must order the same way
+type sortKey struct {
+	sql   string
+	vars  []interface{}
+	desc  bool
+	value func(product *models.Product) interface{} // a float64, int, string or time.Time
+}
+
+// sortKeys returns the keys of a sort order of models.ProductSorts, ending
+// with the ID tiebreaker
+func sortKeys(order string, search Search) ([]sortKey, error) {
+	var keys []sortKey
+
+	switch order {
+	case models.SortPriceAsc:
+		keys = append(keys, sortKey{sql: "price", value: func(p *models.Product) interface{} { return p.Price }})
+	case models.SortPriceDesc:
+		keys = append(keys, sortKey{sql: "price", desc: true, value: func(p *models.Product) interface{} { return p.Price }})
+	case models.SortRating:
+		keys = append(keys, sortKey{sql: "rating", desc: true, value: func(p *models.Product) interface{} { return p.Rating }})
+	case models.SortNewest:
+		keys = append(keys, sortKey{sql: "created_at", desc: true, value: func(p *models.Product) interface{} { return p.CreatedAt.UTC() }})
+	case models.SortName:
+		// Byte order of the lowercased name, which Go strings compare by too
+		keys = append(keys, sortKey{sql: `LOWER(name) COLLATE "C"`, value: func(p *models.Product) interface{} { return strings.ToLower(p.Name) }})
+	case models.SortDiscount:
+		// Products without a discount come last
+		keys = append(keys, sortKey{sql: "COALESCE(discount, -1)", desc: true, value: func(p *models.Product) interface{} { return discount(p) }})
+	case models.SortRelevance:
+		if search.Text == "" {
+			return nil, fmt.Errorf("sort %s needs a search", order)
+		}
+		// Backends without full-text search rank products with Rank
+		query := prefixQuery(search)
+		keys = append(keys, sortKey{sql: searchRank, vars: []interface{}{query, query}, desc: true, value: func(p *models.Product) interface{} { return p.SearchRank }})
+	default:
+		return nil, fmt.Errorf("unknown sort %q", order)
+	}
+
+	keys = append(keys, sortKey{sql: "id", value: func(p *models.Product) interface{} { return p.ID }})
+	return keys, nil
+}
+
+// GormOrder translates a sort order into an ORDER BY clause on the products
+// table. Reversed, it lists products from the end, to read a page backwards.
+func GormOrder(order string, search Search, reverse bool) (clause.OrderBy, error) {
+	keys, err := sortKeys(order, search)
+	if err != nil {
+		return clause.OrderBy{}, err
+	}
+
+	terms := make([]string, len(keys))
+	var vars []interface{}
+	for i, key := range keys {
+		direction := "ASC"
+		if key.desc != reverse {
+			direction = "DESC"
+		}
+		terms[i] = key.sql + " " + direction
+		vars = append(vars, key.vars...)
+	}
+
+	return clause.OrderBy{Expression: clause.Expr{SQL: strings.Join(terms, ", "), Vars: vars, WithoutParentheses: true}}, nil
+}
+
+// SortProducts sorts products in memory as GormOrder sorts them in SQL
+func SortProducts(products []models.Product, order string, search Search) error {
+	keys, err := sortKeys(order, search)
+	if err != nil {
+		return err
+	}
+
+	sort.SliceStable(products, func(i, j int) bool {
+		return compareProducts(keys, &products[i], sortValues(keys, &products[j])) < 0
+	})
+	return nil
+}
+
+// SortValues returns the values a product takes for the keys of a sort
+// order, which mark its place in a listing
+func SortValues(product *models.Product, order string, search Search) ([]json.RawMessage, error) {
+	keys, err := sortKeys(order, search)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]json.RawMessage, len(keys))
+	for i, value := range sortValues(keys, product) {
+		if values[i], err = json.Marshal(value); err != nil {
+			return nil, fmt.Errorf("failed to encode sort value: %w", err)
+		}
+	}
+	return values, nil
+}
+
+// GormSeek translates the place marked by SortValues into a condition that
+// holds for the products after it, or before it when not forward
+func GormSeek(order string, search Search, values []json.RawMessage, forward bool) (clause.Expression, error) {
+	keys, bound, err := decodeSortValues(order, search, values)
+	if err != nil {
+		return nil, err
+	}
+
+	// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ..., with < for descending keys
+	alternatives := make([]clause.Expression, len(keys))
+	for i, key := range keys {
+		operands := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			operands = append(operands, clause.Expr{SQL: keys[j].sql + " = ?", Vars: append(append([]interface{}{}, keys[j].vars...), bound[j])})
+		}
+
+		operator := ">"
+		if key.desc == forward {
+			operator = "<"
+		}
+		operands = append(operands, clause.Expr{SQL: key.sql + " " + operator + " ?", Vars: append(append([]interface{}{}, key.vars...), bound[i])})
+		alternatives[i] = clause.And(operands...)
+	}
+
+	return clause.Or(alternatives...), nil
+}
+
+// SeekProducts returns the sorted products after the place marked by
+// SortValues, or before it when not forward
+func SeekProducts(products []models.Product, order string, search Search, values []json.RawMessage, forward bool) ([]models.Product, error) {
+	keys, bound, err := decodeSortValues(order, search, values)
+	if err != nil {
+		return nil, err
+	}
+
+	// First product after the mark
+	start := sort.Search(len(products), func(i int) bool {
+		return compareProducts(keys, &products[i], bound) > 0
+	})
+	if forward {
+		return products[start:], nil
+	}
+
+	// Products before the mark end where those equal to it start
+	end := sort.Search(len(products), func(i int) bool {
+		return compareProducts(keys, &products[i], bound) >= 0
+	})
+	return products[:end], nil
+}
+
+// maxSortKeyString is the most bytes of a string value a DynamoDB sort key
+// holds, leaving room under the 1024 byte limit of index sort keys for the
+// ID. Names sharing a longer prefix are ordered by ID.
+const maxSortKeyString = 512
+
+// DynamoSortKey encodes the sort values of a product as a string whose byte
+// order is the order of the listing, for the sort key of a DynamoDB index.
+// Only orders of fields work; relevance depends on the search.
+func DynamoSortKey(product *models.Product, order string) (string, error) {
+	keys, err := sortKeys(order, Search{})
+	if err != nil {
+		return "", err
+	}
+	return encodeSortKey(keys, sortValues(keys, product))
+}
+
+// DynamoSeekKey encodes the place marked by SortValues as DynamoSortKey
+// does, along with the ID of the product there
+func DynamoSeekKey(order string, values []json.RawMessage) (string, string, error) {
+	keys, bound, err := decodeSortValues(order, Search{}, values)
+	if err != nil {
+		return "", "", err
+	}
+	key, err := encodeSortKey(keys, bound)
+	if err != nil {
+		return "", "", err
+	}
+	return key, bound[len(bound)-1].(string), nil
+}
+
+// encodeSortKey writes numbers and times as fixed-width hex of bits that
+// order as the values do, inverted for descending keys, and strings as they
+// are, ended by a NUL byte when more keys follow
+func encodeSortKey(keys []sortKey, values []interface{}) (string, error) {
+	var encoded strings.Builder
+	for i, key := range keys {
+		var bits uint64
+		switch value := values[i].(type) {
+		case float64:
+			bits = orderedFloatBits(value)
+		case int:
+			bits = orderedFloatBits(float64(value))
+		case time.Time:
+			bits = uint64(value.UnixNano()) ^ (1 << 63)
+		case string:
+			if key.desc {
+				return "", fmt.Errorf("descending string key %s cannot be encoded", key.sql)
+			}
+			encoded.WriteString(truncateUTF8(value, maxSortKeyString))
+			if i < len(keys)-1 {
+				encoded.WriteByte(0)
+			}
+			continue
+		default:
+			return "", fmt.Errorf("sort value %T cannot be encoded", value)
+		}
+
+		if key.desc {
+			bits = ^bits
+		}
+		fmt.Fprintf(&encoded, "%016x", bits)
+	}
+	return encoded.String(), nil
+}
+
+// orderedFloatBits maps a float to bits whose unsigned order is the order of
+// the floats: the sign bit is flipped for positives, every bit for negatives
+func orderedFloatBits(value float64) uint64 {
+	bits := math.Float64bits(value)
+	if bits&(1<<63) != 0 {
+		return ^bits
+	}
+	return bits | (1 << 63)
+}
+
+// truncateUTF8 cuts a string to at most n bytes without splitting a rune
+func truncateUTF8(value string, n int) string {
+	if len(value) <= n {
+		return value
+	}
+	for n > 0 && !utf8.RuneStart(value[n]) {
+		n--
+	}
+	return value[:n]
+}
+
+func sortValues(keys []sortKey, product *models.Product) []interface{} {
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		values[i] = key.value(product)
+	}
+	return values
+}
+
+// decodeSortValues reads values of SortValues back into the types of the keys
+func decodeSortValues(order string, search Search, values []json.RawMessage) ([]sortKey, []interface{}, error) {
+	keys, err := sortKeys(order, search)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(values) != len(keys) {
+		return nil, nil, fmt.Errorf("expected %d sort values, got %d", len(keys), len(values))
+	}
+
+	bound := make([]interface{}, len(keys))
+	for i, key := range keys {
+		switch key.value(&models.Product{}).(type) {
+		case float64:
+			var value float64
+			err = json.Unmarshal(values[i], &value)
+			bound[i] = value
+		case int:
+			var value int
+			err = json.Unmarshal(values[i], &value)
+			bound[i] = value
+		case string:
+			var value string
+			err = json.Unmarshal(values[i], &value)
+			bound[i] = value
+		case time.Time:
+			var value time.Time
+			err = json.Unmarshal(values[i], &value)
+			bound[i] = value
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid sort value: %w", err)
+		}
+	}
+
+	return keys, bound, nil
+}
+
+// compareProducts compares a product with the sort values of another
+func compareProducts(keys []sortKey, product *models.Product, values []interface{}) int {
+	for i, key := range keys {
+		c := compareValues(key.value(product), values[i])
+		if key.desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case float64:
+		return compareFloat(a, b.(float64))
+	case int:
+		return compareFloat(float64(a), float64(b.(int)))
+	case string:
+		return strings.Compare(a, b.(string))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	default:
+		return 0
+	}
+}
+
+// discount sorts products without a discount after those with any
+func discount(product *models.Product) float64 {
+	if product.Discount == nil {
+		return -1
+	}
+	return *product.Discount
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
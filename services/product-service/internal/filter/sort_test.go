@@ -0,0 +1,69 @@
+package filter_test
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"product-service/internal/filter"
+	"product-service/internal/models"
+)
+
+// TestDynamoSortKeyOrder checks that DynamoDB sort keys order the fixture
+// products as SortProducts does, for every order without a search, and that
+// the key of a cursor is that of the product it marks
+func TestDynamoSortKeyOrder(t *testing.T) {
+	products := fixtureProducts(memoryFixture)
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := range products {
+		products[i].ID = string(rune('e'-i)) + "-id"
+		products[i].CreatedAt = created.Add(time.Duration(i%3) * time.Hour)
+	}
+
+	for _, order := range models.ProductSorts {
+		if order == models.SortRelevance {
+			continue
+		}
+
+		want := append([]models.Product(nil), products...)
+		if err := filter.SortProducts(want, order, filter.Search{}); err != nil {
+			t.Fatalf("%s: %v", order, err)
+		}
+
+		keys := make(map[string]string, len(products))
+		for i := range products {
+			key, err := filter.DynamoSortKey(&products[i], order)
+			if err != nil {
+				t.Fatalf("%s: %v", order, err)
+			}
+			keys[products[i].ID] = key
+
+			values, err := filter.SortValues(&products[i], order, filter.Search{})
+			if err != nil {
+				t.Fatalf("%s: %v", order, err)
+			}
+			seekKey, id, err := filter.DynamoSeekKey(order, values)
+			if err != nil {
+				t.Fatalf("%s: %v", order, err)
+			}
+			if seekKey != key || id != products[i].ID {
+				t.Errorf("%s: cursor of %s encodes %q and %s, want %q", order, products[i].SKU, seekKey, id, key)
+			}
+		}
+
+		got := append([]models.Product(nil), products...)
+		sort.Slice(got, func(i, j int) bool { return keys[got[i].ID] < keys[got[j].ID] })
+		if skus(got) != skus(want) {
+			t.Errorf("%s: sort keys order %s, want %s", order, skus(got), skus(want))
+		}
+	}
+}
+
+func skus(products []models.Product) string {
+	names := make([]string, len(products))
+	for i := range products {
+		names[i] = products[i].SKU
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"product-service/internal/models"
+	"shared/apperr"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func (h *LambdaHandler) listDepartments(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	includeInactive, _ := strconv.ParseBool(request.QueryStringParameters["include_inactive"])
+
+	departments, err := h.productService.ListDepartments(ctx, includeInactive)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, departments, headers), nil
+}
+
+func (h *LambdaHandler) getDepartment(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	key := extractIDFromPath(request.Path)
+	if key == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Department ID or slug is required"), headers), nil
+	}
+
+	department, err := h.productService.GetDepartment(ctx, key)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, department, headers), nil
+}
+
+func (h *LambdaHandler) createDepartment(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	var createRequest models.CreateDepartmentRequest
+
+	if err := json.Unmarshal([]byte(request.Body), &createRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
+	}
+
+	if err := h.validator.Struct(&createRequest); err != nil {
+		return h.errorResponse(ctx, validationError(err), headers), nil
+	}
+
+	department, err := h.productService.CreateDepartment(ctx, &createRequest)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusCreated, department, headers), nil
+}
+
+func (h *LambdaHandler) updateDepartment(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	id := extractIDFromPath(request.Path)
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Department ID is required"), headers), nil
+	}
+
+	var updateRequest models.UpdateDepartmentRequest
+
+	if err := json.Unmarshal([]byte(request.Body), &updateRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
+	}
+
+	if err := h.validator.Struct(&updateRequest); err != nil {
+		return h.errorResponse(ctx, validationError(err), headers), nil
+	}
+
+	department, err := h.productService.UpdateDepartment(ctx, id, &updateRequest)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, department, headers), nil
+}
+
+func (h *LambdaHandler) deactivateDepartment(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	id := extractIDFromPath(request.Path)
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Department ID is required"), headers), nil
+	}
+
+	err := h.productService.DeactivateDepartment(ctx, id)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusNoContent, nil, headers), nil
+}
+
+func (h *LambdaHandler) listCategories(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	includeInactive, _ := strconv.ParseBool(request.QueryStringParameters["include_inactive"])
+
+	categories, err := h.productService.ListCategories(ctx, includeInactive)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, categories, headers), nil
+}
+
+func (h *LambdaHandler) getCategory(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	key := extractIDFromPath(request.Path)
+	if key == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Category ID or slug is required"), headers), nil
+	}
+
+	category, err := h.productService.GetCategory(ctx, key)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, category, headers), nil
+}
+
+func (h *LambdaHandler) createCategory(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	var createRequest models.CreateCategoryRequest
+
+	if err := json.Unmarshal([]byte(request.Body), &createRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
+	}
+
+	if err := h.validator.Struct(&createRequest); err != nil {
+		return h.errorResponse(ctx, validationError(err), headers), nil
+	}
+
+	category, err := h.productService.CreateCategory(ctx, &createRequest)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusCreated, category, headers), nil
+}
+
+func (h *LambdaHandler) updateCategory(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	id := extractIDFromPath(request.Path)
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Category ID is required"), headers), nil
+	}
+
+	var updateRequest models.UpdateCategoryRequest
+
+	if err := json.Unmarshal([]byte(request.Body), &updateRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
+	}
+
+	if err := h.validator.Struct(&updateRequest); err != nil {
+		return h.errorResponse(ctx, validationError(err), headers), nil
+	}
+
+	category, err := h.productService.UpdateCategory(ctx, id, &updateRequest)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, category, headers), nil
+}
+
+func (h *LambdaHandler) deactivateCategory(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	id := extractIDFromPath(request.Path)
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Category ID is required"), headers), nil
+	}
+
+	err := h.productService.DeactivateCategory(ctx, id)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusNoContent, nil, headers), nil
+}
+
+func (h *LambdaHandler) getCategoryTree(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	tree, err := h.productService.GetCategoryTree(ctx)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, tree, headers), nil
+}
+
+func (h *LambdaHandler) getCategorySubtree(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	// Path format: /categories/{idOrSlug}/tree
+	key := extractIDFromPath(strings.TrimSuffix(request.Path, "/tree"))
+	if key == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Category ID or slug is required"), headers), nil
+	}
+
+	tree, err := h.productService.GetCategorySubtree(ctx, key)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, tree, headers), nil
+}
@@ -1,22 +1,41 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"product-service/internal/cursor"
 	"product-service/internal/models"
 	"product-service/internal/repository"
 	"product-service/internal/service"
+	"reflect"
+	"shared/apperr"
+	"shared/auth"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/go-playground/validator/v10"
 )
 
+const (
+	// requestDeadlineMargin is kept back from the Lambda deadline so a request
+	// that runs out of time can still answer with 504 instead of being killed
+	requestDeadlineMargin = 500 * time.Millisecond
+
+	// defaultRequestTimeout bounds requests whose context has no deadline,
+	// as when running locally
+	defaultRequestTimeout = 25 * time.Second
+)
+
 type LambdaHandler struct {
 	productService *service.ProductService
 	validator      *validator.Validate
+	verifier       *auth.Verifier
+	orders         *auth.Verifier
 }
 
 func NewLambdaHandler() *LambdaHandler {
@@ -25,16 +44,42 @@ func NewLambdaHandler() *LambdaHandler {
 		panic(fmt.Sprintf("Failed to initialize database: %v", err))
 	}
 
-	productService := service.NewProductService(repo)
+	cursors, err := cursor.NewCodecFromEnv()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize cursors: %v", err))
+	}
+
+	verifier, err := auth.NewVerifierFromEnv()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize token verifier: %v", err))
+	}
+
+	// Returns are only taken from order-service, on its own credential
+	orders, err := auth.NewServiceVerifierFromEnv("order-service")
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize order-service token verifier: %v", err))
+	}
+
+	productService := service.NewProductService(repo, cursors)
 	validator := validator.New()
+	// Name fields in validation errors as the JSON request does
+	validator.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
 
 	return &LambdaHandler{
 		productService: productService,
 		validator:      validator,
+		verifier:       verifier,
+		orders:         orders,
 	}
 }
 
-func (h *LambdaHandler) HandleRequest(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+func (h *LambdaHandler) HandleRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
 	// Enable CORS
 	headers := map[string]string{
 		"Content-Type":                 "application/json",
@@ -51,37 +96,126 @@ func (h *LambdaHandler) HandleRequest(request events.APIGatewayProxyRequest) (ev
 		}, nil
 	}
 
+	ctx, cancel := requestContext(ctx)
+	defer cancel()
+
 	switch {
 	case request.HTTPMethod == "GET" && request.Path == "/products":
-		return h.listProducts(request, headers)
+		return h.listProducts(ctx, request, headers)
+	case request.HTTPMethod == "GET" && request.Path == "/products/tags":
+		return h.listTagFacets(ctx, request, headers)
+	case request.HTTPMethod == "GET" && request.Path == "/products/suggest":
+		return h.suggest(ctx, request, headers)
+	case request.HTTPMethod == "GET" && strings.HasPrefix(request.Path, "/products/") && strings.HasSuffix(request.Path, "/stock/history"):
+		return h.getStockHistory(ctx, request, headers)
 	case request.HTTPMethod == "GET" && strings.HasPrefix(request.Path, "/products/"):
-		return h.getProduct(request, headers)
+		return h.getProduct(ctx, request, headers)
 	case request.HTTPMethod == "POST" && request.Path == "/products":
-		return h.createProduct(request, headers)
+		return h.createProduct(ctx, request, headers)
 	case request.HTTPMethod == "PUT" && strings.HasPrefix(request.Path, "/products/"):
-		return h.updateProduct(request, headers)
+		return h.updateProduct(ctx, request, headers)
 	case request.HTTPMethod == "DELETE" && strings.HasPrefix(request.Path, "/products/"):
-		return h.deleteProduct(request, headers)
+		return h.deleteProduct(ctx, request, headers)
 	case request.HTTPMethod == "GET" && request.Path == "/products/low-stock":
-		return h.getLowStockProducts(request, headers)
+		return h.getLowStockProducts(ctx, request, headers)
 	case request.HTTPMethod == "POST" && strings.HasPrefix(request.Path, "/products/") && strings.HasSuffix(request.Path, "/stock"):
-		return h.updateStock(request, headers)
+		return h.updateStock(ctx, request, headers)
+	case request.HTTPMethod == "POST" && strings.HasPrefix(request.Path, "/products/") && strings.HasSuffix(request.Path, "/returns"):
+		return h.returnStock(ctx, request, headers)
 	case request.HTTPMethod == "GET" && request.Path == "/products/on-sale":
-		return h.getProductsOnSale(request, headers)
+		return h.getProductsOnSale(ctx, request, headers)
 	case request.HTTPMethod == "GET" && strings.HasPrefix(request.Path, "/products/department/"):
-		return h.getProductsByDepartment(request, headers)
+		return h.getProductsByDepartment(ctx, request, headers)
+	case request.HTTPMethod == "GET" && request.Path == "/departments":
+		return h.listDepartments(ctx, request, headers)
+	case request.HTTPMethod == "GET" && strings.HasPrefix(request.Path, "/departments/"):
+		return h.getDepartment(ctx, request, headers)
+	case request.HTTPMethod == "POST" && request.Path == "/departments":
+		return h.createDepartment(ctx, request, headers)
+	case request.HTTPMethod == "PUT" && strings.HasPrefix(request.Path, "/departments/"):
+		return h.updateDepartment(ctx, request, headers)
+	case request.HTTPMethod == "DELETE" && strings.HasPrefix(request.Path, "/departments/"):
+		return h.deactivateDepartment(ctx, request, headers)
+	case request.HTTPMethod == "GET" && request.Path == "/categories":
+		return h.listCategories(ctx, request, headers)
+	case request.HTTPMethod == "GET" && request.Path == "/categories/tree":
+		return h.getCategoryTree(ctx, request, headers)
+	case request.HTTPMethod == "GET" && strings.HasPrefix(request.Path, "/categories/") && strings.HasSuffix(request.Path, "/tree"):
+		return h.getCategorySubtree(ctx, request, headers)
+	case request.HTTPMethod == "GET" && strings.HasPrefix(request.Path, "/categories/"):
+		return h.getCategory(ctx, request, headers)
+	case request.HTTPMethod == "POST" && request.Path == "/categories":
+		return h.createCategory(ctx, request, headers)
+	case request.HTTPMethod == "PUT" && strings.HasPrefix(request.Path, "/categories/"):
+		return h.updateCategory(ctx, request, headers)
+	case request.HTTPMethod == "DELETE" && strings.HasPrefix(request.Path, "/categories/"):
+		return h.deactivateCategory(ctx, request, headers)
+	case request.HTTPMethod == "POST" && request.Path == "/reservations":
+		return h.createReservation(ctx, request, headers)
+	case request.HTTPMethod == "POST" && request.Path == "/reservations/expire":
+		return h.expireReservations(ctx, request, headers)
+	case request.HTTPMethod == "GET" && strings.HasPrefix(request.Path, "/reservations/"):
+		return h.getReservation(ctx, request, headers)
+	case request.HTTPMethod == "POST" && strings.HasPrefix(request.Path, "/reservations/") && strings.HasSuffix(request.Path, "/confirm"):
+		return h.confirmReservation(ctx, request, headers)
+	case request.HTTPMethod == "POST" && strings.HasPrefix(request.Path, "/reservations/") && strings.HasSuffix(request.Path, "/release"):
+		return h.releaseReservation(ctx, request, headers)
+	case request.HTTPMethod == "GET" && request.Path == "/synonyms":
+		return h.listSynonymGroups(ctx, request, headers)
+	case request.HTTPMethod == "POST" && request.Path == "/synonyms":
+		return h.createSynonymGroup(ctx, request, headers)
+	case request.HTTPMethod == "PUT" && strings.HasPrefix(request.Path, "/synonyms/"):
+		return h.updateSynonymGroup(ctx, request, headers)
+	case request.HTTPMethod == "DELETE" && strings.HasPrefix(request.Path, "/synonyms/"):
+		return h.deleteSynonymGroup(ctx, request, headers)
 	default:
-		return h.errorResponse(http.StatusNotFound, "Route not found", headers), nil
+		return h.errorResponse(ctx, apperr.NotFound("Route not found"), headers), nil
+	}
+}
+
+func (h *LambdaHandler) listProducts(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	filter, err := productFilterFromQuery(request)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	response, err := h.productService.ListProducts(ctx, filter)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, response, headers), nil
+}
+
+// listTagFacets counts the products of a listing by tag. It takes the
+// filters of GET /products.
+func (h *LambdaHandler) listTagFacets(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	filter, err := productFilterFromQuery(request)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	facets, err := h.productService.ListTagFacets(ctx, filter)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
 	}
+
+	return h.successResponse(http.StatusOK, map[string]interface{}{"tags": facets}, headers), nil
 }
 
-func (h *LambdaHandler) listProducts(request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+// productFilterFromQuery reads the filters of a product listing
+func productFilterFromQuery(request events.APIGatewayProxyRequest) (models.ProductFilter, error) {
 	filter := models.ProductFilter{}
 
 	// Parse query parameters
 	if categoryID := request.QueryStringParameters["category_id"]; categoryID != "" {
 		filter.CategoryID = categoryID
 	}
+	if includeSubcategoriesStr := request.QueryStringParameters["include_subcategories"]; includeSubcategoriesStr != "" {
+		if includeSubcategories, err := strconv.ParseBool(includeSubcategoriesStr); err == nil {
+			filter.IncludeSubcategories = includeSubcategories
+		}
+	}
 	if brand := request.QueryStringParameters["brand"]; brand != "" {
 		filter.Brand = brand
 	}
@@ -116,6 +250,22 @@ func (h *LambdaHandler) listProducts(request events.APIGatewayProxyRequest, head
 			filter.MinRating = &minRating
 		}
 	}
+	if tags := request.QueryStringParameters["tags"]; tags != "" {
+		for _, tag := range strings.Split(tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				filter.Tags = append(filter.Tags, tag)
+			}
+		}
+	}
+	if tagMatch := request.QueryStringParameters["tag_match"]; tagMatch != "" {
+		if tagMatch != models.TagMatchAll && tagMatch != models.TagMatchAny {
+			return filter, apperr.InvalidField("tag_match", "tag_match must be any or all")
+		}
+		filter.TagMatch = tagMatch
+	}
+	if sort := request.QueryStringParameters["sort"]; sort != "" {
+		filter.Sort = sort
+	}
 	if limitStr := request.QueryStringParameters["limit"]; limitStr != "" {
 		if limit, err := strconv.Atoi(limitStr); err == nil {
 			filter.Limit = limit
@@ -126,137 +276,193 @@ func (h *LambdaHandler) listProducts(request events.APIGatewayProxyRequest, head
 			filter.Offset = offset
 		}
 	}
-
-	response, err := h.productService.ListProducts(filter)
-	if err != nil {
-		return h.errorResponse(http.StatusInternalServerError, err.Error(), headers), nil
+	if c := request.QueryStringParameters["cursor"]; c != "" {
+		filter.Cursor = c
 	}
 
-	return h.successResponse(http.StatusOK, response, headers), nil
+	return filter, nil
 }
 
-func (h *LambdaHandler) getProduct(request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+func (h *LambdaHandler) getProduct(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
 	id := extractIDFromPath(request.Path)
 	if id == "" {
-		return h.errorResponse(http.StatusBadRequest, "Product ID is required", headers), nil
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Product ID is required"), headers), nil
 	}
 
-	product, err := h.productService.GetProduct(id)
+	product, err := h.productService.GetProduct(ctx, id)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			return h.errorResponse(http.StatusNotFound, "Product not found", headers), nil
-		}
-		return h.errorResponse(http.StatusInternalServerError, err.Error(), headers), nil
+		return h.errorResponse(ctx, err, headers), nil
 	}
 
 	return h.successResponse(http.StatusOK, product, headers), nil
 }
 
-func (h *LambdaHandler) createProduct(request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+func (h *LambdaHandler) createProduct(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
 	var createRequest models.CreateProductRequest
 	
 	if err := json.Unmarshal([]byte(request.Body), &createRequest); err != nil {
-		return h.errorResponse(http.StatusBadRequest, "Invalid JSON payload", headers), nil
+		return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
 	}
 
 	if err := h.validator.Struct(&createRequest); err != nil {
-		return h.errorResponse(http.StatusBadRequest, fmt.Sprintf("Validation error: %s", err.Error()), headers), nil
+		return h.errorResponse(ctx, validationError(err), headers), nil
 	}
 
-	product, err := h.productService.CreateProduct(&createRequest)
+	product, err := h.productService.CreateProduct(ctx, &createRequest)
 	if err != nil {
-		return h.errorResponse(http.StatusInternalServerError, err.Error(), headers), nil
+		return h.errorResponse(ctx, err, headers), nil
 	}
 
 	return h.successResponse(http.StatusCreated, product, headers), nil
 }
 
-func (h *LambdaHandler) updateProduct(request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+func (h *LambdaHandler) updateProduct(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
 	id := extractIDFromPath(request.Path)
 	if id == "" {
-		return h.errorResponse(http.StatusBadRequest, "Product ID is required", headers), nil
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Product ID is required"), headers), nil
 	}
 
 	var updateRequest models.UpdateProductRequest
 	
 	if err := json.Unmarshal([]byte(request.Body), &updateRequest); err != nil {
-		return h.errorResponse(http.StatusBadRequest, "Invalid JSON payload", headers), nil
+		return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
 	}
 
 	if err := h.validator.Struct(&updateRequest); err != nil {
-		return h.errorResponse(http.StatusBadRequest, fmt.Sprintf("Validation error: %s", err.Error()), headers), nil
+		return h.errorResponse(ctx, validationError(err), headers), nil
 	}
 
-	product, err := h.productService.UpdateProduct(id, &updateRequest)
-	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			return h.errorResponse(http.StatusNotFound, "Product not found", headers), nil
+	// Setting stock is a manual adjustment, recorded against the caller
+	var actor string
+	if updateRequest.Stock != nil {
+		var err error
+		actor, err = h.authorize(request, auth.RoleStaff, auth.RoleAdmin)
+		if err != nil {
+			return h.errorResponse(ctx, err, headers), nil
 		}
-		return h.errorResponse(http.StatusInternalServerError, err.Error(), headers), nil
+	}
+
+	product, err := h.productService.UpdateProduct(ctx, id, actor, &updateRequest)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
 	}
 
 	return h.successResponse(http.StatusOK, product, headers), nil
 }
 
-func (h *LambdaHandler) deleteProduct(request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+func (h *LambdaHandler) deleteProduct(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
 	id := extractIDFromPath(request.Path)
 	if id == "" {
-		return h.errorResponse(http.StatusBadRequest, "Product ID is required", headers), nil
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Product ID is required"), headers), nil
 	}
 
-	err := h.productService.DeleteProduct(id)
+	err := h.productService.DeleteProduct(ctx, id)
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			return h.errorResponse(http.StatusNotFound, "Product not found", headers), nil
-		}
-		return h.errorResponse(http.StatusInternalServerError, err.Error(), headers), nil
+		return h.errorResponse(ctx, err, headers), nil
 	}
 
 	return h.successResponse(http.StatusNoContent, nil, headers), nil
 }
 
-func (h *LambdaHandler) getLowStockProducts(request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
-	products, err := h.productService.GetLowStockProducts()
+func (h *LambdaHandler) getLowStockProducts(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	products, err := h.productService.GetLowStockProducts(ctx)
 	if err != nil {
-		return h.errorResponse(http.StatusInternalServerError, err.Error(), headers), nil
+		return h.errorResponse(ctx, err, headers), nil
 	}
 
 	return h.successResponse(http.StatusOK, products, headers), nil
 }
 
-func (h *LambdaHandler) updateStock(request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+func (h *LambdaHandler) updateStock(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
 	id := extractIDFromPath(strings.Replace(request.Path, "/stock", "", 1))
 	if id == "" {
-		return h.errorResponse(http.StatusBadRequest, "Product ID is required", headers), nil
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Product ID is required"), headers), nil
 	}
 
-	var stockRequest struct {
-		Quantity int `json:"quantity" validate:"required"`
+	// Stock movements are recorded against the caller, never a body field.
+	// Only staff adjust stock by hand; order returns come in through
+	// returnStock.
+	actor, err := h.authorize(request, auth.RoleStaff, auth.RoleAdmin)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
 	}
+
+	var stockRequest models.UpdateStockRequest
 	
 	if err := json.Unmarshal([]byte(request.Body), &stockRequest); err != nil {
-		return h.errorResponse(http.StatusBadRequest, "Invalid JSON payload", headers), nil
+		return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
 	}
 
 	if err := h.validator.Struct(&stockRequest); err != nil {
-		return h.errorResponse(http.StatusBadRequest, fmt.Sprintf("Validation error: %s", err.Error()), headers), nil
+		return h.errorResponse(ctx, validationError(err), headers), nil
+	}
+
+	err = h.productService.UpdateStock(ctx, id, actor, &stockRequest)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, map[string]string{"message": "Stock updated successfully"}, headers), nil
+}
+
+// returnStock puts the units of an order line back into stock. Only
+// order-service calls it, with a token it signs for the call.
+func (h *LambdaHandler) returnStock(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	id := extractIDFromPath(strings.TrimSuffix(request.Path, "/returns"))
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Product ID is required"), headers), nil
 	}
 
-	err := h.productService.UpdateStock(id, stockRequest.Quantity)
+	claims, err := h.orders.Verify(auth.BearerToken(request.Headers))
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			return h.errorResponse(http.StatusNotFound, "Product not found", headers), nil
+		return h.errorResponse(ctx, apperr.Unauthorized("%v", err), headers), nil
+	}
+
+	var returnRequest models.ReturnStockRequest
+	if err := json.Unmarshal([]byte(request.Body), &returnRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
+	}
+
+	if err := h.validator.Struct(&returnRequest); err != nil {
+		return h.errorResponse(ctx, validationError(err), headers), nil
+	}
+
+	err = h.productService.ReturnStock(ctx, id, claims.Subject, &returnRequest)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, map[string]string{"message": "Stock returned successfully"}, headers), nil
+}
+
+func (h *LambdaHandler) getStockHistory(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	// Path format: /products/{id}/stock/history
+	id := extractIDFromPath(strings.TrimSuffix(request.Path, "/stock/history"))
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Product ID is required"), headers), nil
+	}
+
+	limit, offset := 0, 0
+	if limitStr := request.QueryStringParameters["limit"]; limitStr != "" {
+		if parsed, err := strconv.Atoi(limitStr); err == nil {
+			limit = parsed
 		}
-		if strings.Contains(err.Error(), "insufficient stock") {
-			return h.errorResponse(http.StatusBadRequest, err.Error(), headers), nil
+	}
+	if offsetStr := request.QueryStringParameters["offset"]; offsetStr != "" {
+		if parsed, err := strconv.Atoi(offsetStr); err == nil {
+			offset = parsed
 		}
-		return h.errorResponse(http.StatusInternalServerError, err.Error(), headers), nil
 	}
 
-	return h.successResponse(http.StatusOK, map[string]string{"message": "Stock updated successfully"}, headers), nil
+	history, err := h.productService.GetStockHistory(ctx, id, limit, offset)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, history, headers), nil
 }
 
-func (h *LambdaHandler) getProductsOnSale(request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+func (h *LambdaHandler) getProductsOnSale(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
 	filter := models.ProductFilter{}
 
 	// Parse query parameters for additional filtering
@@ -271,18 +477,18 @@ func (h *LambdaHandler) getProductsOnSale(request events.APIGatewayProxyRequest,
 		}
 	}
 
-	response, err := h.productService.GetProductsOnSale(filter)
+	response, err := h.productService.GetProductsOnSale(ctx, filter)
 	if err != nil {
-		return h.errorResponse(http.StatusInternalServerError, err.Error(), headers), nil
+		return h.errorResponse(ctx, err, headers), nil
 	}
 
 	return h.successResponse(http.StatusOK, response, headers), nil
 }
 
-func (h *LambdaHandler) getProductsByDepartment(request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+func (h *LambdaHandler) getProductsByDepartment(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
 	departmentID := extractDepartmentIDFromPath(request.Path)
 	if departmentID == "" {
-		return h.errorResponse(http.StatusBadRequest, "Department ID is required", headers), nil
+		return h.errorResponse(ctx, apperr.InvalidField("department_id", "Department ID is required"), headers), nil
 	}
 
 	filter := models.ProductFilter{}
@@ -299,9 +505,9 @@ func (h *LambdaHandler) getProductsByDepartment(request events.APIGatewayProxyRe
 		}
 	}
 
-	response, err := h.productService.GetProductsByDepartment(departmentID, filter)
+	response, err := h.productService.GetProductsByDepartment(ctx, departmentID, filter)
 	if err != nil {
-		return h.errorResponse(http.StatusInternalServerError, err.Error(), headers), nil
+		return h.errorResponse(ctx, err, headers), nil
 	}
 
 	return h.successResponse(http.StatusOK, response, headers), nil
@@ -321,8 +527,9 @@ func (h *LambdaHandler) successResponse(statusCode int, data interface{}, header
 	}
 }
 
-func (h *LambdaHandler) errorResponse(statusCode int, message string, headers map[string]string) events.APIGatewayProxyResponse {
-	errorBody := map[string]string{"error": message}
+// errorResponse answers with the status and JSON error body apperr maps err to
+func (h *LambdaHandler) errorResponse(ctx context.Context, err error, headers map[string]string) events.APIGatewayProxyResponse {
+	statusCode, errorBody := apperr.HTTPResponse(apperr.FromContext(ctx, err))
 	bodyBytes, _ := json.Marshal(errorBody)
 
 	return events.APIGatewayProxyResponse{
@@ -332,6 +539,58 @@ func (h *LambdaHandler) errorResponse(statusCode int, message string, headers ma
 	}
 }
 
+// authorize returns the ID of the user whose bearer token the request
+// carries, refusing users who hold none of roles
+func (h *LambdaHandler) authorize(request events.APIGatewayProxyRequest, roles ...string) (string, error) {
+	claims, err := h.verifier.Verify(auth.BearerToken(request.Headers))
+	if err != nil {
+		return "", apperr.Unauthorized("%v", err)
+	}
+	if !claims.HasRole(roles...) {
+		return "", apperr.Forbidden("requires the %s role", strings.Join(roles, " or "))
+	}
+	return claims.Subject, nil
+}
+
+// validationError turns the errors of the validator into field details named
+// after the JSON fields of the request
+func validationError(err error) error {
+	var fieldErrors validator.ValidationErrors
+	if !errors.As(err, &fieldErrors) {
+		return apperr.Validation(fmt.Sprintf("Validation error: %s", err.Error()))
+	}
+
+	fields := make([]apperr.FieldError, 0, len(fieldErrors))
+	for _, fieldError := range fieldErrors {
+		var message string
+		switch fieldError.Tag() {
+		case "required":
+			message = "is required"
+		case "min":
+			message = fmt.Sprintf("must be at least %s", fieldError.Param())
+		case "max":
+			message = fmt.Sprintf("must be at most %s", fieldError.Param())
+		case "oneof":
+			message = fmt.Sprintf("must be one of %s", fieldError.Param())
+		default:
+			message = fmt.Sprintf("failed the %s check", fieldError.Tag())
+		}
+		fields = append(fields, apperr.FieldError{Field: fieldError.Field(), Message: message})
+	}
+
+	return apperr.Validation("Validation error", fields...)
+}
+
+// requestContext bounds a request by the Lambda deadline, less the margin
+// needed to answer
+func requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithTimeout(ctx, defaultRequestTimeout)
+	}
+	return context.WithDeadline(ctx, deadline.Add(-requestDeadlineMargin))
+}
+
 func extractIDFromPath(path string) string {
 	parts := strings.Split(strings.Trim(path, "/"), "/")
 	if len(parts) >= 2 {
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"product-service/internal/models"
+	"shared/apperr"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func (h *LambdaHandler) createReservation(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	var createRequest models.CreateReservationRequest
+
+	if err := json.Unmarshal([]byte(request.Body), &createRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
+	}
+
+	if err := h.validator.Struct(&createRequest); err != nil {
+		return h.errorResponse(ctx, validationError(err), headers), nil
+	}
+
+	reservation, err := h.productService.ReserveStock(ctx, &createRequest)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusCreated, reservation, headers), nil
+}
+
+func (h *LambdaHandler) getReservation(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	id := extractIDFromPath(request.Path)
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Reservation ID is required"), headers), nil
+	}
+
+	reservation, err := h.productService.GetReservation(ctx, id)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, reservation, headers), nil
+}
+
+func (h *LambdaHandler) confirmReservation(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	// Path format: /reservations/{id}/confirm
+	id := extractIDFromPath(strings.TrimSuffix(request.Path, "/confirm"))
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Reservation ID is required"), headers), nil
+	}
+
+	reservation, err := h.productService.ConfirmReservation(ctx, id)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, reservation, headers), nil
+}
+
+func (h *LambdaHandler) releaseReservation(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	// Path format: /reservations/{id}/release
+	id := extractIDFromPath(strings.TrimSuffix(request.Path, "/release"))
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Reservation ID is required"), headers), nil
+	}
+
+	reservation, err := h.productService.ReleaseReservation(ctx, id)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, reservation, headers), nil
+}
+
+func (h *LambdaHandler) expireReservations(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	expired, err := h.productService.ExpireReservations(ctx)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, map[string]int{"expired": expired}, headers), nil
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"product-service/internal/models"
+	"shared/apperr"
+	"shared/auth"
+	"strconv"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// suggest completes the prefix in q for the search box
+func (h *LambdaHandler) suggest(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	query := request.QueryStringParameters["q"]
+	limit, _ := strconv.Atoi(request.QueryStringParameters["limit"])
+
+	suggestions, err := h.productService.Suggest(ctx, query, limit)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, models.SuggestionListResponse{Query: query, Suggestions: suggestions}, headers), nil
+}
+
+func (h *LambdaHandler) listSynonymGroups(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	groups, err := h.productService.ListSynonymGroups(ctx)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, groups, headers), nil
+}
+
+// createSynonymGroup, updateSynonymGroup and deleteSynonymGroup change how
+// every search expands, so only admins may call them
+func (h *LambdaHandler) createSynonymGroup(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	if _, err := h.authorize(request, auth.RoleAdmin); err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	var createRequest models.SynonymGroupRequest
+
+	if err := json.Unmarshal([]byte(request.Body), &createRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
+	}
+
+	if err := h.validator.Struct(&createRequest); err != nil {
+		return h.errorResponse(ctx, validationError(err), headers), nil
+	}
+
+	group, err := h.productService.CreateSynonymGroup(ctx, &createRequest)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusCreated, group, headers), nil
+}
+
+func (h *LambdaHandler) updateSynonymGroup(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	if _, err := h.authorize(request, auth.RoleAdmin); err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	id := extractIDFromPath(request.Path)
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Synonym group ID is required"), headers), nil
+	}
+
+	var updateRequest models.SynonymGroupRequest
+
+	if err := json.Unmarshal([]byte(request.Body), &updateRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
+	}
+
+	if err := h.validator.Struct(&updateRequest); err != nil {
+		return h.errorResponse(ctx, validationError(err), headers), nil
+	}
+
+	group, err := h.productService.UpdateSynonymGroup(ctx, id, &updateRequest)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, group, headers), nil
+}
+
+func (h *LambdaHandler) deleteSynonymGroup(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	if _, err := h.authorize(request, auth.RoleAdmin); err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	id := extractIDFromPath(request.Path)
+	if id == "" {
+		return h.errorResponse(ctx, apperr.InvalidField("id", "Synonym group ID is required"), headers), nil
+	}
+
+	err := h.productService.DeleteSynonymGroup(ctx, id)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusNoContent, nil, headers), nil
+}
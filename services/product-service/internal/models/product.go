@@ -1,20 +1,21 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
 type ProductDimensions struct {
 	Length float64 `json:"length" gorm:"type:decimal(10,2)" validate:"min=0"` // cm
-	Width  float64 `json:"width" gorm:"type:decimal(10,2)" validate:"min=0"`   // cm
+	Width  float64 `json:"width" gorm:"type:decimal(10,2)" validate:"min=0"`  // cm
 	Height float64 `json:"height" gorm:"type:decimal(10,2)" validate:"min=0"` // cm
 }
 
 type Product struct {
 	ID            string            `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	SKU           string            `json:"sku" gorm:"uniqueIndex;not null" validate:"required"`
-	Slug          string            `json:"slug" gorm:"uniqueIndex;not null" validate:"required"`
-	Name          string            `json:"name" gorm:"not null" validate:"required,min=1,max=255"`
+	SKU           string            `json:"sku" gorm:"type:varchar(100);uniqueIndex;not null" validate:"required"`
+	Slug          string            `json:"slug" gorm:"type:varchar(100);uniqueIndex;not null" validate:"required"`
+	Name          string            `json:"name" gorm:"type:varchar(255);not null" validate:"required,min=1,max=255"`
 	Description   string            `json:"description" gorm:"type:text"`
 	Price         float64           `json:"price" gorm:"type:decimal(10,2);not null" validate:"required,min=0"`
 	OriginalPrice *float64          `json:"original_price" gorm:"type:decimal(10,2)" validate:"omitempty,min=0"`
@@ -22,60 +23,123 @@ type Product struct {
 	Category      *Category         `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
 	CategoryID    string            `json:"category_id" gorm:"type:uuid;not null;index" validate:"required"`
 	DepartmentID  string            `json:"department_id" gorm:"type:uuid;not null;index" validate:"required"`
-	Brand         string            `json:"brand" gorm:"index"`
-	Unit          string            `json:"unit"`
-	Stock         int               `json:"stock" gorm:"not null;default:0" validate:"min=0"`
-	MinStock      int               `json:"min_stock" gorm:"not null;default:0" validate:"min=0"`
+	Brand         string            `json:"brand" gorm:"type:varchar(100);index"`
+	Unit          string            `json:"unit" gorm:"type:varchar(50)"`
+	Stock         int               `json:"stock" gorm:"type:integer;not null;default:0" validate:"min=0"`
+	Reserved      int               `json:"reserved" gorm:"type:integer;not null;default:0"`
+	Available     int               `json:"available" gorm:"-"`
+	MinStock      int               `json:"min_stock" gorm:"type:integer;not null;default:0" validate:"min=0"`
 	Weight        float64           `json:"weight" gorm:"type:decimal(10,3)" validate:"min=0"` // gramos
-	WeightUnit    string            `json:"weight_unit"`
+	WeightUnit    string            `json:"weight_unit" gorm:"type:varchar(20)"`
 	Dimensions    ProductDimensions `json:"dimensions" gorm:"embedded;embeddedPrefix:dim_"`
 	IsOnSale      bool              `json:"is_on_sale" gorm:"index;default:false"`
 	Discount      *float64          `json:"discount" gorm:"type:decimal(5,2)" validate:"omitempty,min=0,max=100"`
 	Rating        float64           `json:"rating" gorm:"type:decimal(3,2);default:0" validate:"min=0,max=5"`
-	Reviews       int               `json:"reviews" gorm:"default:0" validate:"min=0"`
+	Reviews       int               `json:"reviews" gorm:"type:integer;default:0" validate:"min=0"`
 	IsActive      bool              `json:"is_active" gorm:"index;default:true"`
-	Tags          []string          `json:"tags" gorm:"type:text[]"`
-	CreatedAt     time.Time         `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt     time.Time         `json:"updated_at" gorm:"autoUpdateTime"`
+	Tags          []string          `json:"tags" gorm:"type:text[];index:idx_products_tags,type:gin"`
+	CreatedAt     time.Time         `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+	UpdatedAt     time.Time         `json:"updated_at" gorm:"type:timestamp;autoUpdateTime"`
+
+	// Set on search results: how well the product matches and a fragment of
+	// its description with the matching words highlighted
+	SearchRank float64 `json:"-" gorm:"->;-:migration" dynamodbav:"-"`
+	Snippet    string  `json:"snippet,omitempty" gorm:"->;-:migration" dynamodbav:"-"`
 }
 
 type Department struct {
 	ID          string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	Name        string    `json:"name" gorm:"not null" validate:"required,min=1,max=100"`
+	Name        string    `json:"name" gorm:"type:varchar(100);not null" validate:"required,min=1,max=100"`
 	Description string    `json:"description" gorm:"type:text"`
-	Icon        string    `json:"icon"`
-	Image       string    `json:"image"`
-	Slug        string    `json:"slug" gorm:"uniqueIndex;not null" validate:"required"`
+	Icon        string    `json:"icon" gorm:"type:varchar(255)"`
+	Image       string    `json:"image" gorm:"type:varchar(255)"`
+	Slug        string    `json:"slug" gorm:"type:varchar(100);uniqueIndex;not null" validate:"required"`
 	IsActive    bool      `json:"is_active" gorm:"index;default:true"`
-	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	CreatedAt   time.Time `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"type:timestamp;autoUpdateTime"`
 }
 
 type Category struct {
 	ID          string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
-	Name        string    `json:"name" gorm:"not null" validate:"required,min=1,max=100"`
-	Slug        string    `json:"slug" gorm:"uniqueIndex;not null" validate:"required"`
+	Name        string    `json:"name" gorm:"type:varchar(100);not null" validate:"required,min=1,max=100"`
+	Slug        string    `json:"slug" gorm:"type:varchar(100);uniqueIndex;not null" validate:"required"`
 	Description string    `json:"description" gorm:"type:text"`
 	ParentID    *string   `json:"parent_id" gorm:"type:uuid;index"`
-	Level       int       `json:"level" gorm:"default:0"`
+	Level       int       `json:"level" gorm:"type:integer;default:0"`
 	IsActive    bool      `json:"is_active" gorm:"index;default:true"`
-	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
-	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+	CreatedAt   time.Time `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"type:timestamp;autoUpdateTime"`
+}
+
+type CategoryNode struct {
+	Category
+	Children []*CategoryNode `json:"children"`
+}
+
+type CategoryTreeResponse struct {
+	Breadcrumbs []Category      `json:"breadcrumbs"`
+	Nodes       []*CategoryNode `json:"nodes"`
 }
 
 type ProductFilter struct {
-	CategoryID   string   `json:"category_id"`
-	DepartmentID string   `json:"department_id"`
-	Brand        string   `json:"brand"`
-	MinPrice     *float64 `json:"min_price"`
-	MaxPrice     *float64 `json:"max_price"`
-	InStock      *bool    `json:"in_stock"`
-	IsOnSale     *bool    `json:"is_on_sale"`
-	MinRating    *float64 `json:"min_rating"`
-	Search       string   `json:"search"`
-	Tags         []string `json:"tags"`
-	Limit        int      `json:"limit" validate:"min=1,max=100"`
-	Offset       int      `json:"offset" validate:"min=0"`
+	CategoryID           string   `json:"category_id"`
+	IncludeSubcategories bool     `json:"include_subcategories"`
+	CategoryIDs          []string `json:"-"`
+	DepartmentID         string   `json:"department_id"`
+	Brand                string   `json:"brand"`
+	MinPrice             *float64 `json:"min_price"`
+	MaxPrice             *float64 `json:"max_price"`
+	InStock              *bool    `json:"in_stock"`
+	IsOnSale             *bool    `json:"is_on_sale"`
+	MinRating            *float64 `json:"min_rating"`
+	Search               string   `json:"search"`
+	Tags                 []string `json:"tags"`
+	TagMatch             string   `json:"tag_match" validate:"omitempty,oneof=any all"`
+	Sort                 string   `json:"sort"`
+	Limit                int      `json:"limit" validate:"min=1,max=100"`
+	Offset               int      `json:"offset" validate:"min=0"`
+
+	// Cursor is a next_cursor or prev_cursor token; it replaces Offset
+	Cursor string       `json:"cursor"`
+	Seek   *ProductSeek `json:"-"`
+
+	// Synonyms maps lowercased words of Search to the other terms of their
+	// synonym groups
+	Synonyms map[string][]string `json:"-"`
+}
+
+// ProductSeek is a decoded cursor: the page starts after, or ends before
+// when Backward, the product with these sort values
+type ProductSeek struct {
+	Values   []json.RawMessage
+	Backward bool
+}
+
+// Tag match modes of ProductFilter.TagMatch
+const (
+	TagMatchAll = "all" // products with every tag, the default
+	TagMatchAny = "any" // products with at least one of the tags
+)
+
+// Sort orders of ProductFilter.Sort. Ties are broken by ID so pages do not
+// overlap.
+const (
+	SortPriceAsc  = "price_asc"
+	SortPriceDesc = "price_desc"
+	SortRating    = "rating"    // best rated first
+	SortNewest    = "newest"    // the default without a search
+	SortName      = "name"      // alphabetical
+	SortDiscount  = "discount"  // biggest discount first
+	SortRelevance = "relevance" // best match first, only when searching; the default with one
+)
+
+// ProductSorts lists the sort orders ProductFilter.Sort accepts
+var ProductSorts = []string{SortPriceAsc, SortPriceDesc, SortRating, SortNewest, SortName, SortDiscount, SortRelevance}
+
+// TagFacet counts the products carrying a tag
+type TagFacet struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
 }
 
 type ProductListResponse struct {
@@ -83,6 +147,17 @@ type ProductListResponse struct {
 	TotalCount int       `json:"total_count"`
 	Limit      int       `json:"limit"`
 	Offset     int       `json:"offset"`
+	HasMore    bool      `json:"has_more"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+	PrevCursor string    `json:"prev_cursor,omitempty"`
+	DidYouMean string    `json:"did_you_mean,omitempty"` // a respelled search, when one finds nothing
+
+	// TotalCountApproximate tells that counting stopped early, so TotalCount
+	// is a lower bound
+	TotalCountApproximate bool `json:"total_count_approximate,omitempty"`
+
+	// HasPrevious tells whether products come before the page
+	HasPrevious bool `json:"-"`
 }
 
 type CreateProductRequest struct {
@@ -161,4 +236,4 @@ type UpdateCategoryRequest struct {
 	Description *string `json:"description"`
 	ParentID    *string `json:"parent_id"`
 	IsActive    *bool   `json:"is_active"`
-}
\ No newline at end of file
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	ReservationStatusActive    = "active"
+	ReservationStatusConfirmed = "confirmed"
+	ReservationStatusReleased  = "released"
+	ReservationStatusExpired   = "expired"
+)
+
+type StockReservation struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	ProductID string    `json:"product_id" gorm:"type:uuid;not null;index"`
+	OrderID   string    `json:"order_id" gorm:"type:varchar(100);not null;index"`
+	Quantity  int       `json:"quantity" gorm:"type:integer;not null"`
+	Status    string    `json:"status" gorm:"type:varchar(20);not null;index;default:active"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"type:timestamp;not null;index" dynamodbav:"expires_at,unixtime"`
+	CreatedAt time.Time `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"type:timestamp;autoUpdateTime"`
+}
+
+type CreateReservationRequest struct {
+	ProductID  string `json:"product_id" validate:"required"`
+	OrderID    string `json:"order_id" validate:"required"`
+	Quantity   int    `json:"quantity" validate:"required,min=1"`
+	TTLSeconds int    `json:"ttl_seconds" validate:"omitempty,min=1,max=86400"`
+}
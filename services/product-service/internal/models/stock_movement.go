@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	StockMovementSale       = "sale"
+	StockMovementRestock    = "restock"
+	StockMovementAdjustment = "adjustment"
+	StockMovementReturn     = "return"
+	StockMovementShrinkage  = "shrinkage"
+)
+
+// StockMovement is an append-only ledger entry recording a single change to a
+// product's stock. Summing Delta per product yields its expected stock.
+type StockMovement struct {
+	ID          string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	ProductID   string    `json:"product_id" gorm:"type:uuid;not null;index"`
+	Delta       int       `json:"delta" gorm:"type:integer;not null"`
+	Reason      string    `json:"reason" gorm:"type:varchar(20);not null"`
+	ReferenceID string    `json:"reference_id" gorm:"type:varchar(100);index"`
+	Actor       string    `json:"actor" gorm:"type:varchar(100)"`
+	CreatedAt   time.Time `json:"created_at" gorm:"type:timestamp;autoCreateTime;index"`
+	SortKey     string    `json:"-" gorm:"-" dynamodbav:"sort_key"`
+}
+
+// StockReturn records the return of an order's units of a product, so that
+// a retried return puts nothing back twice
+type StockReturn struct {
+	OrderID    string    `gorm:"primaryKey;type:varchar(100)"`
+	ProductID  string    `gorm:"primaryKey;type:uuid"`
+	MovementID string    `gorm:"type:uuid;not null"`
+	CreatedAt  time.Time `gorm:"type:timestamp;autoCreateTime"`
+}
+
+type StockMovementListResponse struct {
+	Movements  []StockMovement `json:"movements"`
+	TotalCount int             `json:"total_count"`
+	Limit      int             `json:"limit"`
+	Offset     int             `json:"offset"`
+	HasMore    bool            `json:"has_more"`
+}
+
+// UpdateStockRequest adjusts stock by Quantity. The movement is recorded
+// with the authenticated caller as its actor.
+type UpdateStockRequest struct {
+	Quantity    int    `json:"quantity" validate:"required"`
+	Reason      string `json:"reason" validate:"omitempty,oneof=sale restock adjustment return shrinkage"`
+	ReferenceID string `json:"reference_id"`
+}
+
+// ReturnStockRequest puts Quantity units of an order back into stock. Only
+// order-service sends it, once per order and product.
+type ReturnStockRequest struct {
+	OrderID  string `json:"order_id" validate:"required"`
+	Quantity int    `json:"quantity" validate:"required,min=1"`
+}
+
+// StockDrift reports a product whose stock no longer matches its ledger
+type StockDrift struct {
+	ProductID   string `json:"product_id"`
+	SKU         string `json:"sku"`
+	Stock       int    `json:"stock"`
+	LedgerStock int    `json:"ledger_stock"`
+	Drift       int    `json:"drift"`
+}
@@ -0,0 +1,38 @@
+package models
+
+// Kinds of Suggestion
+const (
+	SuggestionProduct  = "product"
+	SuggestionBrand    = "brand"
+	SuggestionCategory = "category"
+)
+
+// Suggestion is a term the search box can complete a prefix to: the name of
+// a product or category, or a brand
+type Suggestion struct {
+	Kind string `json:"kind"`
+	Text string `json:"text"`
+	ID   string `json:"id,omitempty"` // of the product or category
+	Slug string `json:"slug,omitempty"`
+
+	// Popularity is the net units sold of the product, or of the products
+	// of the brand or category
+	Popularity int `json:"-"`
+}
+
+type SuggestionListResponse struct {
+	Query       string       `json:"query"`
+	Suggestions []Suggestion `json:"suggestions"`
+}
+
+// SuggestionKey stores a Suggestion under one of its keys, as the suggestions
+// job rebuilds them. A prefix is completed by the keys it starts.
+type SuggestionKey struct {
+	ID           int64  `gorm:"primaryKey"`
+	SearchKey    string `gorm:"type:text;not null;index:idx_suggestion_keys_search_key,expression:search_key text_pattern_ops"`
+	Kind         string `gorm:"type:varchar(20);not null"`
+	Text         string `gorm:"type:text;not null"`
+	SuggestionID string `gorm:"type:varchar(36);not null;default:''"`
+	Slug         string `gorm:"type:varchar(100);not null;default:''"`
+	Popularity   int64  `gorm:"type:bigint;not null;default:0"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// SynonymGroup is a set of search terms that mean the same, like "tomate",
+// "jitomate" and "tomatoes": searching for any of them finds all. Terms are
+// lowercased and each belongs to one group at most. Searches are expanded
+// word by word, so terms of several words are only found as synonyms of
+// others.
+type SynonymGroup struct {
+	ID        string         `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Terms     pq.StringArray `json:"terms" gorm:"type:text[];not null;index:idx_synonym_groups_terms,type:gin"`
+	CreatedAt time.Time      `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"type:timestamp;autoUpdateTime"`
+}
+
+type SynonymGroupRequest struct {
+	Terms []string `json:"terms" validate:"required,min=2,max=20,dive,required,max=100"`
+}
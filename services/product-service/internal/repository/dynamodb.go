@@ -1,13 +1,16 @@
 package repository
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"product-service/internal/filter"
 	"product-service/internal/models"
+	"shared/apperr"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -17,18 +20,58 @@ import (
 )
 
 type ProductRepository interface {
-	GetProduct(id string) (*models.Product, error)
-	ListProducts(filter models.ProductFilter) (*models.ProductListResponse, error)
-	CreateProduct(product *models.Product) error
-	UpdateProduct(id string, updates *models.UpdateProductRequest) (*models.Product, error)
-	DeleteProduct(id string) error
-	UpdateStock(id string, quantity int) error
-	GetLowStockProducts() ([]models.Product, error)
+	GetProduct(ctx context.Context, id string) (*models.Product, error)
+	ListProducts(ctx context.Context, filter models.ProductFilter) (*models.ProductListResponse, error)
+	ListTagFacets(ctx context.Context, filter models.ProductFilter) ([]models.TagFacet, error)
+	CreateProduct(ctx context.Context, product *models.Product) error
+	UpdateProduct(ctx context.Context, id string, updates *models.UpdateProductRequest, actor string) (*models.Product, error)
+	DeleteProduct(ctx context.Context, id string) error
+	UpdateStock(ctx context.Context, movement *models.StockMovement) error
+	ReturnStock(ctx context.Context, movement *models.StockMovement) error
+	GetLowStockProducts(ctx context.Context) ([]models.Product, error)
+
+	ListDepartments(ctx context.Context, includeInactive bool) ([]models.Department, error)
+	GetDepartment(ctx context.Context, id string) (*models.Department, error)
+	GetDepartmentBySlug(ctx context.Context, slug string) (*models.Department, error)
+	CreateDepartment(ctx context.Context, department *models.Department) error
+	UpdateDepartment(ctx context.Context, id string, updates *models.UpdateDepartmentRequest) (*models.Department, error)
+
+	ListCategories(ctx context.Context, includeInactive bool) ([]models.Category, error)
+	GetCategory(ctx context.Context, id string) (*models.Category, error)
+	GetCategoryBySlug(ctx context.Context, slug string) (*models.Category, error)
+	CreateCategory(ctx context.Context, category *models.Category) error
+	UpdateCategory(ctx context.Context, id string, updates *models.UpdateCategoryRequest) (*models.Category, error)
+	MoveCategory(ctx context.Context, id string, updates *models.UpdateCategoryRequest, relevel func(categories []models.Category) (map[string]int, error)) (*models.Category, error)
+
+	ReserveStock(ctx context.Context, reservation *models.StockReservation) error
+	GetReservation(ctx context.Context, id string) (*models.StockReservation, error)
+	ConfirmReservation(ctx context.Context, id string) (*models.StockReservation, error)
+	ReleaseReservation(ctx context.Context, id string) (*models.StockReservation, error)
+	ExpireReservations(ctx context.Context, before time.Time, productID string) (int, error)
+
+	ListStockMovements(ctx context.Context, productID string, limit, offset int) (*models.StockMovementListResponse, error)
+	ReconcileStock(ctx context.Context) ([]models.StockDrift, error)
+
+	ListSynonymGroups(ctx context.Context) ([]models.SynonymGroup, error)
+	CreateSynonymGroup(ctx context.Context, group *models.SynonymGroup) error
+	UpdateSynonymGroup(ctx context.Context, id string, terms []string) (*models.SynonymGroup, error)
+	DeleteSynonymGroup(ctx context.Context, id string) error
+	SpellingSuggestions(ctx context.Context, words []string) (map[string]string, error)
+	ListSuggestions(ctx context.Context) ([]models.Suggestion, error)
+	ReplaceSuggestions(ctx context.Context, suggestions []models.Suggestion) error
+	FindSuggestions(ctx context.Context, prefix string, limit int) ([]models.Suggestion, error)
 }
 
 type DynamoDBRepository struct {
-	client    *dynamodb.DynamoDB
-	tableName string
+	client            *dynamodb.DynamoDB
+	tableName         string
+	departmentsTable  string
+	categoriesTable   string
+	reservationsTable string
+	movementsTable    string
+	synonymsTable     string
+	slugsTable        string
+	suggestionsTable  string
 }
 
 func NewDynamoDBRepository(tableName string) *DynamoDBRepository {
@@ -36,12 +79,19 @@ func NewDynamoDBRepository(tableName string) *DynamoDBRepository {
 	client := dynamodb.New(sess)
 	
 	return &DynamoDBRepository{
-		client:    client,
-		tableName: tableName,
+		client:            client,
+		tableName:         tableName,
+		departmentsTable:  tableName + "-departments",
+		categoriesTable:   tableName + "-categories",
+		reservationsTable: tableName + "-reservations",
+		movementsTable:    tableName + "-stock-movements",
+		synonymsTable:     tableName + "-synonyms",
+		slugsTable:        tableName + "-slugs",
+		suggestionsTable:  tableName + "-suggestions",
 	}
 }
 
-func (r *DynamoDBRepository) GetProduct(id string) (*models.Product, error) {
+func (r *DynamoDBRepository) GetProduct(ctx context.Context, id string) (*models.Product, error) {
 	input := &dynamodb.GetItemInput{
 		TableName: aws.String(r.tableName),
 		Key: map[string]*dynamodb.AttributeValue{
@@ -51,13 +101,13 @@ func (r *DynamoDBRepository) GetProduct(id string) (*models.Product, error) {
 		},
 	}
 
-	result, err := r.client.GetItem(input)
+	result, err := r.client.GetItemWithContext(ctx, input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get product: %w", err)
 	}
 
 	if result.Item == nil {
-		return nil, errors.New("product not found")
+		return nil, apperr.NotFound("product not found")
 	}
 
 	var product models.Product
@@ -69,146 +119,216 @@ func (r *DynamoDBRepository) GetProduct(id string) (*models.Product, error) {
 	return &product, nil
 }
 
-func (r *DynamoDBRepository) ListProducts(filter models.ProductFilter) (*models.ProductListResponse, error) {
-	var filterExpression []string
-	var expressionAttributeNames map[string]*string
-	var expressionAttributeValues map[string]*dynamodb.AttributeValue
-
-	expressionAttributeNames = make(map[string]*string)
-	expressionAttributeValues = make(map[string]*dynamodb.AttributeValue)
+func (r *DynamoDBRepository) ListProducts(ctx context.Context, productFilter models.ProductFilter) (*models.ProductListResponse, error) {
+	if productFilter.Sort == models.SortRelevance {
+		return r.listRankedProducts(ctx, productFilter)
+	}
 
-	// Build filter expression
-	if filter.CategoryID != "" {
-		filterExpression = append(filterExpression, "#category_id = :category_id")
-		expressionAttributeNames["#category_id"] = aws.String("category_id")
-		expressionAttributeValues[":category_id"] = &dynamodb.AttributeValue{S: aws.String(filter.CategoryID)}
+	totalCount, approximate, err := r.countListing(ctx, productFilter)
+	if err != nil {
+		return nil, err
 	}
 
-	if filter.DepartmentID != "" {
-		filterExpression = append(filterExpression, "#department_id = :department_id")
-		expressionAttributeNames["#department_id"] = aws.String("department_id")
-		expressionAttributeValues[":department_id"] = &dynamodb.AttributeValue{S: aws.String(filter.DepartmentID)}
+	products, err := r.queryListing(ctx, productFilter)
+	if err != nil {
+		return nil, err
 	}
 
-	if filter.Brand != "" {
-		filterExpression = append(filterExpression, "#brand = :brand")
-		expressionAttributeNames["#brand"] = aws.String("brand")
-		expressionAttributeValues[":brand"] = &dynamodb.AttributeValue{S: aws.String(filter.Brand)}
+	response := &models.ProductListResponse{
+		TotalCount:            totalCount,
+		TotalCountApproximate: approximate,
+		Limit:                 productFilter.Limit,
+		Offset:                productFilter.Offset,
 	}
+	pageProducts(response, products, productFilter)
 
-	if filter.MinPrice != nil {
-		filterExpression = append(filterExpression, "#price >= :min_price")
-		expressionAttributeNames["#price"] = aws.String("price")
-		expressionAttributeValues[":min_price"] = &dynamodb.AttributeValue{N: aws.String(fmt.Sprintf("%f", *filter.MinPrice))}
+	return response, nil
+}
+
+// listRankedProducts lists the products matching a search by relevance. The
+// rank is computed in memory, so every match is read and sorted.
+func (r *DynamoDBRepository) listRankedProducts(ctx context.Context, productFilter models.ProductFilter) (*models.ProductListResponse, error) {
+	products, err := r.scanProducts(ctx, productFilter)
+	if err != nil {
+		return nil, err
 	}
 
-	if filter.MaxPrice != nil {
-		filterExpression = append(filterExpression, "#price <= :max_price")
-		expressionAttributeNames["#price"] = aws.String("price")
-		expressionAttributeValues[":max_price"] = &dynamodb.AttributeValue{N: aws.String(fmt.Sprintf("%f", *filter.MaxPrice))}
+	// Scan order is undefined, so products are sorted before paging
+	if err := filter.SortProducts(products, productFilter.Sort, filter.SearchOf(productFilter)); err != nil {
+		return nil, fmt.Errorf("failed to sort products: %w", err)
 	}
 
-	if filter.InStock != nil && *filter.InStock {
-		filterExpression = append(filterExpression, "#stock > :zero")
-		expressionAttributeNames["#stock"] = aws.String("stock")
-		expressionAttributeValues[":zero"] = &dynamodb.AttributeValue{N: aws.String("0")}
+	totalCount := len(products)
+
+	// Apply pagination, seeking past the cursor instead of an offset
+	seek := productFilter.Seek
+	if seek != nil {
+		products, err = filter.SeekProducts(products, productFilter.Sort, filter.SearchOf(productFilter), seek.Values, !seek.Backward)
+		if err != nil {
+			return nil, fmt.Errorf("failed to seek products: %w", err)
+		}
+		if seek.Backward {
+			// Read backward pages from the end, as pageProducts expects
+			reversed := make([]models.Product, len(products))
+			for i := range products {
+				reversed[len(products)-1-i] = products[i]
+			}
+			products = reversed
+		}
+	} else if productFilter.Offset >= len(products) {
+		products = []models.Product{}
+	} else if productFilter.Offset > 0 {
+		products = products[productFilter.Offset:]
 	}
 
-	if filter.IsOnSale != nil && *filter.IsOnSale {
-		filterExpression = append(filterExpression, "#is_on_sale = :is_on_sale")
-		expressionAttributeNames["#is_on_sale"] = aws.String("is_on_sale")
-		expressionAttributeValues[":is_on_sale"] = &dynamodb.AttributeValue{BOOL: aws.Bool(true)}
+	// One product past the page tells whether there are more
+	if productFilter.Limit > 0 && productFilter.Limit+1 < len(products) {
+		products = products[:productFilter.Limit+1]
 	}
 
-	if filter.MinRating != nil {
-		filterExpression = append(filterExpression, "#rating >= :min_rating")
-		expressionAttributeNames["#rating"] = aws.String("rating")
-		expressionAttributeValues[":min_rating"] = &dynamodb.AttributeValue{N: aws.String(fmt.Sprintf("%f", *filter.MinRating))}
+	response := &models.ProductListResponse{
+		TotalCount: totalCount,
+		Limit:      productFilter.Limit,
+		Offset:     productFilter.Offset,
 	}
+	pageProducts(response, products, productFilter)
 
-	// Always filter for active products
-	filterExpression = append(filterExpression, "#is_active = :is_active")
-	expressionAttributeNames["#is_active"] = aws.String("is_active")
-	expressionAttributeValues[":is_active"] = &dynamodb.AttributeValue{BOOL: aws.Bool(true)}
+	return response, nil
+}
 
-	input := &dynamodb.ScanInput{
-		TableName: aws.String(r.tableName),
+// ListTagFacets counts the products matching the filter by tag, most used
+// first
+func (r *DynamoDBRepository) ListTagFacets(ctx context.Context, productFilter models.ProductFilter) ([]models.TagFacet, error) {
+	products, err := r.scanProducts(ctx, productFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, product := range products {
+		for _, tag := range product.Tags {
+			counts[tag]++
+		}
 	}
 
-	if len(filterExpression) > 0 {
-		input.FilterExpression = aws.String(strings.Join(filterExpression, " AND "))
-		input.ExpressionAttributeNames = expressionAttributeNames
-		input.ExpressionAttributeValues = expressionAttributeValues
+	facets := make([]models.TagFacet, 0, len(counts))
+	for tag, count := range counts {
+		facets = append(facets, models.TagFacet{Tag: tag, Count: count})
 	}
+	sort.Slice(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Tag < facets[j].Tag
+	})
+
+	return facets, nil
+}
 
-	if filter.Limit > 0 {
-		input.Limit = aws.Int64(int64(filter.Limit))
+// maxScannedProducts bounds the items scanProducts reads. A filter expression
+// does not narrow a Scan: it reads, and is charged for, every item of the
+// table, about one read unit per 4KB, however few match. Past this bound the
+// table is too large to scan within a request.
+const maxScannedProducts = 10000
+
+// scanProducts reads every product matching the filter, ignoring its page,
+// failing when the table holds more than maxScannedProducts items
+func (r *DynamoDBRepository) scanProducts(ctx context.Context, productFilter models.ProductFilter) ([]models.Product, error) {
+	condition, residual, err := filter.DynamoFilter(filter.FromProductFilter(productFilter))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build product filter: %w", err)
 	}
 
-	result, err := r.client.Scan(input)
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(r.tableName),
+	}
+	condition.Apply(input)
+
+	// A Scan limit caps the items read, not the items matched, so the
+	// filter is applied to the whole table before paging
+	var items []map[string]*dynamodb.AttributeValue
+	scannedCount := int64(0)
+	err = r.client.ScanPagesWithContext(ctx, input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		items = append(items, page.Items...)
+		scannedCount += aws.Int64Value(page.ScannedCount)
+		return scannedCount <= maxScannedProducts
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan products: %w", err)
 	}
+	if scannedCount > maxScannedProducts {
+		return nil, apperr.Unavailable(fmt.Sprintf("products table holds more than %d items to scan", maxScannedProducts), nil)
+	}
 
-	var products []models.Product
-	err = dynamodbattribute.UnmarshalListOfMaps(result.Items, &products)
+	var scanned []models.Product
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &scanned)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal products: %w", err)
 	}
 
-	// Apply search filter in memory (for simplicity)
-	if filter.Search != "" {
-		filteredProducts := make([]models.Product, 0)
-		searchLower := strings.ToLower(filter.Search)
-		for _, product := range products {
-			if strings.Contains(strings.ToLower(product.Name), searchLower) ||
-				strings.Contains(strings.ToLower(product.Description), searchLower) ||
-				strings.Contains(strings.ToLower(product.SKU), searchLower) ||
-				strings.Contains(strings.ToLower(product.Brand), searchLower) ||
-				strings.Contains(strings.ToLower(product.Slug), searchLower) {
-				filteredProducts = append(filteredProducts, product)
+	// Conditions DynamoDB cannot evaluate, like search, are matched here
+	products := make([]models.Product, 0, len(scanned))
+	for i := range scanned {
+		ok, err := filter.Match(residual, &scanned[i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter products: %w", err)
+		}
+		if ok {
+			if productFilter.Search != "" {
+				scanned[i].SearchRank = filter.Rank(&scanned[i], filter.SearchOf(productFilter))
 			}
+			products = append(products, scanned[i])
 		}
-		products = filteredProducts
-	}
-
-	// Apply offset
-	if filter.Offset > 0 && filter.Offset < len(products) {
-		products = products[filter.Offset:]
-	} else if filter.Offset >= len(products) {
-		products = []models.Product{}
 	}
 
-	totalCount := len(products)
-	if filter.Limit > 0 && filter.Limit < len(products) {
-		products = products[:filter.Limit]
-	}
-
-	return &models.ProductListResponse{
-		Products:   products,
-		TotalCount: totalCount,
-		Limit:      filter.Limit,
-		Offset:     filter.Offset,
-	}, nil
+	return products, nil
 }
 
-func (r *DynamoDBRepository) CreateProduct(product *models.Product) error {
+func (r *DynamoDBRepository) CreateProduct(ctx context.Context, product *models.Product) error {
 	product.ID = uuid.New().String()
 	product.CreatedAt = time.Now().UTC()
 	product.UpdatedAt = time.Now().UTC()
 	product.IsActive = true
+	product.Available = product.Stock - product.Reserved
 
 	item, err := dynamodbattribute.MarshalMap(product)
 	if err != nil {
 		return fmt.Errorf("failed to marshal product: %w", err)
 	}
+	listing, err := listingAttributes(product)
+	if err != nil {
+		return err
+	}
+	for name, value := range listing {
+		item[name] = value
+	}
 
-	input := &dynamodb.PutItemInput{
-		TableName: aws.String(r.tableName),
-		Item:      item,
+	transactItems := []*dynamodb.TransactWriteItem{
+		{
+			Put: &dynamodb.Put{
+				TableName: aws.String(r.tableName),
+				Item:      item,
+			},
+		},
 	}
 
-	_, err = r.client.PutItem(input)
+	// Opening stock is the first ledger entry so the ledger always sums to
+	// the product's stock
+	if product.Stock != 0 {
+		ledgerEntry, err := r.stockMovementPut(&models.StockMovement{
+			ProductID: product.ID,
+			Delta:     product.Stock,
+			Reason:    models.StockMovementRestock,
+		})
+		if err != nil {
+			return err
+		}
+		transactItems = append(transactItems, ledgerEntry)
+	}
+
+	_, err = r.client.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: transactItems,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to create product: %w", err)
 	}
@@ -216,7 +336,9 @@ func (r *DynamoDBRepository) CreateProduct(product *models.Product) error {
 	return nil
 }
 
-func (r *DynamoDBRepository) UpdateProduct(id string, updates *models.UpdateProductRequest) (*models.Product, error) {
+// UpdateProduct updates a product's fields and, when updates set its stock,
+// records the difference as an adjustment by actor in the same transaction
+func (r *DynamoDBRepository) UpdateProduct(ctx context.Context, id string, updates *models.UpdateProductRequest, actor string) (*models.Product, error) {
 	var updateExpression []string
 	var expressionAttributeNames map[string]*string
 	var expressionAttributeValues map[string]*dynamodb.AttributeValue
@@ -239,7 +361,7 @@ func (r *DynamoDBRepository) UpdateProduct(id string, updates *models.UpdateProd
 	if updates.Price != nil {
 		updateExpression = append(updateExpression, "#price = :price")
 		expressionAttributeNames["#price"] = aws.String("price")
-		expressionAttributeValues[":price"] = &dynamodb.AttributeValue{N: aws.String(fmt.Sprintf("%f", *updates.Price))}
+		expressionAttributeValues[":price"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatFloat(*updates.Price, 'f', -1, 64))}
 	}
 
 	if updates.CategoryID != nil {
@@ -278,12 +400,6 @@ func (r *DynamoDBRepository) UpdateProduct(id string, updates *models.UpdateProd
 		expressionAttributeValues[":unit"] = &dynamodb.AttributeValue{S: aws.String(*updates.Unit)}
 	}
 
-	if updates.Stock != nil {
-		updateExpression = append(updateExpression, "#stock = :stock")
-		expressionAttributeNames["#stock"] = aws.String("stock")
-		expressionAttributeValues[":stock"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(*updates.Stock))}
-	}
-
 	if updates.Weight != nil {
 		updateExpression = append(updateExpression, "#weight = :weight")
 		expressionAttributeNames["#weight"] = aws.String("weight")
@@ -305,13 +421,13 @@ func (r *DynamoDBRepository) UpdateProduct(id string, updates *models.UpdateProd
 	if updates.Discount != nil {
 		updateExpression = append(updateExpression, "#discount = :discount")
 		expressionAttributeNames["#discount"] = aws.String("discount")
-		expressionAttributeValues[":discount"] = &dynamodb.AttributeValue{N: aws.String(fmt.Sprintf("%f", *updates.Discount))}
+		expressionAttributeValues[":discount"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatFloat(*updates.Discount, 'f', -1, 64))}
 	}
 
 	if updates.Rating != nil {
 		updateExpression = append(updateExpression, "#rating = :rating")
 		expressionAttributeNames["#rating"] = aws.String("rating")
-		expressionAttributeValues[":rating"] = &dynamodb.AttributeValue{N: aws.String(fmt.Sprintf("%f", *updates.Rating))}
+		expressionAttributeValues[":rating"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatFloat(*updates.Rating, 'f', -1, 64))}
 	}
 
 	if updates.Reviews != nil {
@@ -320,10 +436,50 @@ func (r *DynamoDBRepository) UpdateProduct(id string, updates *models.UpdateProd
 		expressionAttributeValues[":reviews"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(*updates.Reviews))}
 	}
 
+	var removeExpression []string
 	if updates.IsActive != nil {
 		updateExpression = append(updateExpression, "#is_active = :is_active")
 		expressionAttributeNames["#is_active"] = aws.String("is_active")
 		expressionAttributeValues[":is_active"] = &dynamodb.AttributeValue{BOOL: aws.Bool(*updates.IsActive)}
+
+		// Only active products are in the listing indexes
+		expressionAttributeNames["#listing"] = aws.String(listingAttribute)
+		if *updates.IsActive {
+			updateExpression = append(updateExpression, "#listing = :listing")
+			expressionAttributeValues[":listing"] = &dynamodb.AttributeValue{S: aws.String(listingActive)}
+		} else {
+			removeExpression = append(removeExpression, "#listing")
+		}
+	}
+
+	// Each listing sort key depends on one field and the ID, so those of the
+	// updated fields are set along with them
+	keyed := &models.Product{ID: id, Discount: updates.Discount}
+	var resorted []string
+	if updates.Name != nil {
+		keyed.Name = *updates.Name
+		resorted = append(resorted, models.SortName)
+	}
+	if updates.Price != nil {
+		keyed.Price = *updates.Price
+		resorted = append(resorted, models.SortPriceAsc, models.SortPriceDesc)
+	}
+	if updates.Rating != nil {
+		keyed.Rating = *updates.Rating
+		resorted = append(resorted, models.SortRating)
+	}
+	if updates.Discount != nil {
+		resorted = append(resorted, models.SortDiscount)
+	}
+	for _, order := range resorted {
+		sortKey, err := filter.DynamoSortKey(keyed, order)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode sort key: %w", err)
+		}
+		attribute := listingSortAttribute(order)
+		updateExpression = append(updateExpression, "#"+attribute+" = :"+attribute)
+		expressionAttributeNames["#"+attribute] = aws.String(attribute)
+		expressionAttributeValues[":"+attribute] = &dynamodb.AttributeValue{S: aws.String(sortKey)}
 	}
 
 	// Always update the updated_at timestamp
@@ -332,7 +488,16 @@ func (r *DynamoDBRepository) UpdateProduct(id string, updates *models.UpdateProd
 	expressionAttributeValues[":updated_at"] = &dynamodb.AttributeValue{S: aws.String(time.Now().UTC().Format(time.RFC3339))}
 
 	if len(updateExpression) == 0 {
-		return nil, errors.New("no fields to update")
+		return nil, apperr.Validation("no fields to update")
+	}
+
+	if updates.Stock != nil {
+		movement := &models.StockMovement{ProductID: id, Reason: models.StockMovementAdjustment, Actor: actor}
+		err := r.setStock(ctx, movement, *updates.Stock, updateExpression, removeExpression, expressionAttributeNames, expressionAttributeValues)
+		if err != nil {
+			return nil, err
+		}
+		return r.GetProduct(ctx, id)
 	}
 
 	input := &dynamodb.UpdateItemInput{
@@ -340,13 +505,13 @@ func (r *DynamoDBRepository) UpdateProduct(id string, updates *models.UpdateProd
 		Key: map[string]*dynamodb.AttributeValue{
 			"id": {S: aws.String(id)},
 		},
-		UpdateExpression:          aws.String("SET " + strings.Join(updateExpression, ", ")),
+		UpdateExpression:          aws.String(updateProductExpression(updateExpression, removeExpression)),
 		ExpressionAttributeNames:  expressionAttributeNames,
 		ExpressionAttributeValues: expressionAttributeValues,
 		ReturnValues:              aws.String("ALL_NEW"),
 	}
 
-	result, err := r.client.UpdateItem(input)
+	result, err := r.client.UpdateItemWithContext(ctx, input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update product: %w", err)
 	}
@@ -360,7 +525,7 @@ func (r *DynamoDBRepository) UpdateProduct(id string, updates *models.UpdateProd
 	return &product, nil
 }
 
-func (r *DynamoDBRepository) DeleteProduct(id string) error {
+func (r *DynamoDBRepository) DeleteProduct(ctx context.Context, id string) error {
 	input := &dynamodb.DeleteItemInput{
 		TableName: aws.String(r.tableName),
 		Key: map[string]*dynamodb.AttributeValue{
@@ -368,7 +533,7 @@ func (r *DynamoDBRepository) DeleteProduct(id string) error {
 		},
 	}
 
-	_, err := r.client.DeleteItem(input)
+	_, err := r.client.DeleteItemWithContext(ctx, input)
 	if err != nil {
 		return fmt.Errorf("failed to delete product: %w", err)
 	}
@@ -376,32 +541,182 @@ func (r *DynamoDBRepository) DeleteProduct(id string) error {
 	return nil
 }
 
-func (r *DynamoDBRepository) UpdateStock(id string, quantity int) error {
-	input := &dynamodb.UpdateItemInput{
-		TableName: aws.String(r.tableName),
-		Key: map[string]*dynamodb.AttributeValue{
-			"id": {S: aws.String(id)},
-		},
-		UpdateExpression: aws.String("SET #stock = #stock + :quantity, #updated_at = :updated_at"),
-		ExpressionAttributeNames: map[string]*string{
-			"#stock":      aws.String("stock"),
-			"#updated_at": aws.String("updated_at"),
-		},
-		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
-			":quantity":   {N: aws.String(strconv.Itoa(quantity))},
-			":updated_at": {S: aws.String(time.Now().UTC().Format(time.RFC3339))},
+func (r *DynamoDBRepository) UpdateStock(ctx context.Context, movement *models.StockMovement) error {
+	return r.updateStock(ctx, movement)
+}
+
+// stockReturns keys the claims of the slugs table recording the returns of
+// stock, one per product and order
+const stockReturns = "return"
+
+// ReturnStock puts the units of an order back into a product's stock once.
+// The claim of the return is written with the stock, so a return already
+// made for the order and product puts nothing back again and order-service
+// can retry a return that failed partway.
+func (r *DynamoDBRepository) ReturnStock(ctx context.Context, movement *models.StockMovement) error {
+	claim := &dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{
+			TableName:           aws.String(r.slugsTable),
+			Item:                slugKey(stockReturns, movement.ProductID+"#"+movement.ReferenceID),
+			ConditionExpression: aws.String("attribute_not_exists(#id)"),
+			ExpressionAttributeNames: map[string]*string{
+				"#id": aws.String("id"),
+			},
 		},
 	}
 
-	_, err := r.client.UpdateItem(input)
+	err := r.updateStock(ctx, movement, claim)
+	if isTransactionConditionFailed(err, 2) {
+		return nil
+	}
+	return err
+}
+
+// updateStock adjusts a product's stock by movement and records it, along
+// with the others items, in one transaction
+func (r *DynamoDBRepository) updateStock(ctx context.Context, movement *models.StockMovement, others ...*dynamodb.TransactWriteItem) error {
+	ledgerEntry, err := r.stockMovementPut(movement)
+	if err != nil {
+		return err
+	}
+
+	err = r.transactWrite(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: append([]*dynamodb.TransactWriteItem{
+			{
+				Update: &dynamodb.Update{
+					TableName: aws.String(r.tableName),
+					Key: map[string]*dynamodb.AttributeValue{
+						"id": {S: aws.String(movement.ProductID)},
+					},
+					UpdateExpression: aws.String("SET #stock = #stock + :quantity, #available = if_not_exists(#available, #stock) + :quantity, #updated_at = :updated_at"),
+					// available + quantity >= 0 is evaluated as available >= -quantity so
+					// the guard is applied atomically with the write and never consumes
+					// units held by reservations
+					ConditionExpression: aws.String("attribute_exists(#id) AND (#available >= :min_stock OR (attribute_not_exists(#available) AND #stock >= :min_stock))"),
+					ExpressionAttributeNames: map[string]*string{
+						"#id":         aws.String("id"),
+						"#stock":      aws.String("stock"),
+						"#available":  aws.String("available"),
+						"#updated_at": aws.String("updated_at"),
+					},
+					ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+						":quantity":   {N: aws.String(strconv.Itoa(movement.Delta))},
+						":min_stock":  {N: aws.String(strconv.Itoa(-movement.Delta))},
+						":updated_at": {S: aws.String(time.Now().UTC().Format(time.RFC3339))},
+					},
+				},
+			},
+			ledgerEntry,
+		}, others...),
+	})
 	if err != nil {
+		if isTransactionConditionFailed(err, 0) {
+			item, getErr := r.getItemByID(ctx, r.tableName, movement.ProductID)
+			if getErr != nil {
+				return fmt.Errorf("failed to update stock: %w", getErr)
+			}
+			if item == nil {
+				return apperr.NotFound("product not found")
+			}
+			return apperr.InsufficientStock("product %s cannot be adjusted by %d", movement.ProductID, movement.Delta)
+		}
 		return fmt.Errorf("failed to update stock: %w", err)
 	}
 
 	return nil
 }
 
-func (r *DynamoDBRepository) GetLowStockProducts() ([]models.Product, error) {
+// updateProductExpression joins the SET and REMOVE actions of a product update
+func updateProductExpression(set, remove []string) string {
+	expression := "SET " + strings.Join(set, ", ")
+	if len(remove) > 0 {
+		expression += " REMOVE " + strings.Join(remove, ", ")
+	}
+	return expression
+}
+
+// setStock writes the update of a product the set and remove actions, names
+// and values make up, setting its stock to stock and recording the difference
+// as movement in the same transaction. The write is conditioned on the stock
+// and reservations the difference was computed from, and is recomputed from a
+// fresh read while concurrent writes keep changing them.
+func (r *DynamoDBRepository) setStock(ctx context.Context, movement *models.StockMovement, stock int, set, remove []string, names map[string]*string, values map[string]*dynamodb.AttributeValue) error {
+	names["#stock"] = aws.String("stock")
+	names["#reserved"] = aws.String("reserved")
+	names["#available"] = aws.String("available")
+
+	for attempt := 0; ; attempt++ {
+		item, err := r.getItemByID(ctx, r.tableName, movement.ProductID)
+		if err != nil {
+			return fmt.Errorf("failed to set stock: %w", err)
+		}
+		if item == nil {
+			return apperr.NotFound("product not found")
+		}
+
+		var current models.Product
+		if err := dynamodbattribute.UnmarshalMap(item, &current); err != nil {
+			return fmt.Errorf("failed to unmarshal product: %w", err)
+		}
+		if stock < current.Reserved {
+			return apperr.InsufficientStock("product %s cannot be set to %d units, %d are reserved", movement.ProductID, stock, current.Reserved)
+		}
+
+		// Values left over from an earlier attempt would be unused by this
+		// one's expressions, which DynamoDB rejects
+		attemptValues := make(map[string]*dynamodb.AttributeValue, len(values)+4)
+		for name, value := range values {
+			attemptValues[name] = value
+		}
+		attemptValues[":stock"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(stock))}
+		attemptValues[":current"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(current.Stock))}
+		attemptValues[":available"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(stock - current.Reserved))}
+
+		// Items never reserved have no reserved attribute to compare
+		reservedCondition := "attribute_not_exists(#reserved)"
+		if _, ok := item["reserved"]; ok {
+			reservedCondition = "#reserved = :reserved"
+			attemptValues[":reserved"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(current.Reserved))}
+		}
+
+		items := []*dynamodb.TransactWriteItem{
+			{
+				Update: &dynamodb.Update{
+					TableName: aws.String(r.tableName),
+					Key: map[string]*dynamodb.AttributeValue{
+						"id": {S: aws.String(movement.ProductID)},
+					},
+					UpdateExpression:          aws.String(updateProductExpression(append(set[:len(set):len(set)], "#stock = :stock", "#available = :available"), remove)),
+					ConditionExpression:       aws.String("#stock = :current AND " + reservedCondition),
+					ExpressionAttributeNames:  names,
+					ExpressionAttributeValues: attemptValues,
+				},
+			},
+		}
+
+		movement.Delta = stock - current.Stock
+		if movement.Delta != 0 {
+			ledgerEntry, err := r.stockMovementPut(movement)
+			if err != nil {
+				return err
+			}
+			items = append(items, ledgerEntry)
+		}
+
+		err = r.transactWrite(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+		if err == nil {
+			return nil
+		}
+		if !isTransactionConditionFailed(err, 0) {
+			return fmt.Errorf("failed to set stock: %w", err)
+		}
+		if attempt == transactionConflictRetries {
+			return apperr.Conflict("stock of product %s was updated concurrently, retry", movement.ProductID)
+		}
+	}
+}
+
+func (r *DynamoDBRepository) GetLowStockProducts(ctx context.Context) ([]models.Product, error) {
 	input := &dynamodb.ScanInput{
 		TableName:        aws.String(r.tableName),
 		FilterExpression: aws.String("#stock <= #min_stock AND #is_active = :is_active"),
@@ -415,7 +730,7 @@ func (r *DynamoDBRepository) GetLowStockProducts() ([]models.Product, error) {
 		},
 	}
 
-	result, err := r.client.Scan(input)
+	result, err := r.client.ScanWithContext(ctx, input)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get low stock products: %w", err)
 	}
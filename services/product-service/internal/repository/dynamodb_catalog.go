@@ -0,0 +1,692 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"product-service/internal/models"
+	"shared/apperr"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/uuid"
+)
+
+func (r *DynamoDBRepository) ListDepartments(ctx context.Context, includeInactive bool) ([]models.Department, error) {
+	items, err := r.scanAll(ctx, activeScanInput(r.departmentsTable, includeInactive))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list departments: %w", err)
+	}
+
+	var departments []models.Department
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &departments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal departments: %w", err)
+	}
+
+	return departments, nil
+}
+
+func (r *DynamoDBRepository) GetDepartment(ctx context.Context, id string) (*models.Department, error) {
+	item, err := r.getItemByID(ctx, r.departmentsTable, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get department: %w", err)
+	}
+
+	var department models.Department
+	if item != nil {
+		err = dynamodbattribute.UnmarshalMap(item, &department)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal department: %w", err)
+		}
+	}
+
+	if item == nil || !department.IsActive {
+		return nil, apperr.NotFound("department not found")
+	}
+
+	return &department, nil
+}
+
+func (r *DynamoDBRepository) GetDepartmentBySlug(ctx context.Context, slug string) (*models.Department, error) {
+	items, err := r.scanBySlug(ctx, r.departmentsTable, slug, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get department: %w", err)
+	}
+
+	var departments []models.Department
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &departments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal department: %w", err)
+	}
+
+	for _, department := range departments {
+		if department.IsActive {
+			return &department, nil
+		}
+	}
+
+	return nil, apperr.NotFound("department not found")
+}
+
+func (r *DynamoDBRepository) CreateDepartment(ctx context.Context, department *models.Department) error {
+	existing, err := r.scanBySlug(ctx, r.departmentsTable, department.Slug, "")
+	if err != nil {
+		return fmt.Errorf("failed to check department slug: %w", err)
+	}
+	if len(existing) > 0 {
+		return apperr.Conflict("department slug already exists")
+	}
+
+	department.ID = uuid.New().String()
+	department.CreatedAt = time.Now().UTC()
+	department.UpdatedAt = time.Now().UTC()
+	department.IsActive = true
+
+	item, err := dynamodbattribute.MarshalMap(department)
+	if err != nil {
+		return fmt.Errorf("failed to marshal department: %w", err)
+	}
+
+	_, err = r.client.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			r.claimSlug(departmentSlugs, department.Slug, department.ID),
+			{
+				Put: &dynamodb.Put{
+					TableName: aws.String(r.departmentsTable),
+					Item:      item,
+				},
+			},
+		},
+	})
+	if err != nil {
+		if isTransactionConditionFailed(err, 0) {
+			return apperr.Conflict("department slug already exists")
+		}
+		return fmt.Errorf("failed to create department: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DynamoDBRepository) UpdateDepartment(ctx context.Context, id string, updates *models.UpdateDepartmentRequest) (*models.Department, error) {
+	update := newUpdateBuilder()
+
+	if updates.Name != nil {
+		update.setString("name", *updates.Name)
+	}
+	if updates.Description != nil {
+		update.setString("description", *updates.Description)
+	}
+	if updates.Icon != nil {
+		update.setString("icon", *updates.Icon)
+	}
+	if updates.Image != nil {
+		update.setString("image", *updates.Image)
+	}
+	if updates.Slug != nil {
+		existing, err := r.scanBySlug(ctx, r.departmentsTable, *updates.Slug, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check department slug: %w", err)
+		}
+		if len(existing) > 0 {
+			return nil, apperr.Conflict("department slug already exists")
+		}
+		update.setString("slug", *updates.Slug)
+	}
+	if updates.IsActive != nil {
+		update.setBool("is_active", *updates.IsActive)
+	}
+
+	if update.empty() {
+		return nil, apperr.Validation("no fields to update")
+	}
+
+	var attributes map[string]*dynamodb.AttributeValue
+	var err error
+	if updates.Slug != nil {
+		attributes, err = r.transactUpdate(ctx, r.departmentsTable, departmentSlugs, id, updates.Slug, update, nil)
+	} else {
+		attributes, err = r.applyUpdate(ctx, r.departmentsTable, id, update)
+	}
+	if err != nil {
+		if isConditionalCheckFailed(err) {
+			return nil, apperr.NotFound("department not found")
+		}
+		return nil, fmt.Errorf("failed to update department: %w", err)
+	}
+
+	var department models.Department
+	err = dynamodbattribute.UnmarshalMap(attributes, &department)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal updated department: %w", err)
+	}
+
+	return &department, nil
+}
+
+func (r *DynamoDBRepository) ListCategories(ctx context.Context, includeInactive bool) ([]models.Category, error) {
+	items, err := r.scanAll(ctx, activeScanInput(r.categoriesTable, includeInactive))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list categories: %w", err)
+	}
+
+	var categories []models.Category
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &categories)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+func (r *DynamoDBRepository) GetCategory(ctx context.Context, id string) (*models.Category, error) {
+	item, err := r.getItemByID(ctx, r.categoriesTable, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category: %w", err)
+	}
+
+	var category models.Category
+	if item != nil {
+		err = dynamodbattribute.UnmarshalMap(item, &category)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal category: %w", err)
+		}
+	}
+
+	if item == nil || !category.IsActive {
+		return nil, apperr.NotFound("category not found")
+	}
+
+	return &category, nil
+}
+
+func (r *DynamoDBRepository) GetCategoryBySlug(ctx context.Context, slug string) (*models.Category, error) {
+	items, err := r.scanBySlug(ctx, r.categoriesTable, slug, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category: %w", err)
+	}
+
+	var categories []models.Category
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &categories)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal category: %w", err)
+	}
+
+	for _, category := range categories {
+		if category.IsActive {
+			return &category, nil
+		}
+	}
+
+	return nil, apperr.NotFound("category not found")
+}
+
+func (r *DynamoDBRepository) CreateCategory(ctx context.Context, category *models.Category) error {
+	existing, err := r.scanBySlug(ctx, r.categoriesTable, category.Slug, "")
+	if err != nil {
+		return fmt.Errorf("failed to check category slug: %w", err)
+	}
+	if len(existing) > 0 {
+		return apperr.Conflict("category slug already exists")
+	}
+
+	category.ID = uuid.New().String()
+	category.CreatedAt = time.Now().UTC()
+	category.UpdatedAt = time.Now().UTC()
+	category.IsActive = true
+
+	item, err := dynamodbattribute.MarshalMap(category)
+	if err != nil {
+		return fmt.Errorf("failed to marshal category: %w", err)
+	}
+
+	_, err = r.client.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			r.claimSlug(categorySlugs, category.Slug, category.ID),
+			{
+				Put: &dynamodb.Put{
+					TableName: aws.String(r.categoriesTable),
+					Item:      item,
+				},
+			},
+		},
+	})
+	if err != nil {
+		if isTransactionConditionFailed(err, 0) {
+			return apperr.Conflict("category slug already exists")
+		}
+		return fmt.Errorf("failed to create category: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DynamoDBRepository) UpdateCategory(ctx context.Context, id string, updates *models.UpdateCategoryRequest) (*models.Category, error) {
+	return r.updateCategory(ctx, id, updates, nil, nil)
+}
+
+// categoryTreeVersion keys the item of the slugs table holding the version of
+// the category tree, which every move bumps
+const categoryTreeVersion = "category-tree"
+
+// MoveCategory applies updates that move a category and stores the new levels
+// of the categories below it in the same transaction, so that a failure
+// leaves the hierarchy as it was. A transaction holds at most 100 writes,
+// which bounds the subtree a single move can re-level. The move is planned
+// with relevel on the tree as read, and only commits if no other move bumped
+// the tree version since, so that concurrent moves cannot make a cycle.
+func (r *DynamoDBRepository) MoveCategory(ctx context.Context, id string, updates *models.UpdateCategoryRequest, relevel func(categories []models.Category) (map[string]int, error)) (*models.Category, error) {
+	item, err := r.getItemByID(ctx, r.slugsTable, categoryTreeVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category tree version: %w", err)
+	}
+	version := 0
+	if item != nil {
+		if err := dynamodbattribute.Unmarshal(item["version"], &version); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal category tree version: %w", err)
+		}
+	}
+
+	categories, err := r.ListCategories(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := relevel(categories)
+	if err != nil {
+		return nil, err
+	}
+
+	condition := "attribute_not_exists(#id)"
+	values := map[string]*dynamodb.AttributeValue{
+		":next": {N: aws.String(strconv.Itoa(version + 1))},
+	}
+	if item != nil {
+		condition = "#version = :version"
+		values[":version"] = &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(version))}
+	}
+	bump := &dynamodb.TransactWriteItem{
+		Update: &dynamodb.Update{
+			TableName:           aws.String(r.slugsTable),
+			Key:                 map[string]*dynamodb.AttributeValue{"id": {S: aws.String(categoryTreeVersion)}},
+			UpdateExpression:    aws.String("SET #version = :next"),
+			ConditionExpression: aws.String(condition),
+			ExpressionAttributeNames: map[string]*string{
+				"#id":      aws.String("id"),
+				"#version": aws.String("version"),
+			},
+			ExpressionAttributeValues: values,
+		},
+	}
+
+	return r.updateCategory(ctx, id, updates, levels, bump)
+}
+
+// updateCategory applies updates to a category, with the level changes and
+// a guard to write in the same transaction when moving it
+func (r *DynamoDBRepository) updateCategory(ctx context.Context, id string, updates *models.UpdateCategoryRequest, levels map[string]int, guard *dynamodb.TransactWriteItem) (*models.Category, error) {
+	update := newUpdateBuilder()
+
+	if updates.Name != nil {
+		update.setString("name", *updates.Name)
+	}
+	if updates.Description != nil {
+		update.setString("description", *updates.Description)
+	}
+	if updates.Slug != nil {
+		existing, err := r.scanBySlug(ctx, r.categoriesTable, *updates.Slug, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check category slug: %w", err)
+		}
+		if len(existing) > 0 {
+			return nil, apperr.Conflict("category slug already exists")
+		}
+		update.setString("slug", *updates.Slug)
+	}
+	if updates.ParentID != nil {
+		// An empty parent ID moves the category back to the root
+		if *updates.ParentID == "" {
+			update.setNull("parent_id")
+		} else {
+			update.setString("parent_id", *updates.ParentID)
+		}
+	}
+	if updates.IsActive != nil {
+		update.setBool("is_active", *updates.IsActive)
+	}
+
+	if update.empty() {
+		return nil, apperr.Validation("no fields to update")
+	}
+
+	var others []*dynamodb.TransactWriteItem
+	if guard != nil {
+		others = append(others, guard)
+	}
+	for levelID, level := range levels {
+		if levelID == id {
+			update.set("level", &dynamodb.AttributeValue{N: aws.String(strconv.Itoa(level))})
+			continue
+		}
+		others = append(others, r.categoryLevelUpdate(levelID, level))
+	}
+	// Leave room for the category and the claims of its slug
+	if len(others) > maxTransactItems-3 {
+		return nil, apperr.Validation(fmt.Sprintf("cannot move a category with more than %d descendants", maxTransactItems-4))
+	}
+
+	var attributes map[string]*dynamodb.AttributeValue
+	var err error
+	if updates.Slug != nil || len(others) > 0 {
+		attributes, err = r.transactUpdate(ctx, r.categoriesTable, categorySlugs, id, updates.Slug, update, others)
+	} else {
+		attributes, err = r.applyUpdate(ctx, r.categoriesTable, id, update)
+	}
+	if err != nil {
+		if isConditionalCheckFailed(err) {
+			return nil, apperr.NotFound("category not found")
+		}
+		return nil, fmt.Errorf("failed to update category: %w", err)
+	}
+
+	var category models.Category
+	err = dynamodbattribute.UnmarshalMap(attributes, &category)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal updated category: %w", err)
+	}
+
+	return &category, nil
+}
+
+// categoryLevelUpdate sets the level of an existing category
+func (r *DynamoDBRepository) categoryLevelUpdate(id string, level int) *dynamodb.TransactWriteItem {
+	return &dynamodb.TransactWriteItem{
+		Update: &dynamodb.Update{
+			TableName: aws.String(r.categoriesTable),
+			Key: map[string]*dynamodb.AttributeValue{
+				"id": {S: aws.String(id)},
+			},
+			UpdateExpression:    aws.String("SET #level = :level"),
+			ConditionExpression: aws.String("attribute_exists(#id)"),
+			ExpressionAttributeNames: map[string]*string{
+				"#id":    aws.String("id"),
+				"#level": aws.String("level"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":level": {N: aws.String(strconv.Itoa(level))},
+			},
+		},
+	}
+}
+
+// updateBuilder collects SET clauses for an UpdateItem call
+type updateBuilder struct {
+	expressions []string
+	names       map[string]*string
+	values      map[string]*dynamodb.AttributeValue
+}
+
+func newUpdateBuilder() *updateBuilder {
+	return &updateBuilder{
+		names:  make(map[string]*string),
+		values: make(map[string]*dynamodb.AttributeValue),
+	}
+}
+
+func (b *updateBuilder) set(attribute string, value *dynamodb.AttributeValue) {
+	b.expressions = append(b.expressions, fmt.Sprintf("#%s = :%s", attribute, attribute))
+	b.names["#"+attribute] = aws.String(attribute)
+	b.values[":"+attribute] = value
+}
+
+func (b *updateBuilder) setString(attribute, value string) {
+	b.set(attribute, &dynamodb.AttributeValue{S: aws.String(value)})
+}
+
+func (b *updateBuilder) setBool(attribute string, value bool) {
+	b.set(attribute, &dynamodb.AttributeValue{BOOL: aws.Bool(value)})
+}
+
+func (b *updateBuilder) setNull(attribute string) {
+	b.set(attribute, &dynamodb.AttributeValue{NULL: aws.Bool(true)})
+}
+
+func (b *updateBuilder) empty() bool {
+	return len(b.expressions) == 0
+}
+
+// expression stamps updated_at and returns the SET expression of the update
+func (b *updateBuilder) expression() *string {
+	// Always update the updated_at timestamp
+	b.setString("updated_at", time.Now().UTC().Format(time.RFC3339))
+	b.names["#id"] = aws.String("id")
+
+	return aws.String("SET " + strings.Join(b.expressions, ", "))
+}
+
+// applyUpdate runs the collected SET clauses against an existing item and
+// returns its new attributes
+func (r *DynamoDBRepository) applyUpdate(ctx context.Context, table, id string, update *updateBuilder) (map[string]*dynamodb.AttributeValue, error) {
+	result, err := r.client.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(table),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {S: aws.String(id)},
+		},
+		UpdateExpression:          update.expression(),
+		ConditionExpression:       aws.String("attribute_exists(#id)"),
+		ExpressionAttributeNames:  update.names,
+		ExpressionAttributeValues: update.values,
+		ReturnValues:              aws.String("ALL_NEW"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Attributes, nil
+}
+
+// Slug claims are items of the slugs table keyed by kind and slug, put in the
+// same transaction as the department or category holding the slug. Their
+// condition keeps two concurrent writes from claiming the same slug, which
+// the scanBySlug check alone cannot.
+const (
+	departmentSlugs = "department"
+	categorySlugs   = "category"
+)
+
+func slugKey(kind, slug string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"id": {S: aws.String(kind + "#" + slug)},
+	}
+}
+
+// claimSlug puts the claim of a slug for ownerID, failing when another item
+// already holds it
+func (r *DynamoDBRepository) claimSlug(kind, slug, ownerID string) *dynamodb.TransactWriteItem {
+	item := slugKey(kind, slug)
+	item["owner_id"] = &dynamodb.AttributeValue{S: aws.String(ownerID)}
+
+	return &dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{
+			TableName:           aws.String(r.slugsTable),
+			Item:                item,
+			ConditionExpression: aws.String("attribute_not_exists(#id) OR #owner_id = :owner_id"),
+			ExpressionAttributeNames: map[string]*string{
+				"#id":       aws.String("id"),
+				"#owner_id": aws.String("owner_id"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":owner_id": {S: aws.String(ownerID)},
+			},
+		},
+	}
+}
+
+// releaseSlug deletes the claim of a slug held by ownerID. Slugs written
+// before claims were kept have none, which is fine.
+func (r *DynamoDBRepository) releaseSlug(kind, slug, ownerID string) *dynamodb.TransactWriteItem {
+	return &dynamodb.TransactWriteItem{
+		Delete: &dynamodb.Delete{
+			TableName:           aws.String(r.slugsTable),
+			Key:                 slugKey(kind, slug),
+			ConditionExpression: aws.String("attribute_not_exists(#id) OR #owner_id = :owner_id"),
+			ExpressionAttributeNames: map[string]*string{
+				"#id":       aws.String("id"),
+				"#owner_id": aws.String("owner_id"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":owner_id": {S: aws.String(ownerID)},
+			},
+		},
+	}
+}
+
+// maxTransactItems is the most items one TransactWriteItems call can write
+const maxTransactItems = 100
+
+// transactUpdate runs an update of an item in one transaction with other
+// writes and returns its new attributes. When slug is set and differs from
+// the slug of the item, its slug claim moves in the same transaction.
+func (r *DynamoDBRepository) transactUpdate(ctx context.Context, table, kind, id string, slug *string, update *updateBuilder, others []*dynamodb.TransactWriteItem) (map[string]*dynamodb.AttributeValue, error) {
+	itemUpdate := &dynamodb.Update{
+		TableName: aws.String(table),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {S: aws.String(id)},
+		},
+		UpdateExpression:          update.expression(),
+		ConditionExpression:       aws.String("attribute_exists(#id)"),
+		ExpressionAttributeNames:  update.names,
+		ExpressionAttributeValues: update.values,
+	}
+	items := []*dynamodb.TransactWriteItem{{Update: itemUpdate}}
+
+	movesSlug := false
+	if slug != nil {
+		current, err := r.getItemByID(ctx, table, id)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil {
+			return nil, apperr.NotFound("%s not found", kind)
+		}
+
+		currentSlug := ""
+		if value, ok := current["slug"]; ok {
+			currentSlug = aws.StringValue(value.S)
+		}
+		if currentSlug != *slug {
+			// The update only applies while the item still holds the slug read
+			// above, so that the claim released is the right one
+			itemUpdate.ConditionExpression = aws.String("attribute_exists(#id) AND #slug = :current_slug")
+			update.values[":current_slug"] = &dynamodb.AttributeValue{S: aws.String(currentSlug)}
+			items = []*dynamodb.TransactWriteItem{
+				r.claimSlug(kind, *slug, id),
+				{Update: itemUpdate},
+				r.releaseSlug(kind, currentSlug, id),
+			}
+			movesSlug = true
+		}
+	}
+
+	_, err := r.client.TransactWriteItemsWithContext(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: append(items, others...),
+	})
+	if err != nil {
+		switch {
+		case movesSlug && isTransactionConditionFailed(err, 0):
+			return nil, apperr.Conflict("%s slug already exists", kind)
+		case movesSlug && isTransactionConditionFailed(err, 1):
+			return nil, apperr.Conflict("%s was updated concurrently, retry", kind)
+		case !movesSlug && isTransactionConditionFailed(err, 0):
+			return nil, apperr.NotFound("%s not found", kind)
+		}
+		for i := range others {
+			if isTransactionConditionFailed(err, len(items)+i) {
+				return nil, apperr.Conflict("%s was updated concurrently, retry", kind)
+			}
+		}
+		return nil, err
+	}
+
+	return r.getItemByID(ctx, table, id)
+}
+
+func (r *DynamoDBRepository) getItemByID(ctx context.Context, table, id string) (map[string]*dynamodb.AttributeValue, error) {
+	result, err := r.client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(table),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {S: aws.String(id)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.Item, nil
+}
+
+// scanBySlug returns every item in table with the given slug, ignoring the
+// item whose ID equals excludeID
+func (r *DynamoDBRepository) scanBySlug(ctx context.Context, table, slug, excludeID string) ([]map[string]*dynamodb.AttributeValue, error) {
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String(table),
+		FilterExpression: aws.String("#slug = :slug"),
+		ExpressionAttributeNames: map[string]*string{
+			"#slug": aws.String("slug"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":slug": {S: aws.String(slug)},
+		},
+	}
+
+	if excludeID != "" {
+		input.FilterExpression = aws.String("#slug = :slug AND #id <> :id")
+		input.ExpressionAttributeNames["#id"] = aws.String("id")
+		input.ExpressionAttributeValues[":id"] = &dynamodb.AttributeValue{S: aws.String(excludeID)}
+	}
+
+	return r.scanAll(ctx, input)
+}
+
+// scanAll follows LastEvaluatedKey until every matching item has been read
+func (r *DynamoDBRepository) scanAll(ctx context.Context, input *dynamodb.ScanInput) ([]map[string]*dynamodb.AttributeValue, error) {
+	var items []map[string]*dynamodb.AttributeValue
+	err := r.client.ScanPagesWithContext(ctx, input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		items = append(items, page.Items...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func activeScanInput(table string, includeInactive bool) *dynamodb.ScanInput {
+	input := &dynamodb.ScanInput{
+		TableName: aws.String(table),
+	}
+
+	if !includeInactive {
+		input.FilterExpression = aws.String("#is_active = :is_active")
+		input.ExpressionAttributeNames = map[string]*string{
+			"#is_active": aws.String("is_active"),
+		}
+		input.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":is_active": {BOOL: aws.Bool(true)},
+		}
+	}
+
+	return input
+}
+
+func isConditionalCheckFailed(err error) bool {
+	var awsErr awserr.Error
+	return errors.As(err, &awsErr) && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
@@ -0,0 +1,324 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"product-service/internal/filter"
+	"product-service/internal/models"
+	"shared/apperr"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+)
+
+// The products table has a global secondary index per sort order, so that a
+// page of a listing is read in order from its cursor rather than sorted from
+// a scan of every product. Each index is keyed by listing (hash), set only on
+// active products to keep inactive ones out, and sort_<order> (range), the
+// sort values filter.DynamoSortKey encodes. All active products share one
+// listing partition.
+const (
+	listingAttribute = "listing"
+	listingActive    = "active"
+
+	// listingQueryPage is the fewest items a listing query reads at once, so
+	// that selective filters do not take a round trip per match
+	listingQueryPage = 100
+)
+
+// listingSorts are the sort orders with an index. Relevance ranks products
+// against a search, so those listings are sorted in memory.
+var listingSorts = []string{
+	models.SortPriceAsc,
+	models.SortPriceDesc,
+	models.SortRating,
+	models.SortNewest,
+	models.SortName,
+	models.SortDiscount,
+}
+
+func listingIndex(order string) string {
+	return "listing-" + strings.ReplaceAll(order, "_", "-")
+}
+
+func listingSortAttribute(order string) string {
+	return "sort_" + order
+}
+
+// listingIndexes defines the listing indexes of the products table and the
+// attributes they are keyed by. They project every attribute, as listings
+// return whole products.
+func listingIndexes() ([]*dynamodb.AttributeDefinition, []*dynamodb.GlobalSecondaryIndex) {
+	attributes := []*dynamodb.AttributeDefinition{
+		{AttributeName: aws.String(listingAttribute), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+	}
+	indexes := make([]*dynamodb.GlobalSecondaryIndex, 0, len(listingSorts))
+	for _, order := range listingSorts {
+		attributes = append(attributes, &dynamodb.AttributeDefinition{
+			AttributeName: aws.String(listingSortAttribute(order)),
+			AttributeType: aws.String(dynamodb.ScalarAttributeTypeS),
+		})
+		indexes = append(indexes, &dynamodb.GlobalSecondaryIndex{
+			IndexName: aws.String(listingIndex(order)),
+			KeySchema: []*dynamodb.KeySchemaElement{
+				{AttributeName: aws.String(listingAttribute), KeyType: aws.String(dynamodb.KeyTypeHash)},
+				{AttributeName: aws.String(listingSortAttribute(order)), KeyType: aws.String(dynamodb.KeyTypeRange)},
+			},
+			Projection: &dynamodb.Projection{ProjectionType: aws.String(dynamodb.ProjectionTypeAll)},
+		})
+	}
+	return attributes, indexes
+}
+
+// listingAttributes returns the index attributes of a product, to store with
+// it
+func listingAttributes(product *models.Product) (map[string]*dynamodb.AttributeValue, error) {
+	attributes := make(map[string]*dynamodb.AttributeValue, len(listingSorts)+1)
+	if product.IsActive {
+		attributes[listingAttribute] = &dynamodb.AttributeValue{S: aws.String(listingActive)}
+	}
+	for _, order := range listingSorts {
+		key, err := filter.DynamoSortKey(product, order)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode sort key: %w", err)
+		}
+		attributes[listingSortAttribute(order)] = &dynamodb.AttributeValue{S: aws.String(key)}
+	}
+	return attributes, nil
+}
+
+// listingQuery queries the index of the sort order of a listing for the
+// products matching its filter, returning the rest of the filter to match in
+// memory
+func (r *DynamoDBRepository) listingQuery(productFilter models.ProductFilter) (*dynamodb.QueryInput, filter.Expr, error) {
+	condition, residual, err := filter.DynamoFilter(filter.FromProductFilter(productFilter))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build product filter: %w", err)
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.tableName),
+		IndexName:              aws.String(listingIndex(productFilter.Sort)),
+		KeyConditionExpression: aws.String("#listing = :listing"),
+		ExpressionAttributeNames: map[string]*string{
+			"#listing": aws.String(listingAttribute),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":listing": {S: aws.String(listingActive)},
+		},
+	}
+	condition.ApplyQuery(input)
+
+	return input, residual, nil
+}
+
+// countListing counts the products of a listing. When DynamoDB evaluates the
+// whole filter the count is a paginated Select COUNT, which reads no
+// attributes; a search is matched in memory, so its candidates are read.
+// Counting stops past maxScannedProducts items read, and the count is then
+// approximate: those found so far.
+func (r *DynamoDBRepository) countListing(ctx context.Context, productFilter models.ProductFilter) (int, bool, error) {
+	input, residual, err := r.listingQuery(productFilter)
+	if err != nil {
+		return 0, false, err
+	}
+	pushed := false
+	if operands, ok := residual.(filter.And); ok && len(operands) == 0 {
+		pushed = true
+		input.Select = aws.String(dynamodb.SelectCount)
+	}
+
+	count := 0
+	scannedCount := int64(0)
+	var matchErr error
+	err = r.client.QueryPagesWithContext(ctx, input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		scannedCount += aws.Int64Value(page.ScannedCount)
+		if pushed {
+			count += int(aws.Int64Value(page.Count))
+			return scannedCount <= maxScannedProducts
+		}
+
+		var products []models.Product
+		if matchErr = dynamodbattribute.UnmarshalListOfMaps(page.Items, &products); matchErr != nil {
+			return false
+		}
+		for i := range products {
+			var ok bool
+			if ok, matchErr = filter.Match(residual, &products[i]); matchErr != nil {
+				return false
+			}
+			if ok {
+				count++
+			}
+		}
+		return scannedCount <= maxScannedProducts
+	})
+	if err == nil {
+		err = matchErr
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to count products: %w", err)
+	}
+
+	return count, scannedCount > maxScannedProducts, nil
+}
+
+// queryListing reads the products of a listing from the index of its sort
+// order, starting past its cursor and going backwards for a backward page,
+// with one more than the limit when there are more. DynamoDB filters items
+// after reading them, so the query reads on until enough match.
+func (r *DynamoDBRepository) queryListing(ctx context.Context, productFilter models.ProductFilter) ([]models.Product, error) {
+	input, residual, err := r.listingQuery(productFilter)
+	if err != nil {
+		return nil, err
+	}
+
+	seek := productFilter.Seek
+	skip := productFilter.Offset
+	if seek != nil {
+		sortKey, id, err := filter.DynamoSeekKey(productFilter.Sort, seek.Values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to seek products: %w", err)
+		}
+		input.ExclusiveStartKey = map[string]*dynamodb.AttributeValue{
+			"id":                                     {S: aws.String(id)},
+			listingAttribute:                         {S: aws.String(listingActive)},
+			listingSortAttribute(productFilter.Sort): {S: aws.String(sortKey)},
+		}
+		input.ScanIndexForward = aws.Bool(!seek.Backward)
+		skip = 0
+	}
+
+	// One product past the page tells whether there are more
+	want := 0
+	if productFilter.Limit > 0 {
+		want = productFilter.Limit + 1
+	}
+
+	products := []models.Product{}
+	scannedCount := int64(0)
+	for {
+		if want > 0 {
+			input.Limit = aws.Int64(int64(max(skip+want-len(products), listingQueryPage)))
+		}
+
+		output, err := r.client.QueryWithContext(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query products: %w", err)
+		}
+
+		var page []models.Product
+		if err := dynamodbattribute.UnmarshalListOfMaps(output.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal products: %w", err)
+		}
+
+		// Conditions DynamoDB cannot evaluate, like search, are matched here
+		for i := range page {
+			ok, err := filter.Match(residual, &page[i])
+			if err != nil {
+				return nil, fmt.Errorf("failed to filter products: %w", err)
+			}
+			if !ok {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+
+			products = append(products, page[i])
+			if len(products) == want {
+				return products, nil
+			}
+		}
+
+		if output.LastEvaluatedKey == nil {
+			return products, nil
+		}
+		scannedCount += aws.Int64Value(output.ScannedCount)
+		if scannedCount > maxScannedProducts {
+			return nil, apperr.Unavailable(fmt.Sprintf("listing reads more than %d products to fill a page", maxScannedProducts), nil)
+		}
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+}
+
+// ReindexProducts sets the listing attributes of every product, for products
+// written before the listing indexes existed, and returns how many it set
+func (r *DynamoDBRepository) ReindexProducts(ctx context.Context) (int, error) {
+	items, err := r.scanAll(ctx, &dynamodb.ScanInput{TableName: aws.String(r.tableName)})
+	if err != nil {
+		return 0, fmt.Errorf("failed to scan products: %w", err)
+	}
+
+	reindexed := 0
+	for _, item := range items {
+		for attempt := 0; ; attempt++ {
+			err := r.reindexProduct(ctx, item)
+			if err == nil {
+				reindexed++
+				break
+			}
+			if !isConditionalCheckFailed(err) || attempt == transactionConflictRetries {
+				return reindexed, fmt.Errorf("failed to reindex product: %w", err)
+			}
+
+			// The product changed since it was read: index it as it is now
+			item, err = r.getItemByID(ctx, r.tableName, aws.StringValue(item["id"].S))
+			if err != nil {
+				return reindexed, fmt.Errorf("failed to get product: %w", err)
+			}
+			if item == nil {
+				break
+			}
+		}
+	}
+
+	return reindexed, nil
+}
+
+// reindexProduct writes the listing attributes of a product item, provided
+// it was not updated since it was read
+func (r *DynamoDBRepository) reindexProduct(ctx context.Context, item map[string]*dynamodb.AttributeValue) error {
+	var product models.Product
+	if err := dynamodbattribute.UnmarshalMap(item, &product); err != nil {
+		return fmt.Errorf("failed to unmarshal product: %w", err)
+	}
+	attributes, err := listingAttributes(&product)
+	if err != nil {
+		return err
+	}
+
+	var set []string
+	names := map[string]*string{"#listing": aws.String(listingAttribute)}
+	values := map[string]*dynamodb.AttributeValue{}
+	for name, value := range attributes {
+		names["#"+name] = aws.String(name)
+		values[":"+name] = value
+		set = append(set, "#"+name+" = :"+name)
+	}
+	expression := "SET " + strings.Join(set, ", ")
+	if !product.IsActive {
+		expression += " REMOVE #listing"
+	}
+
+	condition := "attribute_not_exists(#updated_at)"
+	names["#updated_at"] = aws.String("updated_at")
+	if updatedAt, ok := item["updated_at"]; ok {
+		condition = "#updated_at = :updated_at"
+		values[":updated_at"] = updatedAt
+	}
+
+	_, err = r.client.UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(r.tableName),
+		Key:                       map[string]*dynamodb.AttributeValue{"id": item["id"]},
+		UpdateExpression:          aws.String(expression),
+		ConditionExpression:       aws.String(condition),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	})
+	return err
+}
@@ -0,0 +1,347 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"product-service/internal/models"
+	"shared/apperr"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/uuid"
+)
+
+// DynamoDB condition expressions cannot do arithmetic, so products keep a
+// denormalized "available" attribute (stock - reserved) that every stock and
+// reservation write maintains and guards on. Items written before it existed
+// fall back to stock.
+
+func (r *DynamoDBRepository) ReserveStock(ctx context.Context, reservation *models.StockReservation) error {
+	now := time.Now().UTC()
+	reservation.ID = uuid.New().String()
+	reservation.Status = models.ReservationStatusActive
+	reservation.CreatedAt = now
+	reservation.UpdatedAt = now
+
+	item, err := dynamodbattribute.MarshalMap(reservation)
+	if err != nil {
+		return fmt.Errorf("failed to marshal reservation: %w", err)
+	}
+
+	quantity := strconv.Itoa(reservation.Quantity)
+	err = r.transactWrite(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{
+				Update: &dynamodb.Update{
+					TableName: aws.String(r.tableName),
+					Key: map[string]*dynamodb.AttributeValue{
+						"id": {S: aws.String(reservation.ProductID)},
+					},
+					UpdateExpression:    aws.String("SET #reserved = if_not_exists(#reserved, :zero) + :quantity, #available = if_not_exists(#available, #stock) - :quantity, #updated_at = :updated_at"),
+					ConditionExpression: aws.String("#is_active = :true AND (#available >= :quantity OR (attribute_not_exists(#available) AND #stock >= :quantity))"),
+					ExpressionAttributeNames: map[string]*string{
+						"#reserved":   aws.String("reserved"),
+						"#available":  aws.String("available"),
+						"#stock":      aws.String("stock"),
+						"#is_active":  aws.String("is_active"),
+						"#updated_at": aws.String("updated_at"),
+					},
+					ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+						":quantity":   {N: aws.String(quantity)},
+						":zero":       {N: aws.String("0")},
+						":true":       {BOOL: aws.Bool(true)},
+						":updated_at": {S: aws.String(now.Format(time.RFC3339))},
+					},
+				},
+			},
+			{
+				Put: &dynamodb.Put{
+					TableName: aws.String(r.reservationsTable),
+					Item:      item,
+				},
+			},
+		},
+	})
+	if err != nil {
+		if !isTransactionConditionFailed(err, 0) {
+			return fmt.Errorf("failed to reserve stock: %w", err)
+		}
+
+		product, getErr := r.GetProduct(ctx, reservation.ProductID)
+		if getErr != nil || !product.IsActive {
+			return apperr.NotFound("product not found")
+		}
+		return apperr.InsufficientStock("product %s cannot reserve %d units", reservation.ProductID, reservation.Quantity)
+	}
+
+	return nil
+}
+
+func (r *DynamoDBRepository) GetReservation(ctx context.Context, id string) (*models.StockReservation, error) {
+	item, err := r.getItemByID(ctx, r.reservationsTable, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reservation: %w", err)
+	}
+
+	if item == nil {
+		return nil, apperr.NotFound("reservation not found")
+	}
+
+	var reservation models.StockReservation
+	err = dynamodbattribute.UnmarshalMap(item, &reservation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal reservation: %w", err)
+	}
+
+	return &reservation, nil
+}
+
+func (r *DynamoDBRepository) ConfirmReservation(ctx context.Context, id string) (*models.StockReservation, error) {
+	reservation, err := r.GetReservation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if reservation.Status != models.ReservationStatusActive {
+		return nil, fmt.Errorf("%w: reservation %s is %s", ErrReservationNotActive, id, reservation.Status)
+	}
+
+	now := time.Now().UTC()
+	if !reservation.ExpiresAt.After(now) {
+		if _, err := r.closeReservation(ctx, reservation, models.ReservationStatusExpired); err != nil && !errors.Is(err, ErrReservationNotActive) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: reservation %s expired", ErrReservationNotActive, id)
+	}
+
+	ledgerEntry, err := r.stockMovementPut(&models.StockMovement{
+		ProductID:   reservation.ProductID,
+		Delta:       -reservation.Quantity,
+		Reason:      models.StockMovementSale,
+		ReferenceID: reservation.OrderID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Confirming turns the hold into a real decrement; available is unchanged
+	quantity := strconv.Itoa(reservation.Quantity)
+	err = r.transactWrite(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			r.reservationStatusUpdate(id, models.ReservationStatusConfirmed, now),
+			{
+				Update: &dynamodb.Update{
+					TableName: aws.String(r.tableName),
+					Key: map[string]*dynamodb.AttributeValue{
+						"id": {S: aws.String(reservation.ProductID)},
+					},
+					UpdateExpression: aws.String("SET #stock = #stock - :quantity, #reserved = #reserved - :quantity, #updated_at = :updated_at"),
+					ExpressionAttributeNames: map[string]*string{
+						"#stock":      aws.String("stock"),
+						"#reserved":   aws.String("reserved"),
+						"#updated_at": aws.String("updated_at"),
+					},
+					ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+						":quantity":   {N: aws.String(quantity)},
+						":updated_at": {S: aws.String(now.Format(time.RFC3339))},
+					},
+				},
+			},
+			ledgerEntry,
+		},
+	})
+	if err != nil {
+		if isTransactionConditionFailed(err, 0) {
+			return nil, fmt.Errorf("%w: reservation %s", ErrReservationNotActive, id)
+		}
+		return nil, fmt.Errorf("failed to confirm reservation: %w", err)
+	}
+
+	reservation.Status = models.ReservationStatusConfirmed
+	reservation.UpdatedAt = now
+	return reservation, nil
+}
+
+func (r *DynamoDBRepository) ReleaseReservation(ctx context.Context, id string) (*models.StockReservation, error) {
+	reservation, err := r.GetReservation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if reservation.Status != models.ReservationStatusActive {
+		return nil, fmt.Errorf("%w: reservation %s is %s", ErrReservationNotActive, id, reservation.Status)
+	}
+
+	return r.closeReservation(ctx, reservation, models.ReservationStatusReleased)
+}
+
+func (r *DynamoDBRepository) ExpireReservations(ctx context.Context, before time.Time, productID string) (int, error) {
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String(r.reservationsTable),
+		FilterExpression: aws.String("#status = :active AND #expires_at <= :before"),
+		ExpressionAttributeNames: map[string]*string{
+			"#status":     aws.String("status"),
+			"#expires_at": aws.String("expires_at"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":active": {S: aws.String(models.ReservationStatusActive)},
+			":before": {N: aws.String(strconv.FormatInt(before.Unix(), 10))},
+		},
+	}
+
+	if productID != "" {
+		input.FilterExpression = aws.String("#status = :active AND #expires_at <= :before AND #product_id = :product_id")
+		input.ExpressionAttributeNames["#product_id"] = aws.String("product_id")
+		input.ExpressionAttributeValues[":product_id"] = &dynamodb.AttributeValue{S: aws.String(productID)}
+	}
+
+	items, err := r.scanAll(ctx, input)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find expired reservations: %w", err)
+	}
+
+	var reservations []models.StockReservation
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &reservations)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unmarshal reservations: %w", err)
+	}
+
+	expired := 0
+	for i := range reservations {
+		_, err := r.closeReservation(ctx, &reservations[i], models.ReservationStatusExpired)
+		if errors.Is(err, ErrReservationNotActive) {
+			// Confirmed or released concurrently
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		expired++
+	}
+
+	return expired, nil
+}
+
+// closeReservation returns the held units to the product and marks the
+// reservation with the given final status, provided it is still active
+func (r *DynamoDBRepository) closeReservation(ctx context.Context, reservation *models.StockReservation, status string) (*models.StockReservation, error) {
+	now := time.Now().UTC()
+	quantity := strconv.Itoa(reservation.Quantity)
+
+	err := r.transactWrite(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			r.reservationStatusUpdate(reservation.ID, status, now),
+			{
+				Update: &dynamodb.Update{
+					TableName: aws.String(r.tableName),
+					Key: map[string]*dynamodb.AttributeValue{
+						"id": {S: aws.String(reservation.ProductID)},
+					},
+					UpdateExpression: aws.String("SET #reserved = #reserved - :quantity, #available = #available + :quantity, #updated_at = :updated_at"),
+					ExpressionAttributeNames: map[string]*string{
+						"#reserved":   aws.String("reserved"),
+						"#available":  aws.String("available"),
+						"#updated_at": aws.String("updated_at"),
+					},
+					ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+						":quantity":   {N: aws.String(quantity)},
+						":updated_at": {S: aws.String(now.Format(time.RFC3339))},
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		if isTransactionConditionFailed(err, 0) {
+			return nil, fmt.Errorf("%w: reservation %s", ErrReservationNotActive, reservation.ID)
+		}
+		return nil, fmt.Errorf("failed to release reservation: %w", err)
+	}
+
+	reservation.Status = status
+	reservation.UpdatedAt = now
+	return reservation, nil
+}
+
+// reservationStatusUpdate moves an active reservation to status; the
+// condition makes confirm, release and expiry mutually exclusive
+func (r *DynamoDBRepository) reservationStatusUpdate(id, status string, now time.Time) *dynamodb.TransactWriteItem {
+	return &dynamodb.TransactWriteItem{
+		Update: &dynamodb.Update{
+			TableName: aws.String(r.reservationsTable),
+			Key: map[string]*dynamodb.AttributeValue{
+				"id": {S: aws.String(id)},
+			},
+			UpdateExpression:    aws.String("SET #status = :status, #updated_at = :updated_at"),
+			ConditionExpression: aws.String("#status = :active"),
+			ExpressionAttributeNames: map[string]*string{
+				"#status":     aws.String("status"),
+				"#updated_at": aws.String("updated_at"),
+			},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":status":     {S: aws.String(status)},
+				":active":     {S: aws.String(models.ReservationStatusActive)},
+				":updated_at": {S: aws.String(now.Format(time.RFC3339))},
+			},
+		},
+	}
+}
+
+// isTransactionConditionFailed reports whether a TransactWriteItems call was
+// cancelled because the condition on the item at index failed
+func isTransactionConditionFailed(err error, index int) bool {
+	var canceled *dynamodb.TransactionCanceledException
+	if !errors.As(err, &canceled) || index >= len(canceled.CancellationReasons) {
+		return false
+	}
+
+	reason := canceled.CancellationReasons[index]
+	return reason != nil && aws.StringValue(reason.Code) == "ConditionalCheckFailed"
+}
+
+// transactionConflictRetries bounds how many times a transaction cancelled by
+// concurrent transactions on the same items is retried
+const transactionConflictRetries = 8
+
+// transactWrite runs TransactWriteItems, retrying with jittered backoff while
+// it is cancelled by concurrent transactions on the same items, as happens
+// when many orders hit one product at once. Conditions are evaluated afresh
+// on every attempt.
+func (r *DynamoDBRepository) transactWrite(ctx context.Context, input *dynamodb.TransactWriteItemsInput) error {
+	backoff := 10 * time.Millisecond
+	for attempt := 0; ; attempt++ {
+		_, err := r.client.TransactWriteItemsWithContext(ctx, input)
+		if err == nil || attempt == transactionConflictRetries || !isTransactionConflict(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff/2 + time.Duration(rand.Int63n(int64(backoff)))):
+		}
+		backoff *= 2
+	}
+}
+
+// isTransactionConflict reports whether a TransactWriteItems call was
+// cancelled because another transaction was writing the same items
+func isTransactionConflict(err error) bool {
+	var canceled *dynamodb.TransactionCanceledException
+	if !errors.As(err, &canceled) {
+		return false
+	}
+
+	for _, reason := range canceled.CancellationReasons {
+		if reason != nil && aws.StringValue(reason.Code) == "TransactionConflict" {
+			return true
+		}
+	}
+	return false
+}
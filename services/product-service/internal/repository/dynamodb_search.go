@@ -0,0 +1,382 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"product-service/internal/filter"
+	"product-service/internal/models"
+	"product-service/internal/suggest"
+	"shared/apperr"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func (r *DynamoDBRepository) ListSynonymGroups(ctx context.Context) ([]models.SynonymGroup, error) {
+	items, err := r.scanAll(ctx, &dynamodb.ScanInput{
+		TableName: aws.String(r.synonymsTable),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list synonym groups: %w", err)
+	}
+
+	groups := []models.SynonymGroup{}
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &groups)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal synonym groups: %w", err)
+	}
+
+	// Oldest first, as Postgres lists them
+	sort.Slice(groups, func(i, j int) bool {
+		if !groups[i].CreatedAt.Equal(groups[j].CreatedAt) {
+			return groups[i].CreatedAt.Before(groups[j].CreatedAt)
+		}
+		return groups[i].ID < groups[j].ID
+	})
+
+	return groups, nil
+}
+
+func (r *DynamoDBRepository) CreateSynonymGroup(ctx context.Context, group *models.SynonymGroup) error {
+	group.ID = uuid.New().String()
+	group.CreatedAt = time.Now().UTC()
+	group.UpdatedAt = time.Now().UTC()
+
+	item, err := dynamodbattribute.MarshalMap(group)
+	if err != nil {
+		return fmt.Errorf("failed to marshal synonym group: %w", err)
+	}
+
+	_, err = r.client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(r.synonymsTable),
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create synonym group: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DynamoDBRepository) UpdateSynonymGroup(ctx context.Context, id string, terms []string) (*models.SynonymGroup, error) {
+	value, err := dynamodbattribute.Marshal(pq.StringArray(terms))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal synonym terms: %w", err)
+	}
+
+	update := newUpdateBuilder()
+	update.set("terms", value)
+
+	attributes, err := r.applyUpdate(ctx, r.synonymsTable, id, update)
+	if err != nil {
+		if isConditionalCheckFailed(err) {
+			return nil, apperr.NotFound("synonym group not found")
+		}
+		return nil, fmt.Errorf("failed to update synonym group: %w", err)
+	}
+
+	var group models.SynonymGroup
+	err = dynamodbattribute.UnmarshalMap(attributes, &group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal updated synonym group: %w", err)
+	}
+
+	return &group, nil
+}
+
+func (r *DynamoDBRepository) DeleteSynonymGroup(ctx context.Context, id string) error {
+	_, err := r.client.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(r.synonymsTable),
+		Key: map[string]*dynamodb.AttributeValue{
+			"id": {S: aws.String(id)},
+		},
+		ConditionExpression: aws.String("attribute_exists(#id)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#id": aws.String("id"),
+		},
+	})
+	if err != nil {
+		if isConditionalCheckFailed(err) {
+			return apperr.NotFound("synonym group not found")
+		}
+		return fmt.Errorf("failed to delete synonym group: %w", err)
+	}
+
+	return nil
+}
+
+// SpellingSuggestions maps each word no active product name or brand holds
+// to the most similar word they do hold, comparing trigrams in memory
+func (r *DynamoDBRepository) SpellingSuggestions(ctx context.Context, words []string) (map[string]string, error) {
+	products, err := r.scanProducts(ctx, models.ProductFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	return filter.SpellingSuggestions(products, words), nil
+}
+
+// ListSuggestions computes the suggestions to store: the names of active
+// products and categories and the brands of active products, with the net
+// units sold of each. It reads every product and sale, so it runs in the
+// suggestions job rather than in requests.
+func (r *DynamoDBRepository) ListSuggestions(ctx context.Context) ([]models.Suggestion, error) {
+	productItems, err := r.scanAll(ctx, activeScanInput(r.tableName, false))
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan products: %w", err)
+	}
+
+	var products []models.Product
+	if err := dynamodbattribute.UnmarshalListOfMaps(productItems, &products); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal products: %w", err)
+	}
+
+	categories, err := r.ListCategories(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	movementItems, err := r.scanAll(ctx, &dynamodb.ScanInput{
+		TableName:            aws.String(r.movementsTable),
+		FilterExpression:     aws.String("#reason IN (:sale, :return)"),
+		ProjectionExpression: aws.String("#product_id, #delta"),
+		ExpressionAttributeNames: map[string]*string{
+			"#product_id": aws.String("product_id"),
+			"#delta":      aws.String("delta"),
+			"#reason":     aws.String("reason"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":sale":   {S: aws.String(models.StockMovementSale)},
+			":return": {S: aws.String(models.StockMovementReturn)},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan stock movements: %w", err)
+	}
+
+	var movements []models.StockMovement
+	if err := dynamodbattribute.UnmarshalListOfMaps(movementItems, &movements); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal stock movements: %w", err)
+	}
+
+	sold := make(map[string]int)
+	for _, movement := range movements {
+		sold[movement.ProductID] -= movement.Delta
+	}
+
+	suggestions := make([]models.Suggestion, 0, len(products)+len(categories))
+	brands := make(map[string]int)
+	categorySold := make(map[string]int)
+	for _, product := range products {
+		units := sold[product.ID]
+		suggestions = append(suggestions, models.Suggestion{
+			Kind:       models.SuggestionProduct,
+			Text:       product.Name,
+			ID:         product.ID,
+			Slug:       product.Slug,
+			Popularity: units,
+		})
+		if product.Brand != "" {
+			brands[product.Brand] += units
+		}
+		categorySold[product.CategoryID] += units
+	}
+
+	for brand, units := range brands {
+		suggestions = append(suggestions, models.Suggestion{Kind: models.SuggestionBrand, Text: brand, Popularity: units})
+	}
+
+	for _, category := range categories {
+		suggestions = append(suggestions, models.Suggestion{
+			Kind:       models.SuggestionCategory,
+			Text:       category.Name,
+			ID:         category.ID,
+			Slug:       category.Slug,
+			Popularity: categorySold[category.ID],
+		})
+	}
+
+	return suggestions, nil
+}
+
+// The suggestions table stores each suggestion under every key of its text,
+// keyed by initial (hash), the first character of the key, and sort_key
+// (range), the key followed by what the suggestion is. A prefix is read from
+// the partition of its initial with begins_with.
+type suggestionItem struct {
+	Initial    string `dynamodbav:"initial"`
+	SortKey    string `dynamodbav:"sort_key"`
+	Kind       string `dynamodbav:"kind"`
+	Text       string `dynamodbav:"text"`
+	ID         string `dynamodbav:"id,omitempty"`
+	Slug       string `dynamodbav:"slug,omitempty"`
+	Popularity int    `dynamodbav:"popularity"`
+}
+
+// maxBatchWriteItems is the most writes DynamoDB takes in one BatchWriteItem
+const maxBatchWriteItems = 25
+
+func suggestionInitial(key string) string {
+	_, size := utf8.DecodeRuneInString(key)
+	return key[:size]
+}
+
+// ReplaceSuggestions stores the suggestions under every key of their text,
+// then deletes the stored keys they no longer have. Searches see old and new
+// suggestions together while it runs.
+func (r *DynamoDBRepository) ReplaceSuggestions(ctx context.Context, suggestions []models.Suggestion) error {
+	type itemKey struct{ initial, sortKey string }
+	kept := make(map[itemKey]bool)
+
+	var puts []*dynamodb.WriteRequest
+	for _, suggestion := range suggestions {
+		// Brands have no ID, their text tells them apart
+		ref := suggestion.ID
+		if ref == "" {
+			ref = suggestion.Text
+		}
+		for _, key := range suggest.Keys(suggestion.Text) {
+			item := suggestionItem{
+				Initial:    suggestionInitial(key),
+				SortKey:    key + "\x00" + suggestion.Kind + "\x00" + ref,
+				Kind:       suggestion.Kind,
+				Text:       suggestion.Text,
+				ID:         suggestion.ID,
+				Slug:       suggestion.Slug,
+				Popularity: suggestion.Popularity,
+			}
+			if kept[itemKey{item.Initial, item.SortKey}] {
+				continue
+			}
+			kept[itemKey{item.Initial, item.SortKey}] = true
+
+			attributes, err := dynamodbattribute.MarshalMap(item)
+			if err != nil {
+				return fmt.Errorf("failed to marshal suggestion: %w", err)
+			}
+			puts = append(puts, &dynamodb.WriteRequest{PutRequest: &dynamodb.PutRequest{Item: attributes}})
+		}
+	}
+
+	stored, err := r.scanAll(ctx, &dynamodb.ScanInput{
+		TableName:            aws.String(r.suggestionsTable),
+		ProjectionExpression: aws.String("#initial, #sort_key"),
+		ExpressionAttributeNames: map[string]*string{
+			"#initial":  aws.String("initial"),
+			"#sort_key": aws.String("sort_key"),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan suggestions: %w", err)
+	}
+
+	var deletes []*dynamodb.WriteRequest
+	for _, item := range stored {
+		if !kept[itemKey{aws.StringValue(item["initial"].S), aws.StringValue(item["sort_key"].S)}] {
+			deletes = append(deletes, &dynamodb.WriteRequest{DeleteRequest: &dynamodb.DeleteRequest{Key: item}})
+		}
+	}
+
+	if err := r.batchWrite(ctx, r.suggestionsTable, puts); err != nil {
+		return fmt.Errorf("failed to store suggestions: %w", err)
+	}
+	if err := r.batchWrite(ctx, r.suggestionsTable, deletes); err != nil {
+		return fmt.Errorf("failed to delete stale suggestions: %w", err)
+	}
+	return nil
+}
+
+// FindSuggestions returns up to limit stored suggestions with a key starting
+// with a folded prefix, ranked by suggest.Rank
+func (r *DynamoDBRepository) FindSuggestions(ctx context.Context, prefix string, limit int) ([]models.Suggestion, error) {
+	if prefix == "" {
+		return []models.Suggestion{}, nil
+	}
+
+	items, err := r.queryAll(ctx, &dynamodb.QueryInput{
+		TableName:              aws.String(r.suggestionsTable),
+		KeyConditionExpression: aws.String("#initial = :initial AND begins_with(#sort_key, :prefix)"),
+		ExpressionAttributeNames: map[string]*string{
+			"#initial":  aws.String("initial"),
+			"#sort_key": aws.String("sort_key"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":initial": {S: aws.String(suggestionInitial(prefix))},
+			":prefix":  {S: aws.String(prefix)},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find suggestions: %w", err)
+	}
+
+	var found []suggestionItem
+	if err := dynamodbattribute.UnmarshalListOfMaps(items, &found); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal suggestions: %w", err)
+	}
+
+	suggestions := make([]models.Suggestion, len(found))
+	for i, item := range found {
+		suggestions[i] = models.Suggestion{
+			Kind:       item.Kind,
+			Text:       item.Text,
+			ID:         item.ID,
+			Slug:       item.Slug,
+			Popularity: item.Popularity,
+		}
+	}
+	return suggest.Rank(suggestions, limit), nil
+}
+
+// queryAll follows LastEvaluatedKey until every matching item has been read
+func (r *DynamoDBRepository) queryAll(ctx context.Context, input *dynamodb.QueryInput) ([]map[string]*dynamodb.AttributeValue, error) {
+	var items []map[string]*dynamodb.AttributeValue
+	err := r.client.QueryPagesWithContext(ctx, input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		items = append(items, page.Items...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// batchWrite writes requests to table in batches, resending the writes
+// DynamoDB leaves unprocessed
+func (r *DynamoDBRepository) batchWrite(ctx context.Context, table string, requests []*dynamodb.WriteRequest) error {
+	for start := 0; start < len(requests); start += maxBatchWriteItems {
+		end := start + maxBatchWriteItems
+		if end > len(requests) {
+			end = len(requests)
+		}
+
+		pending := map[string][]*dynamodb.WriteRequest{table: requests[start:end]}
+		for attempt := 0; len(pending[table]) > 0; attempt++ {
+			if attempt > transactionConflictRetries {
+				return fmt.Errorf("%d writes still unprocessed after %d attempts", len(pending[table]), attempt)
+			}
+			if attempt > 0 {
+				// Unprocessed writes were throttled: back off before resending
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(time.Duration(attempt*attempt) * 50 * time.Millisecond):
+				}
+			}
+
+			output, err := r.client.BatchWriteItemWithContext(ctx, &dynamodb.BatchWriteItemInput{RequestItems: pending})
+			if err != nil {
+				return err
+			}
+			pending = output.UnprocessedItems
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"product-service/internal/models"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/google/uuid"
+)
+
+// The stock movements table is keyed by product_id (hash) and sort_key
+// (range). sort_key is a fixed-width timestamp followed by the movement ID so
+// a product's history sorts chronologically.
+const movementSortKeyLayout = "20060102T150405.000000000Z"
+
+func (r *DynamoDBRepository) ListStockMovements(ctx context.Context, productID string, limit, offset int) (*models.StockMovementListResponse, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(r.movementsTable),
+		KeyConditionExpression: aws.String("#product_id = :product_id"),
+		ExpressionAttributeNames: map[string]*string{
+			"#product_id": aws.String("product_id"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":product_id": {S: aws.String(productID)},
+		},
+		ScanIndexForward: aws.Bool(false),
+	}
+
+	totalCount, err := r.countQuery(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count stock movements: %w", err)
+	}
+
+	// Only the pages up to the end of the requested one are read
+	var items []map[string]*dynamodb.AttributeValue
+	err = r.client.QueryPagesWithContext(ctx, input, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		items = append(items, page.Items...)
+		return limit <= 0 || len(items) < offset+limit
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stock movements: %w", err)
+	}
+
+	if offset < len(items) {
+		items = items[offset:]
+	} else {
+		items = nil
+	}
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+
+	movements := []models.StockMovement{}
+	err = dynamodbattribute.UnmarshalListOfMaps(items, &movements)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal stock movements: %w", err)
+	}
+
+	return &models.StockMovementListResponse{
+		Movements:  movements,
+		TotalCount: totalCount,
+		Limit:      limit,
+		Offset:     offset,
+		HasMore:    offset+len(movements) < totalCount,
+	}, nil
+}
+
+// countQuery counts the items a query matches with a paginated Select COUNT,
+// which reads no attributes
+func (r *DynamoDBRepository) countQuery(ctx context.Context, input *dynamodb.QueryInput) (int, error) {
+	count := *input
+	count.Select = aws.String(dynamodb.SelectCount)
+
+	total := 0
+	err := r.client.QueryPagesWithContext(ctx, &count, func(page *dynamodb.QueryOutput, lastPage bool) bool {
+		total += int(aws.Int64Value(page.Count))
+		return true
+	})
+	return total, err
+}
+
+func (r *DynamoDBRepository) ReconcileStock(ctx context.Context) ([]models.StockDrift, error) {
+	productItems, err := r.scanAll(ctx, &dynamodb.ScanInput{
+		TableName:            aws.String(r.tableName),
+		ProjectionExpression: aws.String("#id, #sku, #stock"),
+		ExpressionAttributeNames: map[string]*string{
+			"#id":    aws.String("id"),
+			"#sku":   aws.String("sku"),
+			"#stock": aws.String("stock"),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan products: %w", err)
+	}
+
+	movementItems, err := r.scanAll(ctx, &dynamodb.ScanInput{
+		TableName:            aws.String(r.movementsTable),
+		ProjectionExpression: aws.String("#product_id, #delta"),
+		ExpressionAttributeNames: map[string]*string{
+			"#product_id": aws.String("product_id"),
+			"#delta":      aws.String("delta"),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan stock movements: %w", err)
+	}
+
+	var products []models.Product
+	if err := dynamodbattribute.UnmarshalListOfMaps(productItems, &products); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal products: %w", err)
+	}
+
+	var movements []models.StockMovement
+	if err := dynamodbattribute.UnmarshalListOfMaps(movementItems, &movements); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal stock movements: %w", err)
+	}
+
+	ledger := make(map[string]int)
+	for _, movement := range movements {
+		ledger[movement.ProductID] += movement.Delta
+	}
+
+	var drifts []models.StockDrift
+	for _, product := range products {
+		if product.Stock != ledger[product.ID] {
+			drifts = append(drifts, models.StockDrift{
+				ProductID:   product.ID,
+				SKU:         product.SKU,
+				Stock:       product.Stock,
+				LedgerStock: ledger[product.ID],
+				Drift:       product.Stock - ledger[product.ID],
+			})
+		}
+	}
+
+	return drifts, nil
+}
+
+// stockMovementPut prepares a ledger entry to be written in the same
+// transaction as the stock change it records
+func (r *DynamoDBRepository) stockMovementPut(movement *models.StockMovement) (*dynamodb.TransactWriteItem, error) {
+	movement.ID = uuid.New().String()
+	movement.CreatedAt = time.Now().UTC()
+	movement.SortKey = movement.CreatedAt.Format(movementSortKeyLayout) + "#" + movement.ID
+
+	item, err := dynamodbattribute.MarshalMap(movement)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal stock movement: %w", err)
+	}
+
+	return &dynamodb.TransactWriteItem{
+		Put: &dynamodb.Put{
+			TableName: aws.String(r.movementsTable),
+			Item:      item,
+		},
+	}, nil
+}
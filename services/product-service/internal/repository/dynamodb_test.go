@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/google/uuid"
+)
+
+// newTestDynamoDBRepository creates the products table, with its listing
+// indexes, and the stock movements table of a fresh repository on the
+// DynamoDB endpoint TEST_DYNAMODB_ENDPOINT names, e.g. DynamoDB Local, and
+// skips the test when it is not set
+func newTestDynamoDBRepository(t *testing.T) *DynamoDBRepository {
+	t.Helper()
+	endpoint := os.Getenv("TEST_DYNAMODB_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("TEST_DYNAMODB_ENDPOINT is not set")
+	}
+
+	repo := NewDynamoDBRepository("products-test-" + uuid.New().String()[:8])
+	repo.client = dynamodb.New(session.Must(session.NewSession(
+		aws.NewConfig().WithEndpoint(endpoint).WithRegion("us-east-1"),
+	)))
+
+	products := testTableInput(repo.tableName, "id", "")
+	attributes, indexes := listingIndexes()
+	products.AttributeDefinitions = append(products.AttributeDefinitions, attributes...)
+	products.GlobalSecondaryIndexes = indexes
+	createTestTable(t, repo.client, products)
+	createTestTable(t, repo.client, testTableInput(repo.movementsTable, "product_id", "sort_key"))
+	return repo
+}
+
+// testTableInput defines a table keyed by string attributes
+func testTableInput(table, hashKey, rangeKey string) *dynamodb.CreateTableInput {
+	input := &dynamodb.CreateTableInput{
+		TableName:   aws.String(table),
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String(hashKey), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String(hashKey), KeyType: aws.String(dynamodb.KeyTypeHash)},
+		},
+	}
+	if rangeKey != "" {
+		input.AttributeDefinitions = append(input.AttributeDefinitions,
+			&dynamodb.AttributeDefinition{AttributeName: aws.String(rangeKey), AttributeType: aws.String(dynamodb.ScalarAttributeTypeS)})
+		input.KeySchema = append(input.KeySchema,
+			&dynamodb.KeySchemaElement{AttributeName: aws.String(rangeKey), KeyType: aws.String(dynamodb.KeyTypeRange)})
+	}
+	return input
+}
+
+// createTestTable creates a table and drops it when the test ends
+func createTestTable(t *testing.T, client *dynamodb.DynamoDB, input *dynamodb.CreateTableInput) {
+	t.Helper()
+	table := aws.StringValue(input.TableName)
+
+	if _, err := client.CreateTable(input); err != nil {
+		t.Fatalf("failed to create table %s: %v", table, err)
+	}
+	if err := client.WaitUntilTableExists(&dynamodb.DescribeTableInput{TableName: aws.String(table)}); err != nil {
+		t.Fatalf("failed to wait for table %s: %v", table, err)
+	}
+
+	t.Cleanup(func() {
+		client.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String(table)})
+	})
+}
+
+func TestDynamoDBUpdateStockConcurrentSales(t *testing.T) {
+	repo := newTestDynamoDBRepository(t)
+
+	product := newConcurrencyProduct(uuid.New().String(), uuid.New().String())
+	if err := repo.CreateProduct(context.Background(), product); err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+
+	assertConcurrentSales(t, repo, product.ID)
+}
+
+func TestDynamoDBSetStockDuringSales(t *testing.T) {
+	repo := newTestDynamoDBRepository(t)
+
+	product := newConcurrencyProduct(uuid.New().String(), uuid.New().String())
+	if err := repo.CreateProduct(context.Background(), product); err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+
+	assertSetStockDuringSales(t, repo, product.ID)
+}
+
+func TestDynamoDBReturnStockOnce(t *testing.T) {
+	repo := newTestDynamoDBRepository(t)
+	createTestTable(t, repo.client, testTableInput(repo.slugsTable, "id", ""))
+
+	product := newConcurrencyProduct(uuid.New().String(), uuid.New().String())
+	if err := repo.CreateProduct(context.Background(), product); err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+
+	assertReturnStockOnce(t, repo, product.ID)
+}
+
+func TestDynamoDBListingPages(t *testing.T) {
+	repo := newTestDynamoDBRepository(t)
+	assertListingPages(t, repo, uuid.New().String(), uuid.New().String())
+}
+
+func TestDynamoDBSuggestions(t *testing.T) {
+	repo := newTestDynamoDBRepository(t)
+	createTestTable(t, repo.client, testTableInput(repo.suggestionsTable, "initial", "sort_key"))
+	assertSuggestions(t, repo)
+}
+
+func TestDynamoDBConcurrentCategoryMoves(t *testing.T) {
+	repo := newTestDynamoDBRepository(t)
+	createTestTable(t, repo.client, testTableInput(repo.categoriesTable, "id", ""))
+	createTestTable(t, repo.client, testTableInput(repo.slugsTable, "id", ""))
+	assertConcurrentMoves(t, repo)
+}
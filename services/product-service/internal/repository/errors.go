@@ -0,0 +1,7 @@
+package repository
+
+import "shared/apperr"
+
+// ErrReservationNotActive is returned when confirming or releasing a
+// reservation that has already been confirmed, released or expired.
+var ErrReservationNotActive = apperr.Conflict("reservation is not active")
@@ -0,0 +1,31 @@
+package repository
+
+import "product-service/internal/models"
+
+// pageProducts fills a listing from the products read for it: in page order,
+// or from the end for a backward page, with one more than the limit when
+// there are more. The extra product is dropped once counted.
+func pageProducts(response *models.ProductListResponse, products []models.Product, filter models.ProductFilter) {
+	hasExtra := filter.Limit > 0 && len(products) > filter.Limit
+	if hasExtra {
+		products = products[:filter.Limit]
+	}
+
+	seek := filter.Seek
+	if seek != nil && seek.Backward {
+		for i, j := 0, len(products)-1; i < j; i, j = i+1, j-1 {
+			products[i], products[j] = products[j], products[i]
+		}
+		// The cursor product itself follows the page
+		response.HasMore = true
+		response.HasPrevious = hasExtra
+	} else {
+		response.HasMore = hasExtra
+		response.HasPrevious = seek != nil || filter.Offset > 0
+	}
+
+	if products == nil {
+		products = []models.Product{}
+	}
+	response.Products = products
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"product-service/internal/models"
+)
+
+// productsNamed returns products with the given IDs, in order
+func productsNamed(ids ...string) []models.Product {
+	products := make([]models.Product, len(ids))
+	for i, id := range ids {
+		products[i].ID = id
+	}
+	return products
+}
+
+func TestPageProducts(t *testing.T) {
+	seek := &models.ProductSeek{}
+	backward := &models.ProductSeek{Backward: true}
+
+	cases := []struct {
+		name        string
+		read        []models.Product
+		filter      models.ProductFilter
+		want        string
+		hasMore     bool
+		hasPrevious bool
+	}{
+		{"first page with more", productsNamed("a", "b", "c"), models.ProductFilter{Limit: 2}, "a,b", true, false},
+		{"only page", productsNamed("a", "b"), models.ProductFilter{Limit: 2}, "a,b", false, false},
+		{"empty", nil, models.ProductFilter{Limit: 2}, "", false, false},
+		{"offset page", productsNamed("c"), models.ProductFilter{Limit: 2, Offset: 2}, "c", false, true},
+		{"next page with more", productsNamed("c", "d", "e"), models.ProductFilter{Limit: 2, Seek: seek}, "c,d", true, true},
+		{"last page", productsNamed("e"), models.ProductFilter{Limit: 2, Seek: seek}, "e", false, true},
+		{"previous page with more", productsNamed("d", "c", "b"), models.ProductFilter{Limit: 2, Seek: backward}, "c,d", true, true},
+		{"first page read backward", productsNamed("b", "a"), models.ProductFilter{Limit: 2, Seek: backward}, "a,b", true, false},
+	}
+
+	for _, c := range cases {
+		response := &models.ProductListResponse{}
+		pageProducts(response, c.read, c.filter)
+
+		if response.Products == nil {
+			t.Errorf("%s: products are nil, want an empty list", c.name)
+		}
+		if got := strings.Join(productIDs(response.Products), ","); got != c.want {
+			t.Errorf("%s: page holds %s, want %s", c.name, got, c.want)
+		}
+		if response.HasMore != c.hasMore || response.HasPrevious != c.hasPrevious {
+			t.Errorf("%s: has_more %v and previous %v, want %v and %v",
+				c.name, response.HasMore, response.HasPrevious, c.hasMore, c.hasPrevious)
+		}
+	}
+}
@@ -1,19 +1,26 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"strings"
 
+	"product-service/internal/filter"
 	"product-service/internal/models"
+	"product-service/schema"
+	"shared/apperr"
 	"shared/db"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PostgresRepository struct {
 	*db.BaseRepository
+	departments   *db.Repository[models.Department]
+	categories    *db.Repository[models.Category]
+	synonymGroups *db.Repository[models.SynonymGroup]
 }
 
 func NewPostgresRepository() (*PostgresRepository, error) {
@@ -22,23 +29,26 @@ func NewPostgresRepository() (*PostgresRepository, error) {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	// Auto-migrate the schema
-	err = db.AutoMigrate(database, &models.Product{}, &models.Category{})
+	// Migrate or verify the schema, as DB_SCHEMA_MODE says
+	err = db.PrepareSchema(database, schema.Models()...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to migrate database: %w", err)
+		return nil, fmt.Errorf("failed to prepare database schema: %w", err)
 	}
 
 	return &PostgresRepository{
 		BaseRepository: db.NewBaseRepository(database),
+		departments:    db.NewRepository[models.Department](database),
+		categories:     db.NewRepository[models.Category](database),
+		synonymGroups:  db.NewRepository[models.SynonymGroup](database),
 	}, nil
 }
 
-func (r *PostgresRepository) GetProduct(id string) (*models.Product, error) {
+func (r *PostgresRepository) GetProduct(ctx context.Context, id string) (*models.Product, error) {
 	var product models.Product
-	result := r.DB.Where("id = ? AND is_active = ?", id, true).First(&product)
+	result := r.DB.WithContext(ctx).Where("id = ? AND is_active = ?", id, true).First(&product)
 	
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		return nil, errors.New("product not found")
+		return nil, apperr.NotFound("product not found")
 	}
 	
 	if result.Error != nil {
@@ -48,59 +58,50 @@ func (r *PostgresRepository) GetProduct(id string) (*models.Product, error) {
 	return &product, nil
 }
 
-func (r *PostgresRepository) ListProducts(filter models.ProductFilter) (*models.ProductListResponse, error) {
-	query := r.DB.Model(&models.Product{}).Where("is_active = ?", true)
-
-	// Apply filters
-	if filter.CategoryID != "" {
-		query = query.Where("category_id = ?", filter.CategoryID)
+func (r *PostgresRepository) ListProducts(ctx context.Context, productFilter models.ProductFilter) (*models.ProductListResponse, error) {
+	condition, err := filter.GormExpression(filter.FromProductFilter(productFilter))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build product filter: %w", err)
 	}
 
-	if filter.DepartmentID != "" {
-		query = query.Where("department_id = ?", filter.DepartmentID)
-	}
+	query := r.DB.WithContext(ctx).Model(&models.Product{}).Where(condition)
 
-	if filter.Brand != "" {
-		query = query.Where("brand = ?", filter.Brand)
+	// Count total records
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count products: %w", err)
 	}
 
-	if filter.MinPrice != nil {
-		query = query.Where("price >= ?", *filter.MinPrice)
+	// Search results carry their rank and a highlighted snippet
+	if productFilter.Search != "" {
+		columns, vars := filter.GormSearchColumns(filter.SearchOf(productFilter))
+		query = query.Select(columns, vars...)
 	}
 
-	if filter.MaxPrice != nil {
-		query = query.Where("price <= ?", *filter.MaxPrice)
-	}
+	// A backward page is read in reverse from its cursor, then flipped
+	seek := productFilter.Seek
+	backward := seek != nil && seek.Backward
 
-	if filter.InStock != nil && *filter.InStock {
-		query = query.Where("stock > ?", 0)
-	}
-
-	if filter.IsOnSale != nil && *filter.IsOnSale {
-		query = query.Where("is_on_sale = ?", true)
+	order, err := filter.GormOrder(productFilter.Sort, filter.SearchOf(productFilter), backward)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build product order: %w", err)
 	}
+	query = query.Order(order)
 
-	if filter.MinRating != nil {
-		query = query.Where("rating >= ?", *filter.MinRating)
+	// Apply pagination, seeking past the cursor instead of an offset
+	if seek != nil {
+		after, err := filter.GormSeek(productFilter.Sort, filter.SearchOf(productFilter), seek.Values, !backward)
+		if err != nil {
+			return nil, fmt.Errorf("failed to seek products: %w", err)
+		}
+		query = query.Where(after)
+	} else if productFilter.Offset > 0 {
+		query = query.Offset(productFilter.Offset)
 	}
 
-	if filter.Search != "" {
-		searchPattern := "%" + strings.ToLower(filter.Search) + "%"
-		query = query.Where("LOWER(name) LIKE ? OR LOWER(description) LIKE ? OR LOWER(sku) LIKE ? OR LOWER(brand) LIKE ? OR LOWER(slug) LIKE ?",
-			searchPattern, searchPattern, searchPattern, searchPattern, searchPattern)
-	}
-
-	// Count total records
-	var totalCount int64
-	query.Count(&totalCount)
-
-	// Apply pagination
-	if filter.Offset > 0 {
-		query = query.Offset(filter.Offset)
-	}
-
-	if filter.Limit > 0 {
-		query = query.Limit(filter.Limit)
+	// One product past the page tells whether there are more
+	if productFilter.Limit > 0 {
+		query = query.Limit(productFilter.Limit + 1)
 	}
 
 	var products []models.Product
@@ -109,38 +110,66 @@ func (r *PostgresRepository) ListProducts(filter models.ProductFilter) (*models.
 		return nil, fmt.Errorf("failed to list products: %w", result.Error)
 	}
 
-	return &models.ProductListResponse{
-		Products:   products,
+	response := &models.ProductListResponse{
 		TotalCount: int(totalCount),
-		Limit:      filter.Limit,
-		Offset:     filter.Offset,
-	}, nil
+		Limit:      productFilter.Limit,
+		Offset:     productFilter.Offset,
+	}
+	pageProducts(response, products, productFilter)
+
+	return response, nil
 }
 
-func (r *PostgresRepository) CreateProduct(product *models.Product) error {
-	product.ID = uuid.New().String()
-	product.IsActive = true
+// ListTagFacets counts the products matching the filter by tag, most used
+// first
+func (r *PostgresRepository) ListTagFacets(ctx context.Context, productFilter models.ProductFilter) ([]models.TagFacet, error) {
+	condition, err := filter.GormExpression(filter.FromProductFilter(productFilter))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build product filter: %w", err)
+	}
 
-	result := r.DB.Create(product)
+	var facets []models.TagFacet
+	result := r.DB.WithContext(ctx).Model(&models.Product{}).
+		Select("tag, COUNT(*) AS count").
+		Joins("CROSS JOIN LATERAL unnest(products.tags) AS tag").
+		Where(condition).
+		Group("tag").
+		Order("count DESC, tag").
+		Scan(&facets)
 	if result.Error != nil {
-		return fmt.Errorf("failed to create product: %w", result.Error)
+		return nil, fmt.Errorf("failed to list tag facets: %w", result.Error)
 	}
 
-	return nil
+	return facets, nil
 }
 
-func (r *PostgresRepository) UpdateProduct(id string, updates *models.UpdateProductRequest) (*models.Product, error) {
-	var product models.Product
-	
-	// First get the existing product
-	result := r.DB.Where("id = ?", id).First(&product)
-	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		return nil, errors.New("product not found")
-	}
-	if result.Error != nil {
-		return nil, fmt.Errorf("failed to find product: %w", result.Error)
-	}
+func (r *PostgresRepository) CreateProduct(ctx context.Context, product *models.Product) error {
+	product.ID = uuid.New().String()
+	product.IsActive = true
 
+	return r.Transaction(ctx, func(tx *gorm.DB) error {
+		result := tx.Create(product)
+		if result.Error != nil {
+			return fmt.Errorf("failed to create product: %w", result.Error)
+		}
+
+		// Opening stock is the first ledger entry so the ledger always sums
+		// to the product's stock
+		if product.Stock != 0 {
+			return recordStockMovement(tx, &models.StockMovement{
+				ProductID: product.ID,
+				Delta:     product.Stock,
+				Reason:    models.StockMovementRestock,
+			})
+		}
+
+		return nil
+	})
+}
+
+// UpdateProduct updates a product's fields and, when updates set its stock,
+// records the difference as an adjustment by actor, all in one transaction
+func (r *PostgresRepository) UpdateProduct(ctx context.Context, id string, updates *models.UpdateProductRequest, actor string) (*models.Product, error) {
 	// Apply updates
 	updateFields := make(map[string]interface{})
 	
@@ -171,9 +200,6 @@ func (r *PostgresRepository) UpdateProduct(id string, updates *models.UpdateProd
 	if updates.Unit != nil {
 		updateFields["unit"] = *updates.Unit
 	}
-	if updates.Stock != nil {
-		updateFields["stock"] = *updates.Stock
-	}
 	if updates.Weight != nil {
 		updateFields["weight"] = *updates.Weight
 	}
@@ -196,57 +222,164 @@ func (r *PostgresRepository) UpdateProduct(id string, updates *models.UpdateProd
 		updateFields["is_active"] = *updates.IsActive
 	}
 
-	if len(updateFields) == 0 {
-		return nil, errors.New("no fields to update")
-	}
-
-	result = r.DB.Model(&product).Updates(updateFields)
-	if result.Error != nil {
-		return nil, fmt.Errorf("failed to update product: %w", result.Error)
+	if len(updateFields) == 0 && updates.Stock == nil {
+		return nil, apperr.Validation("no fields to update")
 	}
 
-	// Return updated product
-	result = r.DB.Where("id = ?", id).First(&product)
-	if result.Error != nil {
-		return nil, fmt.Errorf("failed to get updated product: %w", result.Error)
+	var product models.Product
+	err := r.Transaction(ctx, func(tx *gorm.DB) error {
+		// The product row stays locked until commit, so stock sold or
+		// reserved meanwhile is never overwritten by the stock set here
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&product)
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return apperr.NotFound("product not found")
+		}
+		if result.Error != nil {
+			return fmt.Errorf("failed to find product: %w", result.Error)
+		}
+
+		if updates.Stock != nil {
+			err := setStock(tx, &product, &models.StockMovement{
+				ProductID: id,
+				Reason:    models.StockMovementAdjustment,
+				Actor:     actor,
+			}, *updates.Stock)
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(updateFields) > 0 {
+			result = tx.Model(&product).Updates(updateFields)
+			if result.Error != nil {
+				return fmt.Errorf("failed to update product: %w", result.Error)
+			}
+		}
+
+		// Return updated product
+		product = models.Product{}
+		result = tx.Where("id = ?", id).First(&product)
+		if result.Error != nil {
+			return fmt.Errorf("failed to get updated product: %w", result.Error)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return &product, nil
 }
 
-func (r *PostgresRepository) DeleteProduct(id string) error {
-	result := r.DB.Where("id = ?", id).Delete(&models.Product{})
+func (r *PostgresRepository) DeleteProduct(ctx context.Context, id string) error {
+	result := r.DB.WithContext(ctx).Where("id = ?", id).Delete(&models.Product{})
 	if result.Error != nil {
 		return fmt.Errorf("failed to delete product: %w", result.Error)
 	}
 
 	if result.RowsAffected == 0 {
-		return errors.New("product not found")
+		return apperr.NotFound("product not found")
 	}
 
 	return nil
 }
 
-func (r *PostgresRepository) UpdateStock(id string, quantity int) error {
-	result := r.DB.Model(&models.Product{}).
-		Where("id = ?", id).
-		Update("stock", gorm.Expr("stock + ?", quantity))
+func (r *PostgresRepository) UpdateStock(ctx context.Context, movement *models.StockMovement) error {
+	return r.Transaction(ctx, func(tx *gorm.DB) error {
+		// The guard in the WHERE clause makes the check and the write a single
+		// atomic statement, so concurrent decrements cannot overdraw the stock
+		// or consume units held by active reservations
+		result := tx.Model(&models.Product{}).
+			Where("id = ? AND stock - reserved + ? >= 0", movement.ProductID, movement.Delta).
+			Update("stock", gorm.Expr("stock + ?", movement.Delta))
+
+		if result.Error != nil {
+			return fmt.Errorf("failed to update stock: %w", result.Error)
+		}
+
+		if result.RowsAffected == 0 {
+			var count int64
+			if err := tx.Model(&models.Product{}).Where("id = ?", movement.ProductID).Count(&count).Error; err != nil {
+				return fmt.Errorf("failed to update stock: %w", err)
+			}
+			if count == 0 {
+				return apperr.NotFound("product not found")
+			}
+			return apperr.InsufficientStock("product %s cannot be adjusted by %d", movement.ProductID, movement.Delta)
+		}
+
+		return recordStockMovement(tx, movement)
+	})
+}
 
-	if result.Error != nil {
-		return fmt.Errorf("failed to update stock: %w", result.Error)
+// errStockReturned rolls back a return already recorded for its order
+var errStockReturned = errors.New("stock already returned")
+
+// ReturnStock puts the units of an order back into a product's stock once.
+// The return is recorded with the stock, so one already made for the order
+// and product puts nothing back again and order-service can retry a return
+// that failed partway.
+func (r *PostgresRepository) ReturnStock(ctx context.Context, movement *models.StockMovement) error {
+	err := r.Transaction(ctx, func(tx *gorm.DB) error {
+		// Updating the product first locks its row, so concurrent returns of
+		// the same order wait here and then find the return recorded
+		result := tx.Model(&models.Product{}).
+			Where("id = ?", movement.ProductID).
+			Update("stock", gorm.Expr("stock + ?", movement.Delta))
+		if result.Error != nil {
+			return fmt.Errorf("failed to return stock: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return apperr.NotFound("product not found")
+		}
+
+		if err := recordStockMovement(tx, movement); err != nil {
+			return err
+		}
+
+		result = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.StockReturn{
+			OrderID:    movement.ReferenceID,
+			ProductID:  movement.ProductID,
+			MovementID: movement.ID,
+		})
+		if result.Error != nil {
+			return fmt.Errorf("failed to record stock return: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return errStockReturned
+		}
+		return nil
+	})
+	if errors.Is(err, errStockReturned) {
+		return nil
+	}
+	return err
+}
+
+// setStock sets the stock of product, locked by the caller's transaction, to
+// stock and records the difference as movement
+func setStock(tx *gorm.DB, product *models.Product, movement *models.StockMovement, stock int) error {
+	if stock < product.Reserved {
+		return apperr.InsufficientStock("product %s cannot be set to %d units, %d are reserved", product.ID, stock, product.Reserved)
 	}
 
-	if result.RowsAffected == 0 {
-		return errors.New("product not found")
+	movement.Delta = stock - product.Stock
+	if movement.Delta == 0 {
+		return nil
 	}
 
-	return nil
+	result := tx.Model(&models.Product{}).Where("id = ?", product.ID).Update("stock", stock)
+	if result.Error != nil {
+		return fmt.Errorf("failed to set stock: %w", result.Error)
+	}
+
+	return recordStockMovement(tx, movement)
 }
 
-func (r *PostgresRepository) GetLowStockProducts() ([]models.Product, error) {
+func (r *PostgresRepository) GetLowStockProducts(ctx context.Context) ([]models.Product, error) {
 	var products []models.Product
 	
-	result := r.DB.Where("stock <= min_stock AND is_active = ?", true).Find(&products)
+	result := r.DB.WithContext(ctx).Where("stock <= min_stock AND is_active = ?", true).Find(&products)
 	if result.Error != nil {
 		return nil, fmt.Errorf("failed to get low stock products: %w", result.Error)
 	}
@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"product-service/internal/models"
+	"shared/apperr"
+	"shared/db"
+
+	"github.com/google/uuid"
+)
+
+func (r *PostgresRepository) ListDepartments(ctx context.Context, includeInactive bool) ([]models.Department, error) {
+	departments := r.departments
+	if includeInactive {
+		departments = departments.Unscoped()
+	}
+
+	return departments.List(ctx, db.NewSpec().OrderBy(db.Asc("name")))
+}
+
+func (r *PostgresRepository) GetDepartment(ctx context.Context, id string) (*models.Department, error) {
+	return r.departments.Get(ctx, id)
+}
+
+func (r *PostgresRepository) GetDepartmentBySlug(ctx context.Context, slug string) (*models.Department, error) {
+	return r.departments.First(ctx, db.NewSpec().Where(db.Eq("slug", slug)))
+}
+
+func (r *PostgresRepository) CreateDepartment(ctx context.Context, department *models.Department) error {
+	exists, err := r.departments.Unscoped().Exists(ctx, db.NewSpec().Where(db.Eq("slug", department.Slug)))
+	if err != nil {
+		return err
+	}
+	if exists {
+		return apperr.Conflict("department slug already exists")
+	}
+
+	department.ID = uuid.New().String()
+	department.IsActive = true
+
+	// A concurrent create can claim the slug between the check and the
+	// insert, which the unique index then rejects
+	if err := r.departments.Create(ctx, department); err != nil {
+		if db.IsUniqueViolation(err) {
+			return apperr.Conflict("department slug already exists")
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) UpdateDepartment(ctx context.Context, id string, updates *models.UpdateDepartmentRequest) (*models.Department, error) {
+	// Inactive departments can be updated, to reactivate them
+	departments := r.departments.Unscoped()
+
+	// First get the existing department
+	if _, err := departments.Get(ctx, id); err != nil {
+		return nil, err
+	}
+
+	// Apply updates
+	updateFields := make(map[string]interface{})
+
+	if updates.Name != nil {
+		updateFields["name"] = *updates.Name
+	}
+	if updates.Description != nil {
+		updateFields["description"] = *updates.Description
+	}
+	if updates.Icon != nil {
+		updateFields["icon"] = *updates.Icon
+	}
+	if updates.Image != nil {
+		updateFields["image"] = *updates.Image
+	}
+	if updates.Slug != nil {
+		exists, err := departments.Exists(ctx, db.NewSpec().Where(db.Eq("slug", *updates.Slug), db.Ne("id", id)))
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, apperr.Conflict("department slug already exists")
+		}
+		updateFields["slug"] = *updates.Slug
+	}
+	if updates.IsActive != nil {
+		updateFields["is_active"] = *updates.IsActive
+	}
+
+	if err := departments.Update(ctx, id, updateFields); err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, apperr.Conflict("department slug already exists")
+		}
+		return nil, err
+	}
+
+	// Return updated department
+	return departments.Get(ctx, id)
+}
+
+func (r *PostgresRepository) ListCategories(ctx context.Context, includeInactive bool) ([]models.Category, error) {
+	categories := r.categories
+	if includeInactive {
+		categories = categories.Unscoped()
+	}
+
+	return categories.List(ctx, db.NewSpec().OrderBy(db.Asc("level"), db.Asc("name")))
+}
+
+func (r *PostgresRepository) GetCategory(ctx context.Context, id string) (*models.Category, error) {
+	return r.categories.Get(ctx, id)
+}
+
+func (r *PostgresRepository) GetCategoryBySlug(ctx context.Context, slug string) (*models.Category, error) {
+	return r.categories.First(ctx, db.NewSpec().Where(db.Eq("slug", slug)))
+}
+
+func (r *PostgresRepository) CreateCategory(ctx context.Context, category *models.Category) error {
+	exists, err := r.categories.Unscoped().Exists(ctx, db.NewSpec().Where(db.Eq("slug", category.Slug)))
+	if err != nil {
+		return err
+	}
+	if exists {
+		return apperr.Conflict("category slug already exists")
+	}
+
+	category.ID = uuid.New().String()
+	category.IsActive = true
+
+	// A concurrent create can claim the slug between the check and the
+	// insert, which the unique index then rejects
+	if err := r.categories.Create(ctx, category); err != nil {
+		if db.IsUniqueViolation(err) {
+			return apperr.Conflict("category slug already exists")
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) UpdateCategory(ctx context.Context, id string, updates *models.UpdateCategoryRequest) (*models.Category, error) {
+	// Inactive categories can be updated, to reactivate them
+	return updateCategory(ctx, r.categories.Unscoped(), id, updates)
+}
+
+// categoryTreeLockKey identifies the advisory lock category moves hold while
+// they plan and write a move
+const categoryTreeLockKey int64 = 0x43617465676f7279 // "Category"
+
+// MoveCategory applies updates that move a category and stores the new levels
+// of the categories below it in the same transaction, so that a failure
+// leaves the hierarchy as it was. Moves take turns on the tree: each loads
+// it under a lock and plans its levels with relevel, which rejects moves that
+// would make a cycle, so that concurrent moves are checked against each
+// other's result.
+func (r *PostgresRepository) MoveCategory(ctx context.Context, id string, updates *models.UpdateCategoryRequest, relevel func(categories []models.Category) (map[string]int, error)) (*models.Category, error) {
+	var category *models.Category
+	err := r.categories.Unscoped().Transaction(ctx, func(tx *db.Repository[models.Category]) error {
+		if err := tx.DB.WithContext(ctx).Exec("SELECT pg_advisory_xact_lock(?)", categoryTreeLockKey).Error; err != nil {
+			return fmt.Errorf("failed to lock category tree: %w", err)
+		}
+
+		categories, err := tx.List(ctx, db.NewSpec())
+		if err != nil {
+			return err
+		}
+		levels, err := relevel(categories)
+		if err != nil {
+			return err
+		}
+
+		category, err = updateCategory(ctx, tx, id, updates)
+		if err != nil {
+			return err
+		}
+
+		for levelID, level := range levels {
+			if err := tx.Update(ctx, levelID, map[string]interface{}{"level": level}); err != nil {
+				return fmt.Errorf("failed to update category level: %w", err)
+			}
+		}
+		if level, ok := levels[id]; ok {
+			category.Level = level
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+func updateCategory(ctx context.Context, categories *db.Repository[models.Category], id string, updates *models.UpdateCategoryRequest) (*models.Category, error) {
+	// First get the existing category
+	if _, err := categories.Get(ctx, id); err != nil {
+		return nil, err
+	}
+
+	// Apply updates
+	updateFields := make(map[string]interface{})
+
+	if updates.Name != nil {
+		updateFields["name"] = *updates.Name
+	}
+	if updates.Description != nil {
+		updateFields["description"] = *updates.Description
+	}
+	if updates.Slug != nil {
+		exists, err := categories.Exists(ctx, db.NewSpec().Where(db.Eq("slug", *updates.Slug), db.Ne("id", id)))
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, apperr.Conflict("category slug already exists")
+		}
+		updateFields["slug"] = *updates.Slug
+	}
+	if updates.ParentID != nil {
+		// An empty parent ID moves the category back to the root
+		if *updates.ParentID == "" {
+			updateFields["parent_id"] = nil
+		} else {
+			updateFields["parent_id"] = *updates.ParentID
+		}
+	}
+	if updates.IsActive != nil {
+		updateFields["is_active"] = *updates.IsActive
+	}
+
+	if err := categories.Update(ctx, id, updateFields); err != nil {
+		if db.IsUniqueViolation(err) {
+			return nil, apperr.Conflict("category slug already exists")
+		}
+		return nil, err
+	}
+
+	// Return updated category
+	return categories.Get(ctx, id)
+}
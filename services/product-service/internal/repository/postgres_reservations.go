@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"product-service/internal/models"
+	"shared/apperr"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *PostgresRepository) ReserveStock(ctx context.Context, reservation *models.StockReservation) error {
+	reservation.ID = uuid.New().String()
+	reservation.Status = models.ReservationStatusActive
+
+	return r.Transaction(ctx, func(tx *gorm.DB) error {
+		// Holding units only succeeds while enough unreserved stock remains
+		result := tx.Model(&models.Product{}).
+			Where("id = ? AND is_active = ? AND stock - reserved >= ?", reservation.ProductID, true, reservation.Quantity).
+			Update("reserved", gorm.Expr("reserved + ?", reservation.Quantity))
+		if result.Error != nil {
+			return fmt.Errorf("failed to reserve stock: %w", result.Error)
+		}
+
+		if result.RowsAffected == 0 {
+			var count int64
+			if err := tx.Model(&models.Product{}).Where("id = ? AND is_active = ?", reservation.ProductID, true).Count(&count).Error; err != nil {
+				return fmt.Errorf("failed to reserve stock: %w", err)
+			}
+			if count == 0 {
+				return apperr.NotFound("product not found")
+			}
+			return apperr.InsufficientStock("product %s cannot reserve %d units", reservation.ProductID, reservation.Quantity)
+		}
+
+		if err := tx.Create(reservation).Error; err != nil {
+			return fmt.Errorf("failed to create reservation: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (r *PostgresRepository) GetReservation(ctx context.Context, id string) (*models.StockReservation, error) {
+	var reservation models.StockReservation
+	result := r.DB.WithContext(ctx).Where("id = ?", id).First(&reservation)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, apperr.NotFound("reservation not found")
+	}
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get reservation: %w", result.Error)
+	}
+
+	return &reservation, nil
+}
+
+func (r *PostgresRepository) ConfirmReservation(ctx context.Context, id string) (*models.StockReservation, error) {
+	var reservation models.StockReservation
+	expired := false
+
+	err := r.Transaction(ctx, func(tx *gorm.DB) error {
+		if err := r.lockActiveReservation(tx, id, &reservation); err != nil {
+			return err
+		}
+
+		// A stale hold is expired rather than confirmed; the transaction still
+		// commits so the units go back to the product
+		if !reservation.ExpiresAt.After(time.Now().UTC()) {
+			expired = true
+			return r.closeReservation(tx, &reservation, models.ReservationStatusExpired)
+		}
+
+		// Confirming turns the hold into a real decrement
+		result := tx.Model(&models.Product{}).
+			Where("id = ?", reservation.ProductID).
+			Updates(map[string]interface{}{
+				"stock":    gorm.Expr("stock - ?", reservation.Quantity),
+				"reserved": gorm.Expr("reserved - ?", reservation.Quantity),
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to confirm reservation: %w", result.Error)
+		}
+
+		err := recordStockMovement(tx, &models.StockMovement{
+			ProductID:   reservation.ProductID,
+			Delta:       -reservation.Quantity,
+			Reason:      models.StockMovementSale,
+			ReferenceID: reservation.OrderID,
+		})
+		if err != nil {
+			return err
+		}
+
+		return r.setReservationStatus(tx, &reservation, models.ReservationStatusConfirmed)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if expired {
+		return nil, fmt.Errorf("%w: reservation %s expired", ErrReservationNotActive, id)
+	}
+
+	return &reservation, nil
+}
+
+func (r *PostgresRepository) ReleaseReservation(ctx context.Context, id string) (*models.StockReservation, error) {
+	var reservation models.StockReservation
+
+	err := r.Transaction(ctx, func(tx *gorm.DB) error {
+		if err := r.lockActiveReservation(tx, id, &reservation); err != nil {
+			return err
+		}
+		return r.closeReservation(tx, &reservation, models.ReservationStatusReleased)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &reservation, nil
+}
+
+func (r *PostgresRepository) ExpireReservations(ctx context.Context, before time.Time, productID string) (int, error) {
+	var reservations []models.StockReservation
+
+	query := r.DB.WithContext(ctx).Where("status = ? AND expires_at <= ?", models.ReservationStatusActive, before)
+	if productID != "" {
+		query = query.Where("product_id = ?", productID)
+	}
+
+	result := query.Find(&reservations)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to find expired reservations: %w", result.Error)
+	}
+
+	expired := 0
+	for _, candidate := range reservations {
+		var reservation models.StockReservation
+		err := r.Transaction(ctx, func(tx *gorm.DB) error {
+			if err := r.lockActiveReservation(tx, candidate.ID, &reservation); err != nil {
+				return err
+			}
+			return r.closeReservation(tx, &reservation, models.ReservationStatusExpired)
+		})
+		if errors.Is(err, ErrReservationNotActive) {
+			// Confirmed or released concurrently
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		expired++
+	}
+
+	return expired, nil
+}
+
+// lockActiveReservation loads the reservation with a row lock and fails
+// unless it is still active
+func (r *PostgresRepository) lockActiveReservation(tx *gorm.DB, id string, reservation *models.StockReservation) error {
+	result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(reservation)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return apperr.NotFound("reservation not found")
+	}
+	if result.Error != nil {
+		return fmt.Errorf("failed to get reservation: %w", result.Error)
+	}
+
+	if reservation.Status != models.ReservationStatusActive {
+		return fmt.Errorf("%w: reservation %s is %s", ErrReservationNotActive, id, reservation.Status)
+	}
+
+	return nil
+}
+
+// closeReservation returns the held units to the product and marks the
+// reservation with the given final status
+func (r *PostgresRepository) closeReservation(tx *gorm.DB, reservation *models.StockReservation, status string) error {
+	result := tx.Model(&models.Product{}).
+		Where("id = ?", reservation.ProductID).
+		Update("reserved", gorm.Expr("reserved - ?", reservation.Quantity))
+	if result.Error != nil {
+		return fmt.Errorf("failed to release reserved stock: %w", result.Error)
+	}
+
+	return r.setReservationStatus(tx, reservation, status)
+}
+
+func (r *PostgresRepository) setReservationStatus(tx *gorm.DB, reservation *models.StockReservation, status string) error {
+	result := tx.Model(reservation).Update("status", status)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update reservation: %w", result.Error)
+	}
+
+	reservation.Status = status
+	return nil
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"product-service/internal/models"
+	"product-service/internal/suggest"
+	"shared/db"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// suggestionBatchSize is how many suggestion keys one INSERT writes
+const suggestionBatchSize = 500
+
+func (r *PostgresRepository) ListSynonymGroups(ctx context.Context) ([]models.SynonymGroup, error) {
+	return r.synonymGroups.List(ctx, db.NewSpec().OrderBy(db.Asc("created_at"), db.Asc("id")))
+}
+
+func (r *PostgresRepository) CreateSynonymGroup(ctx context.Context, group *models.SynonymGroup) error {
+	group.ID = uuid.New().String()
+	return r.synonymGroups.Create(ctx, group)
+}
+
+func (r *PostgresRepository) UpdateSynonymGroup(ctx context.Context, id string, terms []string) (*models.SynonymGroup, error) {
+	if err := r.synonymGroups.Update(ctx, id, map[string]interface{}{"terms": pq.StringArray(terms)}); err != nil {
+		return nil, err
+	}
+	return r.synonymGroups.Get(ctx, id)
+}
+
+func (r *PostgresRepository) DeleteSynonymGroup(ctx context.Context, id string) error {
+	return r.synonymGroups.Delete(ctx, id)
+}
+
+// SpellingSuggestions maps each word no active product name or brand holds
+// to the most similar word they do hold, by pg_trgm similarity. Words
+// without a similar enough one are left out.
+func (r *PostgresRepository) SpellingSuggestions(ctx context.Context, words []string) (map[string]string, error) {
+	var rows []struct {
+		Word       string
+		Suggestion string
+	}
+
+	result := r.DB.WithContext(ctx).Raw(`
+		WITH vocabulary AS (
+			SELECT DISTINCT word
+			FROM products, regexp_split_to_table(lower(name || ' ' || coalesce(brand, '')), '[[:space:][:punct:]]+') AS word
+			WHERE is_active = true AND word <> ''
+		)
+		SELECT DISTINCT ON (input) input AS word, vocabulary.word AS suggestion
+		FROM unnest(?::text[]) AS input
+		JOIN vocabulary ON vocabulary.word % input
+		ORDER BY input, similarity(vocabulary.word, input) DESC, vocabulary.word`, pq.StringArray(words)).Scan(&rows)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to suggest spellings: %w", result.Error)
+	}
+
+	suggestions := make(map[string]string, len(rows))
+	for _, row := range rows {
+		// A word some product holds needs no respelling
+		if row.Suggestion != row.Word {
+			suggestions[row.Word] = row.Suggestion
+		}
+	}
+	return suggestions, nil
+}
+
+// ListSuggestions computes the suggestions to store: the names of active
+// products and categories and the brands of active products, with the net
+// units sold of each
+func (r *PostgresRepository) ListSuggestions(ctx context.Context) ([]models.Suggestion, error) {
+	var suggestions []models.Suggestion
+
+	result := r.DB.WithContext(ctx).Raw(`
+		WITH sold AS (
+			SELECT product_id, -SUM(delta) AS units
+			FROM stock_movements
+			WHERE reason IN (?, ?)
+			GROUP BY product_id
+		), popular AS (
+			SELECT p.id, p.name, p.slug, p.brand, p.category_id, COALESCE(sold.units, 0) AS units
+			FROM products p
+			LEFT JOIN sold ON sold.product_id = p.id
+			WHERE p.is_active = true
+		)
+		SELECT ? AS kind, name AS text, id::text AS id, slug, units::bigint AS popularity
+		FROM popular
+		UNION ALL
+		SELECT ?, brand, '', '', SUM(units)::bigint
+		FROM popular
+		WHERE brand <> ''
+		GROUP BY brand
+		UNION ALL
+		SELECT ?, c.name, c.id::text, c.slug, COALESCE(SUM(popular.units), 0)::bigint
+		FROM categories c
+		LEFT JOIN popular ON popular.category_id = c.id
+		WHERE c.is_active = true
+		GROUP BY c.id, c.name, c.slug`,
+		models.StockMovementSale, models.StockMovementReturn,
+		models.SuggestionProduct, models.SuggestionBrand, models.SuggestionCategory,
+	).Scan(&suggestions)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list suggestions: %w", result.Error)
+	}
+
+	return suggestions, nil
+}
+
+// ReplaceSuggestions replaces the stored suggestions, storing each under
+// every key of its text. Searches see the old ones until the new are in.
+func (r *PostgresRepository) ReplaceSuggestions(ctx context.Context, suggestions []models.Suggestion) error {
+	var keys []models.SuggestionKey
+	for _, suggestion := range suggestions {
+		for _, key := range suggest.Keys(suggestion.Text) {
+			keys = append(keys, models.SuggestionKey{
+				SearchKey:    key,
+				Kind:         suggestion.Kind,
+				Text:         suggestion.Text,
+				SuggestionID: suggestion.ID,
+				Slug:         suggestion.Slug,
+				Popularity:   int64(suggestion.Popularity),
+			})
+		}
+	}
+
+	return r.Transaction(ctx, func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM suggestion_keys").Error; err != nil {
+			return fmt.Errorf("failed to clear suggestions: %w", err)
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+		if err := tx.CreateInBatches(keys, suggestionBatchSize).Error; err != nil {
+			return fmt.Errorf("failed to store suggestions: %w", err)
+		}
+		return nil
+	})
+}
+
+// FindSuggestions returns up to limit stored suggestions with a key starting
+// with a folded prefix, ranked as suggest.Rank ranks them
+func (r *PostgresRepository) FindSuggestions(ctx context.Context, prefix string, limit int) ([]models.Suggestion, error) {
+	suggestions := []models.Suggestion{}
+
+	// Keys hold only letters, digits and spaces, which LIKE takes literally
+	result := r.DB.WithContext(ctx).Raw(`
+		SELECT kind, text, id, slug, popularity
+		FROM (
+			SELECT DISTINCT ON (kind, suggestion_id, text)
+				kind, text, suggestion_id AS id, slug, popularity
+			FROM suggestion_keys
+			WHERE search_key LIKE ?
+		) matches
+		ORDER BY popularity DESC, octet_length(text), text COLLATE "C", id
+		LIMIT ?`,
+		prefix+"%", limit,
+	).Scan(&suggestions)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to find suggestions: %w", result.Error)
+	}
+
+	return suggestions, nil
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"product-service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func (r *PostgresRepository) ListStockMovements(ctx context.Context, productID string, limit, offset int) (*models.StockMovementListResponse, error) {
+	query := r.DB.WithContext(ctx).Model(&models.StockMovement{}).Where("product_id = ?", productID)
+
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count stock movements: %w", err)
+	}
+
+	var movements []models.StockMovement
+	result := query.Order("created_at DESC").Order("id DESC").Offset(offset).Limit(limit).Find(&movements)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list stock movements: %w", result.Error)
+	}
+
+	return &models.StockMovementListResponse{
+		Movements:  movements,
+		TotalCount: int(totalCount),
+		Limit:      limit,
+		Offset:     offset,
+		HasMore:    offset+len(movements) < int(totalCount),
+	}, nil
+}
+
+func (r *PostgresRepository) ReconcileStock(ctx context.Context) ([]models.StockDrift, error) {
+	var drifts []models.StockDrift
+
+	result := r.DB.WithContext(ctx).Raw(`
+		SELECT p.id AS product_id, p.sku, p.stock,
+			COALESCE(SUM(m.delta), 0) AS ledger_stock,
+			p.stock - COALESCE(SUM(m.delta), 0) AS drift
+		FROM products p
+		LEFT JOIN stock_movements m ON m.product_id = p.id
+		GROUP BY p.id, p.sku, p.stock
+		HAVING p.stock <> COALESCE(SUM(m.delta), 0)
+		ORDER BY p.sku`).Scan(&drifts)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to reconcile stock: %w", result.Error)
+	}
+
+	return drifts, nil
+}
+
+// recordStockMovement appends a ledger entry inside the caller's transaction
+func recordStockMovement(tx *gorm.DB, movement *models.StockMovement) error {
+	movement.ID = uuid.New().String()
+
+	result := tx.Create(movement)
+	if result.Error != nil {
+		return fmt.Errorf("failed to record stock movement: %w", result.Error)
+	}
+
+	return nil
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"product-service/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// newTestPostgresRepository connects to the database the DB_* variables name
+// when TEST_POSTGRES is set, and skips the test otherwise
+func newTestPostgresRepository(t *testing.T) *PostgresRepository {
+	t.Helper()
+	if os.Getenv("TEST_POSTGRES") == "" {
+		t.Skip("TEST_POSTGRES is not set")
+	}
+
+	repo, err := NewPostgresRepository()
+	if err != nil {
+		t.Fatalf("failed to connect to postgres: %v", err)
+	}
+	return repo
+}
+
+// newTestPostgresCatalog creates a department and a category of their own
+// and returns their IDs
+func newTestPostgresCatalog(t *testing.T, repo *PostgresRepository) (string, string) {
+	t.Helper()
+	ctx := context.Background()
+
+	suffix := uuid.New().String()[:8]
+	department := &models.Department{Name: "Test " + suffix, Slug: "test-" + suffix}
+	if err := repo.CreateDepartment(ctx, department); err != nil {
+		t.Fatalf("failed to create department: %v", err)
+	}
+	category := &models.Category{Name: "Test " + suffix, Slug: "test-" + suffix}
+	if err := repo.CreateCategory(ctx, category); err != nil {
+		t.Fatalf("failed to create category: %v", err)
+	}
+	return category.ID, department.ID
+}
+
+// newTestPostgresProduct creates a product holding concurrentStock units, in
+// a department and category of its own
+func newTestPostgresProduct(t *testing.T, repo *PostgresRepository) *models.Product {
+	t.Helper()
+
+	product := newConcurrencyProduct(newTestPostgresCatalog(t, repo))
+	if err := repo.CreateProduct(context.Background(), product); err != nil {
+		t.Fatalf("failed to create product: %v", err)
+	}
+	return product
+}
+
+func TestPostgresUpdateStockConcurrentSales(t *testing.T) {
+	repo := newTestPostgresRepository(t)
+	assertConcurrentSales(t, repo, newTestPostgresProduct(t, repo).ID)
+}
+
+func TestPostgresSetStockDuringSales(t *testing.T) {
+	repo := newTestPostgresRepository(t)
+	assertSetStockDuringSales(t, repo, newTestPostgresProduct(t, repo).ID)
+}
+
+func TestPostgresReturnStockOnce(t *testing.T) {
+	repo := newTestPostgresRepository(t)
+	assertReturnStockOnce(t, repo, newTestPostgresProduct(t, repo).ID)
+}
+
+func TestPostgresListingPages(t *testing.T) {
+	repo := newTestPostgresRepository(t)
+	categoryID, departmentID := newTestPostgresCatalog(t, repo)
+	assertListingPages(t, repo, categoryID, departmentID)
+}
+
+func TestPostgresSuggestions(t *testing.T) {
+	assertSuggestions(t, newTestPostgresRepository(t))
+}
+
+func TestPostgresConcurrentCategoryMoves(t *testing.T) {
+	assertConcurrentMoves(t, newTestPostgresRepository(t))
+}
@@ -0,0 +1,400 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"product-service/internal/filter"
+	"product-service/internal/models"
+	"shared/apperr"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// concurrentStock is the stock of the product the concurrency tests sell
+	concurrentStock = 20
+
+	// concurrentSales is how many single-unit sales hit the product at once
+	concurrentSales = 60
+)
+
+// newConcurrencyProduct returns a product holding concurrentStock units, with
+// unique SKU and slug so that runs do not collide
+func newConcurrencyProduct(categoryID, departmentID string) *models.Product {
+	suffix := uuid.New().String()[:8]
+	return &models.Product{
+		SKU:          "TEST-" + suffix,
+		Slug:         "test-" + suffix,
+		Name:         "Concurrency test " + suffix,
+		Price:        1,
+		CategoryID:   categoryID,
+		DepartmentID: departmentID,
+		Stock:        concurrentStock,
+	}
+}
+
+// assertConcurrentSales runs concurrentSales single-unit sales of a product at
+// once and checks that exactly its stock was sold, that every other sale was
+// refused with ErrInsufficientStock and that the stock never went below zero
+func assertConcurrentSales(t *testing.T, repo ProductRepository, productID string) {
+	t.Helper()
+	ctx := context.Background()
+
+	errs := make([]error, concurrentSales)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = repo.UpdateStock(ctx, &models.StockMovement{
+				ProductID:   productID,
+				Delta:       -1,
+				Reason:      models.StockMovementSale,
+				ReferenceID: fmt.Sprintf("sale-%d", i),
+			})
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	sold := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			sold++
+		case !errors.Is(err, apperr.ErrInsufficientStock):
+			t.Errorf("sale failed with %v, want %v", err, apperr.ErrInsufficientStock)
+		}
+	}
+	if sold != concurrentStock {
+		t.Errorf("sold %d units, want %d", sold, concurrentStock)
+	}
+
+	product, err := repo.GetProduct(ctx, productID)
+	if err != nil {
+		t.Fatalf("failed to get product: %v", err)
+	}
+	if product.Stock != 0 {
+		t.Errorf("stock is %d after the sales, want 0", product.Stock)
+	}
+
+	movements, err := repo.ListStockMovements(ctx, productID, concurrentSales+1, 0)
+	if err != nil {
+		t.Fatalf("failed to list stock movements: %v", err)
+	}
+	// The opening stock plus one entry per sale
+	if movements.TotalCount != concurrentStock+1 {
+		t.Errorf("ledger holds %d movements, want %d", movements.TotalCount, concurrentStock+1)
+	}
+}
+
+// assertSetStockDuringSales sets the stock of a product while sales of it run
+// and checks that no sale was lost: the stock must still match its ledger
+func assertSetStockDuringSales(t *testing.T, repo ProductRepository, productID string) {
+	t.Helper()
+	ctx := context.Background()
+
+	errs := make([]error, concurrentStock+1)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			if i == 0 {
+				stock := concurrentStock / 2
+				_, errs[i] = repo.UpdateProduct(ctx, productID, &models.UpdateProductRequest{Stock: &stock}, "staff-test")
+				return
+			}
+			errs[i] = repo.UpdateStock(ctx, &models.StockMovement{
+				ProductID:   productID,
+				Delta:       -1,
+				Reason:      models.StockMovementSale,
+				ReferenceID: fmt.Sprintf("sale-%d", i),
+			})
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	if errs[0] != nil {
+		t.Errorf("set stock failed with %v", errs[0])
+	}
+	for _, err := range errs[1:] {
+		if err != nil && !errors.Is(err, apperr.ErrInsufficientStock) {
+			t.Errorf("sale failed with %v, want %v", err, apperr.ErrInsufficientStock)
+		}
+	}
+
+	product, err := repo.GetProduct(ctx, productID)
+	if err != nil {
+		t.Fatalf("failed to get product: %v", err)
+	}
+	movements, err := repo.ListStockMovements(ctx, productID, 2*concurrentStock+2, 0)
+	if err != nil {
+		t.Fatalf("failed to list stock movements: %v", err)
+	}
+
+	ledgerStock := 0
+	for _, movement := range movements.Movements {
+		ledgerStock += movement.Delta
+	}
+	if product.Stock != ledgerStock || product.Stock < 0 {
+		t.Errorf("stock is %d, ledger sums to %d", product.Stock, ledgerStock)
+	}
+}
+
+// assertReturnStockOnce returns the units of one order line to a product
+// twice, concurrently, and checks that they were put back once
+func assertReturnStockOnce(t *testing.T, repo ProductRepository, productID string) {
+	t.Helper()
+	ctx := context.Background()
+
+	orderID := uuid.New().String()
+	errs := make([]error, 2)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = repo.ReturnStock(ctx, &models.StockMovement{
+				ProductID:   productID,
+				Delta:       3,
+				Reason:      models.StockMovementReturn,
+				ReferenceID: orderID,
+			})
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Errorf("return failed with %v", err)
+		}
+	}
+
+	product, err := repo.GetProduct(ctx, productID)
+	if err != nil {
+		t.Fatalf("failed to get product: %v", err)
+	}
+	if product.Stock != concurrentStock+3 {
+		t.Errorf("stock is %d after returning 3 units twice, want %d", product.Stock, concurrentStock+3)
+	}
+
+	movements, err := repo.ListStockMovements(ctx, productID, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to list stock movements: %v", err)
+	}
+	// The opening stock plus the one return
+	if movements.TotalCount != 2 {
+		t.Errorf("ledger holds %d movements, want 2", movements.TotalCount)
+	}
+}
+
+// listingPrices are the prices of the products assertListingPages pages
+// through, with ties the ID breaks
+var listingPrices = []float64{3, 1, 2, 2, 5, 4, 2}
+
+// assertListingPages creates products in a category of their own and pages
+// through them by price two at a time, forward to the end and back to the
+// start, checking that the pages hold every product once, in order. A price
+// changed halfway moves its product.
+func assertListingPages(t *testing.T, repo ProductRepository, categoryID, departmentID string) {
+	t.Helper()
+	ctx := context.Background()
+
+	var products []models.Product
+	for _, price := range listingPrices {
+		product := newConcurrencyProduct(categoryID, departmentID)
+		product.Price = price
+		if err := repo.CreateProduct(ctx, product); err != nil {
+			t.Fatalf("failed to create product: %v", err)
+		}
+		products = append(products, *product)
+	}
+
+	// The cheapest product becomes the dearest
+	price := 6.0
+	updated, err := repo.UpdateProduct(ctx, products[1].ID, &models.UpdateProductRequest{Price: &price}, "")
+	if err != nil {
+		t.Fatalf("failed to update product: %v", err)
+	}
+	products[1] = *updated
+
+	if err := filter.SortProducts(products, models.SortPriceAsc, filter.Search{}); err != nil {
+		t.Fatalf("failed to sort products: %v", err)
+	}
+	want := productIDs(products)
+
+	listing := models.ProductFilter{CategoryID: categoryID, Sort: models.SortPriceAsc, Limit: 2}
+	var forward []string
+	for {
+		response, err := repo.ListProducts(ctx, listing)
+		if err != nil {
+			t.Fatalf("failed to list products: %v", err)
+		}
+		if response.TotalCount != len(products) || response.TotalCountApproximate {
+			t.Errorf("total count is %d (approximate: %v), want exactly %d",
+				response.TotalCount, response.TotalCountApproximate, len(products))
+		}
+		forward = append(forward, productIDs(response.Products)...)
+		if !response.HasMore {
+			break
+		}
+		listing.Seek = listingSeek(t, response.Products[len(response.Products)-1], false)
+	}
+	if strings.Join(forward, ",") != strings.Join(want, ",") {
+		t.Errorf("forward pages hold %v, want %v", forward, want)
+	}
+
+	// Back from the last product
+	var backward []string
+	listing.Seek = listingSeek(t, products[len(products)-1], true)
+	for {
+		response, err := repo.ListProducts(ctx, listing)
+		if err != nil {
+			t.Fatalf("failed to list products: %v", err)
+		}
+		backward = append(productIDs(response.Products), backward...)
+		if !response.HasPrevious {
+			break
+		}
+		listing.Seek = listingSeek(t, response.Products[0], true)
+	}
+	if strings.Join(backward, ",") != strings.Join(want[:len(want)-1], ",") {
+		t.Errorf("backward pages hold %v, want %v", backward, want[:len(want)-1])
+	}
+}
+
+// listingSeek marks the place of a product in a listing by price
+func listingSeek(t *testing.T, product models.Product, backward bool) *models.ProductSeek {
+	t.Helper()
+	values, err := filter.SortValues(&product, models.SortPriceAsc, filter.Search{})
+	if err != nil {
+		t.Fatalf("failed to get sort values: %v", err)
+	}
+	return &models.ProductSeek{Values: values, Backward: backward}
+}
+
+func productIDs(products []models.Product) []string {
+	ids := make([]string, len(products))
+	for i := range products {
+		ids[i] = products[i].ID
+	}
+	return ids
+}
+
+// assertSuggestions stores suggestions, completes prefixes against them and
+// checks that storing others drops those left out
+func assertSuggestions(t *testing.T, repo ProductRepository) {
+	t.Helper()
+	ctx := context.Background()
+
+	suggestions := []models.Suggestion{
+		{Kind: models.SuggestionProduct, Text: "Jamón Serrano", ID: uuid.New().String(), Slug: "jamon-serrano", Popularity: 5},
+		{Kind: models.SuggestionBrand, Text: "Serrano Foods", Popularity: 9},
+		{Kind: models.SuggestionCategory, Text: "Jamones", ID: uuid.New().String(), Slug: "jamones", Popularity: 2},
+		{Kind: models.SuggestionProduct, Text: "Queso Manchego", ID: uuid.New().String(), Slug: "queso-manchego", Popularity: 7},
+	}
+	if err := repo.ReplaceSuggestions(ctx, suggestions); err != nil {
+		t.Fatalf("failed to replace suggestions: %v", err)
+	}
+
+	assertFound := func(prefix string, limit int, want string) {
+		t.Helper()
+		found, err := repo.FindSuggestions(ctx, prefix, limit)
+		if err != nil {
+			t.Fatalf("failed to find suggestions: %v", err)
+		}
+		texts := make([]string, len(found))
+		for i := range found {
+			texts[i] = found[i].Text
+		}
+		if got := strings.Join(texts, "|"); got != want {
+			t.Errorf("%q completes to %q, want %q", prefix, got, want)
+		}
+	}
+	assertFound("serr", 10, "Serrano Foods|Jamón Serrano")
+	assertFound("jamon", 10, "Jamón Serrano|Jamones")
+	assertFound("jam", 1, "Jamón Serrano")
+	assertFound("jamon ser", 10, "Jamón Serrano")
+	assertFound("pan", 10, "")
+
+	if err := repo.ReplaceSuggestions(ctx, suggestions[:1]); err != nil {
+		t.Fatalf("failed to replace suggestions: %v", err)
+	}
+	assertFound("serr", 10, "Jamón Serrano")
+}
+
+// assertConcurrentMoves moves two root categories beneath each other at once,
+// many times over, and checks that never both moves commit: the tree must
+// stay free of cycles
+func assertConcurrentMoves(t *testing.T, repo ProductRepository) {
+	t.Helper()
+	ctx := context.Background()
+
+	// relevel stands in for the service's plan, rejecting a move beneath the
+	// category's own subtree
+	relevel := func(id, parentID string) func([]models.Category) (map[string]int, error) {
+		return func(categories []models.Category) (map[string]int, error) {
+			parents := make(map[string]string, len(categories))
+			for _, category := range categories {
+				if category.ParentID != nil {
+					parents[category.ID] = *category.ParentID
+				}
+			}
+			for ancestor := parentID; ancestor != ""; ancestor = parents[ancestor] {
+				if ancestor == id {
+					return nil, apperr.InvalidField("parent_id", "category cycle detected")
+				}
+			}
+			return map[string]int{id: 1}, nil
+		}
+	}
+
+	for round := 0; round < 10; round++ {
+		suffix := uuid.New().String()[:8]
+		a := &models.Category{Name: "Move A " + suffix, Slug: "move-a-" + suffix}
+		b := &models.Category{Name: "Move B " + suffix, Slug: "move-b-" + suffix}
+		for _, category := range []*models.Category{a, b} {
+			if err := repo.CreateCategory(ctx, category); err != nil {
+				t.Fatalf("failed to create category: %v", err)
+			}
+		}
+
+		moves := [][2]string{{a.ID, b.ID}, {b.ID, a.ID}}
+		errs := make([]error, len(moves))
+		start := make(chan struct{})
+		var wg sync.WaitGroup
+		for i, move := range moves {
+			wg.Add(1)
+			go func(i int, id, parentID string) {
+				defer wg.Done()
+				<-start
+				_, errs[i] = repo.MoveCategory(ctx, id, &models.UpdateCategoryRequest{ParentID: &parentID}, relevel(id, parentID))
+			}(i, move[0], move[1])
+		}
+		close(start)
+		wg.Wait()
+
+		for _, err := range errs {
+			if err != nil && !errors.Is(err, apperr.ErrValidation) && !errors.Is(err, apperr.ErrConflict) {
+				t.Fatalf("move failed with %v, want a validation error or a conflict", err)
+			}
+		}
+		if errs[0] == nil && errs[1] == nil {
+			t.Fatalf("both moves committed, making a cycle")
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"product-service/internal/models"
+	"shared/apperr"
+
+	"github.com/google/uuid"
+)
+
+func (s *ProductService) ListDepartments(ctx context.Context, includeInactive bool) ([]models.Department, error) {
+	departments, err := s.repo.ListDepartments(ctx, includeInactive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list departments: %w", err)
+	}
+
+	return departments, nil
+}
+
+// GetDepartment looks a department up by its ID, or by its slug when the key
+// is not a UUID
+func (s *ProductService) GetDepartment(ctx context.Context, key string) (*models.Department, error) {
+	if key == "" {
+		return nil, apperr.InvalidField("id", "department ID or slug is required")
+	}
+
+	var department *models.Department
+	var err error
+	if _, parseErr := uuid.Parse(key); parseErr == nil {
+		department, err = s.repo.GetDepartment(ctx, key)
+	} else {
+		department, err = s.repo.GetDepartmentBySlug(ctx, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get department: %w", err)
+	}
+
+	return department, nil
+}
+
+func (s *ProductService) CreateDepartment(ctx context.Context, request *models.CreateDepartmentRequest) (*models.Department, error) {
+	if request == nil {
+		return nil, apperr.Validation("create department request is required")
+	}
+
+	department := &models.Department{
+		Name:        request.Name,
+		Description: request.Description,
+		Icon:        request.Icon,
+		Image:       request.Image,
+		Slug:        request.Slug,
+	}
+
+	err := s.repo.CreateDepartment(ctx, department)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create department: %w", err)
+	}
+
+	return department, nil
+}
+
+func (s *ProductService) UpdateDepartment(ctx context.Context, id string, request *models.UpdateDepartmentRequest) (*models.Department, error) {
+	if id == "" {
+		return nil, apperr.InvalidField("id", "department ID is required")
+	}
+
+	if request == nil {
+		return nil, apperr.Validation("update department request is required")
+	}
+
+	department, err := s.repo.UpdateDepartment(ctx, id, request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update department: %w", err)
+	}
+
+	return department, nil
+}
+
+func (s *ProductService) DeactivateDepartment(ctx context.Context, id string) error {
+	if id == "" {
+		return apperr.InvalidField("id", "department ID is required")
+	}
+
+	isActive := false
+	_, err := s.repo.UpdateDepartment(ctx, id, &models.UpdateDepartmentRequest{
+		IsActive: &isActive,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to deactivate department: %w", err)
+	}
+
+	return nil
+}
+
+func (s *ProductService) ListCategories(ctx context.Context, includeInactive bool) ([]models.Category, error) {
+	categories, err := s.repo.ListCategories(ctx, includeInactive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+// GetCategory looks a category up by its ID, or by its slug when the key is
+// not a UUID
+func (s *ProductService) GetCategory(ctx context.Context, key string) (*models.Category, error) {
+	if key == "" {
+		return nil, apperr.InvalidField("id", "category ID or slug is required")
+	}
+
+	var category *models.Category
+	var err error
+	if _, parseErr := uuid.Parse(key); parseErr == nil {
+		category, err = s.repo.GetCategory(ctx, key)
+	} else {
+		category, err = s.repo.GetCategoryBySlug(ctx, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category: %w", err)
+	}
+
+	return category, nil
+}
+
+func (s *ProductService) CreateCategory(ctx context.Context, request *models.CreateCategoryRequest) (*models.Category, error) {
+	if request == nil {
+		return nil, apperr.Validation("create category request is required")
+	}
+
+	category := &models.Category{
+		Name:        request.Name,
+		Slug:        request.Slug,
+		Description: request.Description,
+	}
+	if request.ParentID != nil && *request.ParentID != "" {
+		tree, err := s.loadCategoryTree(ctx, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create category: %w", err)
+		}
+		if err := tree.validateParent("", *request.ParentID); err != nil {
+			return nil, err
+		}
+		category.ParentID = request.ParentID
+		category.Level = tree.levels()[*request.ParentID] + 1
+	}
+
+	err := s.repo.CreateCategory(ctx, category)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create category: %w", err)
+	}
+
+	return category, nil
+}
+
+func (s *ProductService) UpdateCategory(ctx context.Context, id string, request *models.UpdateCategoryRequest) (*models.Category, error) {
+	if id == "" {
+		return nil, apperr.InvalidField("id", "category ID is required")
+	}
+
+	if request == nil {
+		return nil, apperr.Validation("update category request is required")
+	}
+
+	if request.ParentID == nil {
+		category, err := s.repo.UpdateCategory(ctx, id, request)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update category: %w", err)
+		}
+		return category, nil
+	}
+
+	// Moving a category re-levels it and its whole subtree, so reject moves
+	// that would make it its own ancestor. The repository plans the move on
+	// the tree as it is when the move is written, so that concurrent moves
+	// cannot both pass the check and make a cycle.
+	parentID := *request.ParentID
+	relevel := func(categories []models.Category) (map[string]int, error) {
+		tree := newCategoryTree(categories)
+		if parentID != "" {
+			if err := tree.validateParent(id, parentID); err != nil {
+				return nil, err
+			}
+		}
+		return tree.movedLevels(id, parentID), nil
+	}
+
+	category, err := s.repo.MoveCategory(ctx, id, request, relevel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update category: %w", err)
+	}
+
+	return category, nil
+}
+
+func (s *ProductService) DeactivateCategory(ctx context.Context, id string) error {
+	if id == "" {
+		return apperr.InvalidField("id", "category ID is required")
+	}
+
+	isActive := false
+	_, err := s.repo.UpdateCategory(ctx, id, &models.UpdateCategoryRequest{
+		IsActive: &isActive,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to deactivate category: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"product-service/internal/models"
+	"shared/apperr"
+	"sort"
+)
+
+// categoryTree indexes a flat list of categories by ID and by parent so the
+// hierarchy can be walked in either direction
+type categoryTree struct {
+	byID     map[string]models.Category
+	children map[string][]string
+	roots    []string
+}
+
+func newCategoryTree(categories []models.Category) *categoryTree {
+	tree := &categoryTree{
+		byID:     make(map[string]models.Category, len(categories)),
+		children: make(map[string][]string),
+	}
+
+	for _, category := range categories {
+		tree.byID[category.ID] = category
+	}
+
+	// Categories whose parent is missing from the list (e.g. an inactive
+	// parent) are treated as roots so they stay reachable
+	for _, category := range categories {
+		if category.ParentID != nil {
+			if _, ok := tree.byID[*category.ParentID]; ok {
+				tree.children[*category.ParentID] = append(tree.children[*category.ParentID], category.ID)
+				continue
+			}
+		}
+		tree.roots = append(tree.roots, category.ID)
+	}
+
+	tree.sortByName(tree.roots)
+	for _, ids := range tree.children {
+		tree.sortByName(ids)
+	}
+
+	return tree
+}
+
+func (t *categoryTree) sortByName(ids []string) {
+	sort.Slice(ids, func(i, j int) bool {
+		return t.byID[ids[i]].Name < t.byID[ids[j]].Name
+	})
+}
+
+// ancestors returns the chain of parents of id, root first
+func (t *categoryTree) ancestors(id string) ([]models.Category, error) {
+	var chain []models.Category
+	seen := map[string]bool{id: true}
+
+	current := t.byID[id]
+	for current.ParentID != nil {
+		parent, ok := t.byID[*current.ParentID]
+		if !ok {
+			break
+		}
+		if seen[parent.ID] {
+			return nil, fmt.Errorf("category cycle detected at %s", parent.ID)
+		}
+		seen[parent.ID] = true
+		chain = append([]models.Category{parent}, chain...)
+		current = parent
+	}
+
+	return chain, nil
+}
+
+// descendants returns the IDs of every category below id
+func (t *categoryTree) descendants(id string) []string {
+	var ids []string
+	queue := append([]string(nil), t.children[id]...)
+	seen := map[string]bool{id: true}
+
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		if seen[next] {
+			continue
+		}
+		seen[next] = true
+		ids = append(ids, next)
+		queue = append(queue, t.children[next]...)
+	}
+
+	return ids
+}
+
+func (t *categoryTree) node(id string) *models.CategoryNode {
+	node := &models.CategoryNode{
+		Category: t.byID[id],
+		Children: make([]*models.CategoryNode, 0, len(t.children[id])),
+	}
+	for _, childID := range t.children[id] {
+		node.Children = append(node.Children, t.node(childID))
+	}
+	return node
+}
+
+func (t *categoryTree) nodes() []*models.CategoryNode {
+	nodes := make([]*models.CategoryNode, 0, len(t.roots))
+	for _, id := range t.roots {
+		nodes = append(nodes, t.node(id))
+	}
+	return nodes
+}
+
+// levels returns the depth of every category, with roots at level 0
+func (t *categoryTree) levels() map[string]int {
+	levels := make(map[string]int, len(t.byID))
+
+	var walk func(id string, level int)
+	walk = func(id string, level int) {
+		if _, done := levels[id]; done {
+			return
+		}
+		levels[id] = level
+		for _, childID := range t.children[id] {
+			walk(childID, level+1)
+		}
+	}
+	for _, id := range t.roots {
+		walk(id, 0)
+	}
+
+	return levels
+}
+
+// movedLevels returns the levels that change when id is moved beneath
+// parentID, or to the root when parentID is empty: its own and those of its
+// whole subtree, along with any other stale level
+func (t *categoryTree) movedLevels(id, parentID string) map[string]int {
+	categories := make([]models.Category, 0, len(t.byID))
+	for _, category := range t.byID {
+		if category.ID == id {
+			category.ParentID = nil
+			if parentID != "" {
+				category.ParentID = &parentID
+			}
+		}
+		categories = append(categories, category)
+	}
+
+	changes := make(map[string]int)
+	for categoryID, level := range newCategoryTree(categories).levels() {
+		if t.byID[categoryID].Level != level {
+			changes[categoryID] = level
+		}
+	}
+
+	return changes
+}
+
+// validateParent checks that parentID exists and that attaching id beneath it
+// would not create a cycle
+func (t *categoryTree) validateParent(id, parentID string) error {
+	if _, ok := t.byID[parentID]; !ok {
+		return apperr.InvalidField("parent_id", "invalid parent category: parent does not exist")
+	}
+	if parentID == id {
+		return apperr.InvalidField("parent_id", "invalid parent category: a category cannot be its own parent")
+	}
+
+	ancestors, err := t.ancestors(parentID)
+	if err != nil {
+		return err
+	}
+	for _, ancestor := range ancestors {
+		if ancestor.ID == id {
+			return apperr.InvalidField("parent_id", "invalid parent category: category cycle detected")
+		}
+	}
+
+	return nil
+}
+
+func (s *ProductService) loadCategoryTree(ctx context.Context, includeInactive bool) (*categoryTree, error) {
+	categories, err := s.repo.ListCategories(ctx, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	return newCategoryTree(categories), nil
+}
+
+// GetCategoryTree returns every active category nested under its parent
+func (s *ProductService) GetCategoryTree(ctx context.Context) (*models.CategoryTreeResponse, error) {
+	tree, err := s.loadCategoryTree(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category tree: %w", err)
+	}
+
+	return &models.CategoryTreeResponse{
+		Breadcrumbs: []models.Category{},
+		Nodes:       tree.nodes(),
+	}, nil
+}
+
+// GetCategorySubtree returns the category identified by key with its
+// descendants, and the breadcrumbs leading to it from the root
+func (s *ProductService) GetCategorySubtree(ctx context.Context, key string) (*models.CategoryTreeResponse, error) {
+	category, err := s.GetCategory(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := s.loadCategoryTree(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category tree: %w", err)
+	}
+
+	breadcrumbs, err := tree.ancestors(category.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category tree: %w", err)
+	}
+
+	return &models.CategoryTreeResponse{
+		Breadcrumbs: append(breadcrumbs, tree.byID[category.ID]),
+		Nodes:       []*models.CategoryNode{tree.node(category.ID)},
+	}, nil
+}
+
+// expandCategoryFilter replaces a single category filter with the category
+// and all of its active descendants
+func (s *ProductService) expandCategoryFilter(ctx context.Context, filter *models.ProductFilter) error {
+	if !filter.IncludeSubcategories || filter.CategoryID == "" {
+		return nil
+	}
+
+	tree, err := s.loadCategoryTree(ctx, false)
+	if err != nil {
+		return err
+	}
+
+	filter.CategoryIDs = append([]string{filter.CategoryID}, tree.descendants(filter.CategoryID)...)
+	return nil
+}
@@ -1,36 +1,45 @@
 package service
 
 import (
-	"errors"
+	"context"
 	"fmt"
+	"product-service/internal/cursor"
+	"product-service/internal/filter"
 	"product-service/internal/models"
 	"product-service/internal/repository"
+	"shared/apperr"
+	"strings"
 )
 
 type ProductService struct {
-	repo repository.ProductRepository
+	repo    repository.ProductRepository
+	cursors *cursor.Codec
 }
 
-func NewProductService(repo repository.ProductRepository) *ProductService {
+// NewProductService creates a product service issuing listing cursors signed
+// by cursors
+func NewProductService(repo repository.ProductRepository, cursors *cursor.Codec) *ProductService {
 	return &ProductService{
-		repo: repo,
+		repo:    repo,
+		cursors: cursors,
 	}
 }
 
-func (s *ProductService) GetProduct(id string) (*models.Product, error) {
+func (s *ProductService) GetProduct(ctx context.Context, id string) (*models.Product, error) {
 	if id == "" {
-		return nil, errors.New("product ID is required")
+		return nil, apperr.InvalidField("id", "product ID is required")
 	}
 
-	product, err := s.repo.GetProduct(id)
+	product, err := s.repo.GetProduct(ctx, id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get product: %w", err)
 	}
 
+	setAvailability(product)
 	return product, nil
 }
 
-func (s *ProductService) ListProducts(filter models.ProductFilter) (*models.ProductListResponse, error) {
+func (s *ProductService) ListProducts(ctx context.Context, filter models.ProductFilter) (*models.ProductListResponse, error) {
 	// Set default pagination values
 	if filter.Limit <= 0 {
 		filter.Limit = 20
@@ -42,17 +51,122 @@ func (s *ProductService) ListProducts(filter models.ProductFilter) (*models.Prod
 		filter.Offset = 0
 	}
 
-	response, err := s.repo.ListProducts(filter)
+	if err := normalizeSort(&filter); err != nil {
+		return nil, err
+	}
+
+	// A cursor continues the listing it was issued for from the product it marks
+	query := cursor.Query(filter)
+	if filter.Cursor != "" {
+		c, err := s.cursors.Decode(filter.Cursor, query)
+		if err != nil {
+			return nil, apperr.InvalidField("cursor", "cursor is invalid or belongs to another query")
+		}
+		filter.Seek = &models.ProductSeek{Values: c.Values, Backward: c.Backward}
+	}
+
+	if err := s.expandCategoryFilter(ctx, &filter); err != nil {
+		return nil, fmt.Errorf("failed to list products: %w", err)
+	}
+
+	if filter.Search != "" {
+		if err := s.expandSynonyms(ctx, &filter); err != nil {
+			return nil, fmt.Errorf("failed to list products: %w", err)
+		}
+	}
+
+	response, err := s.repo.ListProducts(ctx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list products: %w", err)
 	}
 
+	for i := range response.Products {
+		setAvailability(&response.Products[i])
+	}
+
+	// A search that finds nothing may be misspelled
+	if response.TotalCount == 0 && filter.Search != "" {
+		if response.DidYouMean, err = s.didYouMean(ctx, filter.Search); err != nil {
+			return nil, fmt.Errorf("failed to list products: %w", err)
+		}
+	}
+
+	if err := s.setCursors(response, filter, query); err != nil {
+		return nil, fmt.Errorf("failed to list products: %w", err)
+	}
+
 	return response, nil
 }
 
-func (s *ProductService) CreateProduct(request *models.CreateProductRequest) (*models.Product, error) {
+// setCursors issues the cursors to the pages after and before a listing page
+func (s *ProductService) setCursors(response *models.ProductListResponse, filter models.ProductFilter, query string) error {
+	if len(response.Products) == 0 {
+		return nil
+	}
+
+	var err error
+	if response.HasMore {
+		last := &response.Products[len(response.Products)-1]
+		if response.NextCursor, err = s.encodeCursor(last, filter, query, false); err != nil {
+			return err
+		}
+	}
+	if response.HasPrevious {
+		if response.PrevCursor, err = s.encodeCursor(&response.Products[0], filter, query, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *ProductService) encodeCursor(product *models.Product, productFilter models.ProductFilter, query string, backward bool) (string, error) {
+	values, err := filter.SortValues(product, productFilter.Sort, filter.SearchOf(productFilter))
+	if err != nil {
+		return "", err
+	}
+	return s.cursors.Encode(cursor.Cursor{Query: query, Backward: backward, Values: values})
+}
+
+// normalizeSort defaults the sort order to relevance when searching and to
+// newest otherwise, and rejects orders outside models.ProductSorts
+func normalizeSort(filter *models.ProductFilter) error {
+	if filter.Sort == "" {
+		filter.Sort = models.SortNewest
+		if filter.Search != "" {
+			filter.Sort = models.SortRelevance
+		}
+		return nil
+	}
+
+	for _, sort := range models.ProductSorts {
+		if filter.Sort == sort {
+			if sort == models.SortRelevance && filter.Search == "" {
+				return apperr.InvalidField("sort", "sort relevance needs a search")
+			}
+			return nil
+		}
+	}
+
+	return apperr.InvalidField("sort", fmt.Sprintf("sort must be one of %s", strings.Join(models.ProductSorts, ", ")))
+}
+
+// ListTagFacets counts the active products matching the filter by tag
+func (s *ProductService) ListTagFacets(ctx context.Context, filter models.ProductFilter) ([]models.TagFacet, error) {
+	if err := s.expandCategoryFilter(ctx, &filter); err != nil {
+		return nil, fmt.Errorf("failed to list tag facets: %w", err)
+	}
+
+	facets, err := s.repo.ListTagFacets(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tag facets: %w", err)
+	}
+
+	return facets, nil
+}
+
+func (s *ProductService) CreateProduct(ctx context.Context, request *models.CreateProductRequest) (*models.Product, error) {
 	if request == nil {
-		return nil, errors.New("create product request is required")
+		return nil, apperr.Validation("create product request is required")
 	}
 
 	// Check if SKU already exists
@@ -82,53 +196,57 @@ func (s *ProductService) CreateProduct(request *models.CreateProductRequest) (*m
 		Tags:          request.Tags,
 	}
 
-	err := s.repo.CreateProduct(product)
+	err := s.repo.CreateProduct(ctx, product)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create product: %w", err)
 	}
 
+	setAvailability(product)
 	return product, nil
 }
 
-func (s *ProductService) UpdateProduct(id string, request *models.UpdateProductRequest) (*models.Product, error) {
+// UpdateProduct updates a product's fields. Setting stock is recorded in the
+// ledger as an adjustment by actor, in the transaction that writes the fields.
+func (s *ProductService) UpdateProduct(ctx context.Context, id string, actor string, request *models.UpdateProductRequest) (*models.Product, error) {
 	if id == "" {
-		return nil, errors.New("product ID is required")
+		return nil, apperr.InvalidField("id", "product ID is required")
 	}
 
 	if request == nil {
-		return nil, errors.New("update product request is required")
+		return nil, apperr.Validation("update product request is required")
 	}
 
 	// Verify product exists
-	_, err := s.repo.GetProduct(id)
+	_, err := s.repo.GetProduct(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("product not found: %w", err)
+		return nil, fmt.Errorf("failed to get product: %w", err)
 	}
 
-	updatedProduct, err := s.repo.UpdateProduct(id, request)
+	updatedProduct, err := s.repo.UpdateProduct(ctx, id, request, actor)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update product: %w", err)
 	}
 
+	setAvailability(updatedProduct)
 	return updatedProduct, nil
 }
 
-func (s *ProductService) DeleteProduct(id string) error {
+func (s *ProductService) DeleteProduct(ctx context.Context, id string) error {
 	if id == "" {
-		return errors.New("product ID is required")
+		return apperr.InvalidField("id", "product ID is required")
 	}
 
 	// Verify product exists
-	_, err := s.repo.GetProduct(id)
+	_, err := s.repo.GetProduct(ctx, id)
 	if err != nil {
-		return fmt.Errorf("product not found: %w", err)
+		return fmt.Errorf("failed to get product: %w", err)
 	}
 
 	// Instead of hard delete, we could soft delete by setting is_active = false
 	isActive := false
-	_, err = s.repo.UpdateProduct(id, &models.UpdateProductRequest{
+	_, err = s.repo.UpdateProduct(ctx, id, &models.UpdateProductRequest{
 		IsActive: &isActive,
-	})
+	}, "")
 	
 	if err != nil {
 		return fmt.Errorf("failed to delete product: %w", err)
@@ -137,24 +255,39 @@ func (s *ProductService) DeleteProduct(id string) error {
 	return nil
 }
 
-func (s *ProductService) UpdateStock(id string, quantity int) error {
+// UpdateStock adjusts a product's stock on behalf of actor, the authenticated
+// caller the movement is recorded against
+func (s *ProductService) UpdateStock(ctx context.Context, id string, actor string, request *models.UpdateStockRequest) error {
 	if id == "" {
-		return errors.New("product ID is required")
+		return apperr.InvalidField("id", "product ID is required")
 	}
 
-	// Get current product to check stock constraints
-	product, err := s.repo.GetProduct(id)
-	if err != nil {
-		return fmt.Errorf("product not found: %w", err)
+	if request == nil {
+		return apperr.Validation("update stock request is required")
+	}
+
+	reason := request.Reason
+	if reason == "" {
+		reason = models.StockMovementAdjustment
+		if request.Quantity > 0 {
+			reason = models.StockMovementRestock
+		}
 	}
 
-	// Check if the operation would result in negative stock
-	newStock := product.Stock + quantity
-	if newStock < 0 {
-		return fmt.Errorf("insufficient stock: current=%d, requested=%d", product.Stock, quantity)
+	if err := validateStockMovement(reason, request.Quantity); err != nil {
+		return err
 	}
 
-	err = s.repo.UpdateStock(id, quantity)
+	// The repository rejects adjustments that would make stock negative as
+	// part of the write, returning apperr.ErrInsufficientStock, and
+	// records the movement in the ledger in the same transaction
+	err := s.repo.UpdateStock(ctx, &models.StockMovement{
+		ProductID:   id,
+		Delta:       request.Quantity,
+		Reason:      reason,
+		ReferenceID: request.ReferenceID,
+		Actor:       actor,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to update stock: %w", err)
 	}
@@ -162,53 +295,103 @@ func (s *ProductService) UpdateStock(id string, quantity int) error {
 	return nil
 }
 
-func (s *ProductService) GetLowStockProducts() ([]models.Product, error) {
-	products, err := s.repo.GetLowStockProducts()
+// ReturnStock puts the units of an order back into a product's stock on
+// behalf of actor, the service that returned them. Each order returns a
+// product once; repeating the return changes nothing.
+func (s *ProductService) ReturnStock(ctx context.Context, id string, actor string, request *models.ReturnStockRequest) error {
+	if id == "" {
+		return apperr.InvalidField("id", "product ID is required")
+	}
+
+	if request == nil {
+		return apperr.Validation("return stock request is required")
+	}
+
+	err := s.repo.ReturnStock(ctx, &models.StockMovement{
+		ProductID:   id,
+		Delta:       request.Quantity,
+		Reason:      models.StockMovementReturn,
+		ReferenceID: request.OrderID,
+		Actor:       actor,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to return stock: %w", err)
+	}
+
+	return nil
+}
+
+// validateStockMovement checks that a reason is known and that the quantity
+// moves stock the way the reason does: sales and shrinkage take units out,
+// restocks and returns put them back, and adjustments go either way
+func validateStockMovement(reason string, quantity int) error {
+	switch reason {
+	case models.StockMovementSale, models.StockMovementShrinkage:
+		if quantity >= 0 {
+			return apperr.InvalidField("quantity", fmt.Sprintf("must be negative for a %s", reason))
+		}
+	case models.StockMovementRestock, models.StockMovementReturn:
+		if quantity <= 0 {
+			return apperr.InvalidField("quantity", fmt.Sprintf("must be positive for a %s", reason))
+		}
+	case models.StockMovementAdjustment:
+	default:
+		return apperr.InvalidField("reason", fmt.Sprintf("unknown stock movement reason %q", reason))
+	}
+	return nil
+}
+
+func (s *ProductService) GetLowStockProducts(ctx context.Context) ([]models.Product, error) {
+	products, err := s.repo.GetLowStockProducts(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get low stock products: %w", err)
 	}
 
+	for i := range products {
+		setAvailability(&products[i])
+	}
+
 	return products, nil
 }
 
-func (s *ProductService) SearchProducts(query string, filter models.ProductFilter) (*models.ProductListResponse, error) {
+func (s *ProductService) SearchProducts(ctx context.Context, query string, filter models.ProductFilter) (*models.ProductListResponse, error) {
 	if query == "" {
-		return s.ListProducts(filter)
+		return s.ListProducts(ctx, filter)
 	}
 
 	filter.Search = query
-	return s.ListProducts(filter)
+	return s.ListProducts(ctx, filter)
 }
 
-func (s *ProductService) GetProductsByCategory(categoryID string, filter models.ProductFilter) (*models.ProductListResponse, error) {
+func (s *ProductService) GetProductsByCategory(ctx context.Context, categoryID string, filter models.ProductFilter) (*models.ProductListResponse, error) {
 	if categoryID == "" {
-		return nil, errors.New("category ID is required")
+		return nil, apperr.InvalidField("category_id", "category ID is required")
 	}
 
 	filter.CategoryID = categoryID
-	return s.ListProducts(filter)
+	return s.ListProducts(ctx, filter)
 }
 
-func (s *ProductService) GetProductsByBrand(brand string, filter models.ProductFilter) (*models.ProductListResponse, error) {
+func (s *ProductService) GetProductsByBrand(ctx context.Context, brand string, filter models.ProductFilter) (*models.ProductListResponse, error) {
 	if brand == "" {
-		return nil, errors.New("brand is required")
+		return nil, apperr.InvalidField("brand", "brand is required")
 	}
 
 	filter.Brand = brand
-	return s.ListProducts(filter)
+	return s.ListProducts(ctx, filter)
 }
 
-func (s *ProductService) GetProductsByDepartment(departmentID string, filter models.ProductFilter) (*models.ProductListResponse, error) {
+func (s *ProductService) GetProductsByDepartment(ctx context.Context, departmentID string, filter models.ProductFilter) (*models.ProductListResponse, error) {
 	if departmentID == "" {
-		return nil, errors.New("department ID is required")
+		return nil, apperr.InvalidField("department_id", "department ID is required")
 	}
 
 	filter.DepartmentID = departmentID
-	return s.ListProducts(filter)
+	return s.ListProducts(ctx, filter)
 }
 
-func (s *ProductService) GetProductsOnSale(filter models.ProductFilter) (*models.ProductListResponse, error) {
+func (s *ProductService) GetProductsOnSale(ctx context.Context, filter models.ProductFilter) (*models.ProductListResponse, error) {
 	onSale := true
 	filter.IsOnSale = &onSale
-	return s.ListProducts(filter)
+	return s.ListProducts(ctx, filter)
 }
\ No newline at end of file
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"product-service/internal/models"
+	"shared/apperr"
+	"time"
+)
+
+// DefaultReservationTTL is how long a hold lasts when the request does not
+// specify one
+const DefaultReservationTTL = 15 * time.Minute
+
+// ReserveStock holds units of a product for an order until the reservation
+// is confirmed, released or expires
+func (s *ProductService) ReserveStock(ctx context.Context, request *models.CreateReservationRequest) (*models.StockReservation, error) {
+	if request == nil {
+		return nil, apperr.Validation("create reservation request is required")
+	}
+
+	if request.Quantity <= 0 {
+		return nil, apperr.InvalidField("quantity", "reservation quantity must be positive")
+	}
+
+	ttl := DefaultReservationTTL
+	if request.TTLSeconds > 0 {
+		ttl = time.Duration(request.TTLSeconds) * time.Second
+	}
+
+	// Free up stale holds on this product before checking availability
+	now := time.Now().UTC()
+	if _, err := s.repo.ExpireReservations(ctx, now, request.ProductID); err != nil {
+		return nil, fmt.Errorf("failed to expire reservations: %w", err)
+	}
+
+	reservation := &models.StockReservation{
+		ProductID: request.ProductID,
+		OrderID:   request.OrderID,
+		Quantity:  request.Quantity,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	err := s.repo.ReserveStock(ctx, reservation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve stock: %w", err)
+	}
+
+	return reservation, nil
+}
+
+func (s *ProductService) GetReservation(ctx context.Context, id string) (*models.StockReservation, error) {
+	if id == "" {
+		return nil, apperr.InvalidField("id", "reservation ID is required")
+	}
+
+	reservation, err := s.repo.GetReservation(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reservation: %w", err)
+	}
+
+	return reservation, nil
+}
+
+// ConfirmReservation converts a hold into a real stock decrement
+func (s *ProductService) ConfirmReservation(ctx context.Context, id string) (*models.StockReservation, error) {
+	if id == "" {
+		return nil, apperr.InvalidField("id", "reservation ID is required")
+	}
+
+	reservation, err := s.repo.ConfirmReservation(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm reservation: %w", err)
+	}
+
+	return reservation, nil
+}
+
+// ReleaseReservation returns the held units to the product
+func (s *ProductService) ReleaseReservation(ctx context.Context, id string) (*models.StockReservation, error) {
+	if id == "" {
+		return nil, apperr.InvalidField("id", "reservation ID is required")
+	}
+
+	reservation, err := s.repo.ReleaseReservation(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to release reservation: %w", err)
+	}
+
+	return reservation, nil
+}
+
+// ExpireReservations releases every active reservation past its expiry and
+// returns how many were expired. It is meant to run on a schedule.
+func (s *ProductService) ExpireReservations(ctx context.Context) (int, error) {
+	expired, err := s.repo.ExpireReservations(ctx, time.Now().UTC(), "")
+	if err != nil {
+		return expired, fmt.Errorf("failed to expire reservations: %w", err)
+	}
+
+	return expired, nil
+}
+
+// setAvailability fills in the units that are not held by reservations
+func setAvailability(products ...*models.Product) {
+	for _, product := range products {
+		product.Available = product.Stock - product.Reserved
+	}
+}
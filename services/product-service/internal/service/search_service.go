@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"product-service/internal/filter"
+	"product-service/internal/models"
+	"product-service/internal/suggest"
+	"shared/apperr"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultSuggestionLimit = 8
+	maxSuggestionLimit     = 20
+)
+
+// Suggest completes a prefix typed in the search box to product names,
+// brands and category names, most popular first
+func (s *ProductService) Suggest(ctx context.Context, prefix string, limit int) ([]models.Suggestion, error) {
+	if len(filter.SearchWords(prefix)) == 0 {
+		return nil, apperr.InvalidField("q", "q is required")
+	}
+
+	if limit <= 0 {
+		limit = defaultSuggestionLimit
+	}
+	if limit > maxSuggestionLimit {
+		limit = maxSuggestionLimit
+	}
+
+	suggestions, err := s.repo.FindSuggestions(ctx, suggest.Prefix(prefix), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find suggestions: %w", err)
+	}
+
+	return suggestions, nil
+}
+
+// RebuildSuggestions recomputes the stored suggestions from the catalog and
+// the units sold, returning how many there are. Suggest only reads what the
+// last rebuild stored.
+func (s *ProductService) RebuildSuggestions(ctx context.Context) (int, error) {
+	suggestions, err := s.repo.ListSuggestions(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list suggestions: %w", err)
+	}
+
+	if err := s.repo.ReplaceSuggestions(ctx, suggestions); err != nil {
+		return 0, fmt.Errorf("failed to replace suggestions: %w", err)
+	}
+
+	return len(suggestions), nil
+}
+
+func (s *ProductService) ListSynonymGroups(ctx context.Context) ([]models.SynonymGroup, error) {
+	groups, err := s.repo.ListSynonymGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list synonym groups: %w", err)
+	}
+
+	return groups, nil
+}
+
+func (s *ProductService) CreateSynonymGroup(ctx context.Context, request *models.SynonymGroupRequest) (*models.SynonymGroup, error) {
+	if request == nil {
+		return nil, apperr.Validation("synonym group request is required")
+	}
+
+	terms, err := s.synonymTerms(ctx, request.Terms, "")
+	if err != nil {
+		return nil, err
+	}
+
+	group := &models.SynonymGroup{Terms: terms}
+	err = s.repo.CreateSynonymGroup(ctx, group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create synonym group: %w", err)
+	}
+
+	return group, nil
+}
+
+// UpdateSynonymGroup replaces the terms of a synonym group
+func (s *ProductService) UpdateSynonymGroup(ctx context.Context, id string, request *models.SynonymGroupRequest) (*models.SynonymGroup, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, apperr.NotFound("synonym group not found")
+	}
+
+	if request == nil {
+		return nil, apperr.Validation("synonym group request is required")
+	}
+
+	terms, err := s.synonymTerms(ctx, request.Terms, id)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := s.repo.UpdateSynonymGroup(ctx, id, terms)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update synonym group: %w", err)
+	}
+
+	return group, nil
+}
+
+func (s *ProductService) DeleteSynonymGroup(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return apperr.NotFound("synonym group not found")
+	}
+
+	err := s.repo.DeleteSynonymGroup(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete synonym group: %w", err)
+	}
+
+	return nil
+}
+
+// synonymTerms normalizes the terms of a synonym group as searches are split
+// into words. A group needs two different terms, none of them in another
+// group than groupID.
+func (s *ProductService) synonymTerms(ctx context.Context, terms []string, groupID string) ([]string, error) {
+	normalized := make([]string, 0, len(terms))
+	seen := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		term = strings.Join(filter.SearchWords(term), " ")
+		if term == "" || seen[term] {
+			continue
+		}
+		seen[term] = true
+		normalized = append(normalized, term)
+	}
+	if len(normalized) < 2 {
+		return nil, apperr.InvalidField("terms", "a synonym group needs at least two different terms")
+	}
+
+	groups, err := s.repo.ListSynonymGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check synonym terms: %w", err)
+	}
+	for _, group := range groups {
+		if group.ID == groupID {
+			continue
+		}
+		for _, term := range group.Terms {
+			if seen[term] {
+				return nil, apperr.Conflict("term %q already belongs to another synonym group", term)
+			}
+		}
+	}
+
+	return normalized, nil
+}
+
+// expandSynonyms sets the synonyms of the words of a search on its filter
+func (s *ProductService) expandSynonyms(ctx context.Context, productFilter *models.ProductFilter) error {
+	groups, err := s.repo.ListSynonymGroups(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list synonym groups: %w", err)
+	}
+
+	synonyms := make(map[string][]string)
+	for _, word := range filter.SearchWords(productFilter.Search) {
+		for _, group := range groups {
+			if !containsTerm(group.Terms, word) {
+				continue
+			}
+			for _, term := range group.Terms {
+				if term != word {
+					synonyms[word] = append(synonyms[word], term)
+				}
+			}
+		}
+	}
+
+	if len(synonyms) > 0 {
+		productFilter.Synonyms = synonyms
+	}
+	return nil
+}
+
+// didYouMean respells the words of a search no product holds after the most
+// similar ones that some do. It returns "" when no word needs respelling.
+func (s *ProductService) didYouMean(ctx context.Context, search string) (string, error) {
+	words := filter.SearchWords(search)
+	if len(words) == 0 {
+		return "", nil
+	}
+
+	suggestions, err := s.repo.SpellingSuggestions(ctx, words)
+	if err != nil {
+		return "", fmt.Errorf("failed to suggest a spelling: %w", err)
+	}
+	if len(suggestions) == 0 {
+		return "", nil
+	}
+
+	for i, word := range words {
+		if suggestion, ok := suggestions[word]; ok {
+			words[i] = suggestion
+		}
+	}
+	return strings.Join(words, " "), nil
+}
+
+func containsTerm(terms []string, term string) bool {
+	for _, t := range terms {
+		if t == term {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"product-service/internal/models"
+	"shared/apperr"
+)
+
+// GetStockHistory returns a product's ledger entries, newest first
+func (s *ProductService) GetStockHistory(ctx context.Context, productID string, limit, offset int) (*models.StockMovementListResponse, error) {
+	if productID == "" {
+		return nil, apperr.InvalidField("id", "product ID is required")
+	}
+
+	// Set default pagination values
+	if limit <= 0 {
+		limit = 50
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	history, err := s.repo.ListStockMovements(ctx, productID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stock history: %w", err)
+	}
+
+	return history, nil
+}
+
+// ReconcileStock recomputes every product's stock from the ledger and returns
+// the products whose stored stock disagrees with it
+func (s *ProductService) ReconcileStock(ctx context.Context) ([]models.StockDrift, error) {
+	drifts, err := s.repo.ReconcileStock(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconcile stock: %w", err)
+	}
+
+	return drifts, nil
+}
@@ -0,0 +1,71 @@
+// Package suggest completes what is typed in the search box. Suggestions are
+// stored by the repositories under keys, one per word of their text, so that
+// a prefix typed from any word on finds them by a prefix match on the keys.
+package suggest
+
+import (
+	"sort"
+	"strings"
+
+	"product-service/internal/filter"
+	"product-service/internal/models"
+)
+
+// accents are folded so that "jamon" completes to "Jamón"
+var accents = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u")
+
+// Keys returns the keys of a suggestion text: its folded words from each one
+// to the end
+func Keys(text string) []string {
+	words := words(text)
+	keys := make([]string, len(words))
+	for i := range words {
+		keys[i] = strings.Join(words[i:], " ")
+	}
+	return keys
+}
+
+// Prefix folds what was typed as Keys folds texts, empty when it holds no
+// word
+func Prefix(typed string) string {
+	return strings.Join(words(typed), " ")
+}
+
+// Rank orders the suggestions a prefix found, most popular first, keeping
+// each once and up to limit of them. Ties go to the shorter text, the closer
+// completion.
+func Rank(suggestions []models.Suggestion, limit int) []models.Suggestion {
+	type identity struct{ kind, id, text string }
+	seen := make(map[identity]bool)
+	ranked := []models.Suggestion{}
+	for _, suggestion := range suggestions {
+		id := identity{suggestion.Kind, suggestion.ID, suggestion.Text}
+		if !seen[id] {
+			seen[id] = true
+			ranked = append(ranked, suggestion)
+		}
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		a, b := &ranked[i], &ranked[j]
+		if a.Popularity != b.Popularity {
+			return a.Popularity > b.Popularity
+		}
+		if len(a.Text) != len(b.Text) {
+			return len(a.Text) < len(b.Text)
+		}
+		if a.Text != b.Text {
+			return a.Text < b.Text
+		}
+		return a.ID < b.ID
+	})
+
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
+
+func words(text string) []string {
+	return filter.SearchWords(accents.Replace(strings.ToLower(text)))
+}
@@ -0,0 +1,38 @@
+package suggest
+
+import (
+	"strings"
+	"testing"
+
+	"product-service/internal/models"
+)
+
+func TestKeys(t *testing.T) {
+	keys := Keys("Jamón Serrano, 100g")
+	if got := strings.Join(keys, "|"); got != "jamon serrano 100g|serrano 100g|100g" {
+		t.Errorf("keys are %q", got)
+	}
+
+	if got := Prefix("  JAMÓN, ser"); got != "jamon ser" {
+		t.Errorf("prefix is %q, want %q", got, "jamon ser")
+	}
+}
+
+func TestRank(t *testing.T) {
+	serrano := models.Suggestion{Kind: models.SuggestionProduct, Text: "Jamón Serrano", ID: "p1", Popularity: 5}
+	suggestions := []models.Suggestion{
+		{Kind: models.SuggestionCategory, Text: "Jamones", ID: "c1", Popularity: 2},
+		serrano,
+		{Kind: models.SuggestionBrand, Text: "Serrano Foods", Popularity: 9},
+		serrano, // found by two of its keys
+		{Kind: models.SuggestionCategory, Text: "Jamón", ID: "c2", Popularity: 2},
+	}
+
+	var texts []string
+	for _, suggestion := range Rank(suggestions, 3) {
+		texts = append(texts, suggestion.Text)
+	}
+	if got := strings.Join(texts, "|"); got != "Serrano Foods|Jamón Serrano|Jamón" {
+		t.Errorf("ranked %q", got)
+	}
+}
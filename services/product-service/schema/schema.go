@@ -0,0 +1,19 @@
+// Package schema lists the models product-service keeps in Postgres, for the
+// startup schema check and the schema diff of tools/migrate
+package schema
+
+import "product-service/internal/models"
+
+// Models returns a model of every table the service reads and writes
+func Models() []interface{} {
+	return []interface{}{
+		&models.Department{},
+		&models.Category{},
+		&models.Product{},
+		&models.StockReservation{},
+		&models.StockMovement{},
+		&models.StockReturn{},
+		&models.SynonymGroup{},
+		&models.SuggestionKey{},
+	}
+}
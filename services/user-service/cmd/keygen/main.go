@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log"
+	"shared/auth"
+	"time"
+)
+
+// keygen prints a fresh token key pair: AUTH_SIGNING_KEY for user-service and
+// AUTH_PUBLIC_KEY for every service that verifies its tokens. With -service
+// it prints the pair a service signs its calls to other services with:
+// SERVICE_SIGNING_KEY for the service and its public key for those it calls.
+func main() {
+	var (
+		service = flag.String("service", "", "service to generate a call signing key for, e.g. order-service")
+	)
+	flag.Parse()
+
+	seed := make([]byte, 32)
+	if _, err := rand.Read(seed); err != nil {
+		log.Fatalf("❌ Failed to generate key: %v", err)
+	}
+
+	if *service != "" {
+		signer, err := auth.NewServiceSigner(*service, seed)
+		if err != nil {
+			log.Fatalf("❌ Failed to generate key: %v", err)
+		}
+
+		fmt.Printf("SERVICE_SIGNING_KEY=%s\n", base64.StdEncoding.EncodeToString(seed))
+		fmt.Printf("%s=%s\n", auth.ServicePublicKeyEnv(*service), signer.PublicKey())
+		return
+	}
+
+	signer, err := auth.NewSigner(seed, time.Hour)
+	if err != nil {
+		log.Fatalf("❌ Failed to generate key: %v", err)
+	}
+
+	fmt.Printf("AUTH_SIGNING_KEY=%s\n", base64.StdEncoding.EncodeToString(seed))
+	fmt.Printf("AUTH_PUBLIC_KEY=%s\n", signer.PublicKey())
+}
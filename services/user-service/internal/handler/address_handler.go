@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"shared/apperr"
+	"shared/auth"
+	"strings"
+	"user-service/internal/models"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func (h *LambdaHandler) listAddresses(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	callerID, err := h.userService.Authenticate(auth.BearerToken(request.Headers))
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	// Path format: /users/{id}/addresses
+	addresses, err := h.userService.ListAddresses(ctx, callerID, resolveUserID(request.Path, callerID))
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, map[string]interface{}{"addresses": addresses}, headers), nil
+}
+
+func (h *LambdaHandler) getAddress(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	callerID, err := h.userService.Authenticate(auth.BearerToken(request.Headers))
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	address, err := h.userService.GetAddress(ctx, callerID, resolveUserID(request.Path, callerID), extractAddressIDFromPath(request.Path))
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, address, headers), nil
+}
+
+func (h *LambdaHandler) createAddress(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	callerID, err := h.userService.Authenticate(auth.BearerToken(request.Headers))
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	var createRequest models.CreateAddressRequest
+
+	if err := json.Unmarshal([]byte(request.Body), &createRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
+	}
+
+	if err := h.validator.Struct(&createRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation(fmt.Sprintf("Validation error: %s", err.Error())), headers), nil
+	}
+
+	address, err := h.userService.CreateAddress(ctx, callerID, resolveUserID(request.Path, callerID), &createRequest)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusCreated, address, headers), nil
+}
+
+func (h *LambdaHandler) updateAddress(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	callerID, err := h.userService.Authenticate(auth.BearerToken(request.Headers))
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	var updateRequest models.UpdateAddressRequest
+
+	if err := json.Unmarshal([]byte(request.Body), &updateRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
+	}
+
+	if err := h.validator.Struct(&updateRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation(fmt.Sprintf("Validation error: %s", err.Error())), headers), nil
+	}
+
+	address, err := h.userService.UpdateAddress(ctx, callerID, resolveUserID(request.Path, callerID), extractAddressIDFromPath(request.Path), &updateRequest)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, address, headers), nil
+}
+
+func (h *LambdaHandler) setDefaultAddress(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	callerID, err := h.userService.Authenticate(auth.BearerToken(request.Headers))
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	// Path format: /users/{id}/addresses/{addressId}/default
+	address, err := h.userService.SetDefaultAddress(ctx, callerID, resolveUserID(request.Path, callerID), extractAddressIDFromPath(request.Path))
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, address, headers), nil
+}
+
+func (h *LambdaHandler) deleteAddress(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	callerID, err := h.userService.Authenticate(auth.BearerToken(request.Headers))
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	err = h.userService.DeleteAddress(ctx, callerID, resolveUserID(request.Path, callerID), extractAddressIDFromPath(request.Path))
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusNoContent, nil, headers), nil
+}
+
+// isAddressPath reports whether the path is under /users/{id}/addresses
+func isAddressPath(path string) bool {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	return len(parts) >= 3 && parts[0] == "users" && parts[2] == "addresses"
+}
+
+func extractAddressIDFromPath(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) >= 4 {
+		return parts[3]
+	}
+	return ""
+}
@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"shared/apperr"
+	"shared/auth"
+	"strings"
+	"user-service/internal/models"
+	"user-service/internal/repository"
+	"user-service/internal/service"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/go-playground/validator/v10"
+)
+
+type LambdaHandler struct {
+	userService *service.UserService
+	validator   *validator.Validate
+}
+
+func NewLambdaHandler() *LambdaHandler {
+	repo, err := repository.NewPostgresRepository()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize database: %v", err))
+	}
+
+	signer, err := auth.NewSignerFromEnv()
+	if err != nil {
+		panic(fmt.Sprintf("Failed to initialize token signer: %v", err))
+	}
+
+	userService := service.NewUserService(repo, signer, signer.Verifier())
+	validator := validator.New()
+
+	return &LambdaHandler{
+		userService: userService,
+		validator:   validator,
+	}
+}
+
+func (h *LambdaHandler) HandleRequest(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Enable CORS
+	headers := map[string]string{
+		"Content-Type":                 "application/json",
+		"Access-Control-Allow-Origin":  "*",
+		"Access-Control-Allow-Methods": "GET, POST, PUT, DELETE, OPTIONS",
+		"Access-Control-Allow-Headers": "Content-Type, Authorization",
+	}
+
+	// Handle preflight OPTIONS request
+	if request.HTTPMethod == "OPTIONS" {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusOK,
+			Headers:    headers,
+		}, nil
+	}
+
+	switch {
+	case request.HTTPMethod == "POST" && request.Path == "/auth/signup":
+		return h.signup(ctx, request, headers)
+	case request.HTTPMethod == "POST" && request.Path == "/auth/login":
+		return h.login(ctx, request, headers)
+	case request.HTTPMethod == "GET" && isAddressPath(request.Path) && strings.HasSuffix(request.Path, "/addresses"):
+		return h.listAddresses(ctx, request, headers)
+	case request.HTTPMethod == "POST" && isAddressPath(request.Path) && strings.HasSuffix(request.Path, "/addresses"):
+		return h.createAddress(ctx, request, headers)
+	case request.HTTPMethod == "POST" && isAddressPath(request.Path) && strings.HasSuffix(request.Path, "/default"):
+		return h.setDefaultAddress(ctx, request, headers)
+	case request.HTTPMethod == "GET" && isAddressPath(request.Path):
+		return h.getAddress(ctx, request, headers)
+	case request.HTTPMethod == "PUT" && isAddressPath(request.Path):
+		return h.updateAddress(ctx, request, headers)
+	case request.HTTPMethod == "DELETE" && isAddressPath(request.Path):
+		return h.deleteAddress(ctx, request, headers)
+	case request.HTTPMethod == "GET" && strings.HasPrefix(request.Path, "/users/"):
+		return h.getUser(ctx, request, headers)
+	case request.HTTPMethod == "PUT" && strings.HasPrefix(request.Path, "/users/"):
+		return h.updateUser(ctx, request, headers)
+	case request.HTTPMethod == "DELETE" && strings.HasPrefix(request.Path, "/users/"):
+		return h.deactivateUser(ctx, request, headers)
+	default:
+		return h.errorResponse(ctx, apperr.NotFound("Route not found"), headers), nil
+	}
+}
+
+func (h *LambdaHandler) signup(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	var signupRequest models.SignupRequest
+
+	if err := json.Unmarshal([]byte(request.Body), &signupRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
+	}
+
+	if err := h.validator.Struct(&signupRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation(fmt.Sprintf("Validation error: %s", err.Error())), headers), nil
+	}
+
+	response, err := h.userService.Signup(ctx, &signupRequest)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusCreated, response, headers), nil
+}
+
+func (h *LambdaHandler) login(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	var loginRequest models.LoginRequest
+
+	if err := json.Unmarshal([]byte(request.Body), &loginRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
+	}
+
+	if err := h.validator.Struct(&loginRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation(fmt.Sprintf("Validation error: %s", err.Error())), headers), nil
+	}
+
+	response, err := h.userService.Login(ctx, &loginRequest)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, response, headers), nil
+}
+
+func (h *LambdaHandler) getUser(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	callerID, err := h.userService.Authenticate(auth.BearerToken(request.Headers))
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	user, err := h.userService.GetUser(ctx, callerID, resolveUserID(request.Path, callerID))
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, user, headers), nil
+}
+
+func (h *LambdaHandler) updateUser(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	callerID, err := h.userService.Authenticate(auth.BearerToken(request.Headers))
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	var updateRequest models.UpdateUserRequest
+
+	if err := json.Unmarshal([]byte(request.Body), &updateRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation("Invalid JSON payload"), headers), nil
+	}
+
+	if err := h.validator.Struct(&updateRequest); err != nil {
+		return h.errorResponse(ctx, apperr.Validation(fmt.Sprintf("Validation error: %s", err.Error())), headers), nil
+	}
+
+	user, err := h.userService.UpdateUser(ctx, callerID, resolveUserID(request.Path, callerID), &updateRequest)
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusOK, user, headers), nil
+}
+
+func (h *LambdaHandler) deactivateUser(ctx context.Context, request events.APIGatewayProxyRequest, headers map[string]string) (events.APIGatewayProxyResponse, error) {
+	callerID, err := h.userService.Authenticate(auth.BearerToken(request.Headers))
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	err = h.userService.DeactivateUser(ctx, callerID, resolveUserID(request.Path, callerID))
+	if err != nil {
+		return h.errorResponse(ctx, err, headers), nil
+	}
+
+	return h.successResponse(http.StatusNoContent, nil, headers), nil
+}
+
+func (h *LambdaHandler) successResponse(statusCode int, data interface{}, headers map[string]string) events.APIGatewayProxyResponse {
+	var body string
+	if data != nil {
+		bodyBytes, _ := json.Marshal(data)
+		body = string(bodyBytes)
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers:    headers,
+		Body:       body,
+	}
+}
+
+func (h *LambdaHandler) errorResponse(ctx context.Context, err error, headers map[string]string) events.APIGatewayProxyResponse {
+	statusCode, errorBody := apperr.HTTPResponse(apperr.FromContext(ctx, err))
+	bodyBytes, _ := json.Marshal(errorBody)
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers:    headers,
+		Body:       string(bodyBytes),
+	}
+}
+
+// resolveUserID returns the user ID in the path, where "me" stands for the
+// authenticated user
+func resolveUserID(path string, callerID string) string {
+	id := extractIDFromPath(path)
+	if id == "me" {
+		return callerID
+	}
+	return id
+}
+
+func extractIDFromPath(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) >= 2 {
+		return parts[1]
+	}
+	return ""
+}
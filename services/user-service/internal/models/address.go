@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	AddressTypeShipping = "shipping"
+	AddressTypeBilling  = "billing"
+)
+
+// UserAddress is an entry of a user's address book. Each user has at most one
+// default address per address type.
+type UserAddress struct {
+	ID            string    `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	UserID        string    `json:"user_id" gorm:"type:uuid;not null;index"`
+	AddressType   string    `json:"address_type" gorm:"type:varchar(20);default:shipping"`
+	StreetAddress string    `json:"street_address" gorm:"type:varchar(255);not null"`
+	City          string    `json:"city" gorm:"type:varchar(100);not null"`
+	State         string    `json:"state" gorm:"type:varchar(100)"`
+	PostalCode    string    `json:"postal_code" gorm:"type:varchar(20)"`
+	Country       string    `json:"country" gorm:"type:varchar(100);not null"`
+	IsDefault     bool      `json:"is_default" gorm:"default:false"`
+	IsActive      bool      `json:"is_active" gorm:"index;default:true"`
+	CreatedAt     time.Time `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+	UpdatedAt     time.Time `json:"updated_at" gorm:"type:timestamp;autoUpdateTime"`
+}
+
+type CreateAddressRequest struct {
+	AddressType   string `json:"address_type" validate:"omitempty,oneof=shipping billing"`
+	StreetAddress string `json:"street_address" validate:"required,min=1,max=255"`
+	City          string `json:"city" validate:"required,min=1,max=100"`
+	State         string `json:"state" validate:"omitempty,max=100"`
+	PostalCode    string `json:"postal_code" validate:"omitempty,max=20"`
+	Country       string `json:"country" validate:"required,min=1,max=100"`
+	IsDefault     bool   `json:"is_default"`
+}
+
+type UpdateAddressRequest struct {
+	AddressType   *string `json:"address_type,omitempty" validate:"omitempty,oneof=shipping billing"`
+	StreetAddress *string `json:"street_address,omitempty" validate:"omitempty,min=1,max=255"`
+	City          *string `json:"city,omitempty" validate:"omitempty,min=1,max=100"`
+	State         *string `json:"state,omitempty" validate:"omitempty,max=100"`
+	PostalCode    *string `json:"postal_code,omitempty" validate:"omitempty,max=20"`
+	Country       *string `json:"country,omitempty" validate:"omitempty,min=1,max=100"`
+	IsDefault     *bool   `json:"is_default,omitempty"`
+}
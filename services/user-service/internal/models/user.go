@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+)
+
+type User struct {
+	ID            string     `json:"id" gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
+	Email         string     `json:"email" gorm:"type:varchar(255);uniqueIndex;not null"`
+	PasswordHash  string     `json:"-" gorm:"type:varchar(255);not null"`
+	FirstName     string     `json:"first_name" gorm:"type:varchar(100)"`
+	LastName      string     `json:"last_name" gorm:"type:varchar(100)"`
+	Phone         string     `json:"phone" gorm:"type:varchar(20)"`
+	DateOfBirth   *time.Time `json:"date_of_birth" gorm:"type:date"`
+	Role          string     `json:"role" gorm:"type:varchar(20);not null;default:customer"`
+	IsActive      bool       `json:"is_active" gorm:"index;default:true"`
+	EmailVerified bool       `json:"email_verified" gorm:"default:false"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"type:timestamp;autoCreateTime"`
+	UpdatedAt     time.Time  `json:"updated_at" gorm:"type:timestamp;autoUpdateTime"`
+}
+
+type SignupRequest struct {
+	Email       string  `json:"email" validate:"required,email,max=255"`
+	Password    string  `json:"password" validate:"required,min=8,max=72"` // bcrypt ignores bytes past 72
+	FirstName   string  `json:"first_name" validate:"omitempty,max=100"`
+	LastName    string  `json:"last_name" validate:"omitempty,max=100"`
+	Phone       string  `json:"phone" validate:"omitempty,max=20"`
+	DateOfBirth *string `json:"date_of_birth" validate:"omitempty,datetime=2006-01-02"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type UpdateUserRequest struct {
+	FirstName   *string `json:"first_name,omitempty" validate:"omitempty,max=100"`
+	LastName    *string `json:"last_name,omitempty" validate:"omitempty,max=100"`
+	Phone       *string `json:"phone,omitempty" validate:"omitempty,max=20"`
+	DateOfBirth *string `json:"date_of_birth,omitempty" validate:"omitempty,datetime=2006-01-02"`
+}
+
+type AuthResponse struct {
+	User      *User     `json:"user"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"shared/apperr"
+	"shared/db"
+	"user-service/internal/models"
+	"user-service/schema"
+
+	"github.com/google/uuid"
+)
+
+// emailConstraints are the unique constraints on email, as the migrations
+// and GORM name them
+var emailConstraints = []string{"users_email_key", "idx_users_email"}
+
+type UserRepository interface {
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUser(ctx context.Context, id string) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	UpdateUser(ctx context.Context, id string, updates map[string]interface{}) (*models.User, error)
+	DeactivateUser(ctx context.Context, id string) error
+	ListAddresses(ctx context.Context, userID string) ([]models.UserAddress, error)
+	GetAddress(ctx context.Context, userID string, id string) (*models.UserAddress, error)
+	CreateAddress(ctx context.Context, address *models.UserAddress) error
+	UpdateAddress(ctx context.Context, userID string, id string, updates map[string]interface{}) (*models.UserAddress, error)
+	DeactivateAddress(ctx context.Context, userID string, id string) error
+}
+
+type PostgresRepository struct {
+	*db.BaseRepository
+	users *db.Repository[models.User]
+}
+
+func NewPostgresRepository() (*PostgresRepository, error) {
+	database, err := db.NewPostgresConnectionFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	// Migrate or verify the schema, as DB_SCHEMA_MODE says
+	err = db.PrepareSchema(database, schema.Models()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare database schema: %w", err)
+	}
+
+	return &PostgresRepository{
+		BaseRepository: db.NewBaseRepository(database),
+		users:          db.NewRepository[models.User](database),
+	}, nil
+}
+
+func (r *PostgresRepository) CreateUser(ctx context.Context, user *models.User) error {
+	// Deactivated accounts keep their email
+	exists, err := r.users.Unscoped().Exists(ctx, db.NewSpec().Where(db.Eq("email", user.Email)))
+	if err != nil {
+		return err
+	}
+	if exists {
+		return apperr.Conflict("email already registered")
+	}
+
+	user.ID = uuid.New().String()
+	user.IsActive = true
+
+	result := r.DB.WithContext(ctx).Create(user)
+	if result.Error != nil {
+		// Two signups racing for the same email both pass the check above
+		if db.IsUniqueViolation(result.Error, emailConstraints...) {
+			return apperr.Conflict("email already registered")
+		}
+		return fmt.Errorf("failed to create user: %w", result.Error)
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) GetUser(ctx context.Context, id string) (*models.User, error) {
+	return r.users.Get(ctx, id)
+}
+
+func (r *PostgresRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.users.First(ctx, db.NewSpec().Where(db.Eq("email", email)))
+}
+
+func (r *PostgresRepository) UpdateUser(ctx context.Context, id string, updates map[string]interface{}) (*models.User, error) {
+	if len(updates) == 0 {
+		return nil, apperr.Validation("no fields to update")
+	}
+
+	result := r.DB.WithContext(ctx).Model(&models.User{}).Where("id = ? AND is_active = ?", id, true).Updates(updates)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update user: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, apperr.NotFound("user not found")
+	}
+
+	return r.GetUser(ctx, id)
+}
+
+func (r *PostgresRepository) DeactivateUser(ctx context.Context, id string) error {
+	result := r.DB.WithContext(ctx).Model(&models.User{}).Where("id = ? AND is_active = ?", id, true).Update("is_active", false)
+	if result.Error != nil {
+		return fmt.Errorf("failed to deactivate user: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return apperr.NotFound("user not found")
+	}
+
+	return nil
+}
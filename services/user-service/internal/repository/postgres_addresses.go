@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"shared/apperr"
+	"user-service/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Orders in these statuses no longer need their addresses
+var closedOrderStatuses = []string{"delivered", "cancelled"}
+
+func (r *PostgresRepository) ListAddresses(ctx context.Context, userID string) ([]models.UserAddress, error) {
+	var addresses []models.UserAddress
+
+	result := r.DB.WithContext(ctx).Where("user_id = ? AND is_active = ?", userID, true).
+		Order("address_type").
+		Order("is_default DESC").
+		Order("created_at DESC").
+		Find(&addresses)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list addresses: %w", result.Error)
+	}
+
+	return addresses, nil
+}
+
+func (r *PostgresRepository) GetAddress(ctx context.Context, userID string, id string) (*models.UserAddress, error) {
+	return getAddress(r.DB.WithContext(ctx), userID, id)
+}
+
+// CreateAddress adds an address to the user's book. The first address of a
+// type becomes its default.
+func (r *PostgresRepository) CreateAddress(ctx context.Context, address *models.UserAddress) error {
+	address.ID = uuid.New().String()
+	address.IsActive = true
+
+	return r.Transaction(ctx, func(tx *gorm.DB) error {
+		if err := lockAddressBook(tx, address.UserID); err != nil {
+			return err
+		}
+
+		var count int64
+		err := tx.Model(&models.UserAddress{}).
+			Where("user_id = ? AND address_type = ? AND is_active = ?", address.UserID, address.AddressType, true).
+			Count(&count).Error
+		if err != nil {
+			return fmt.Errorf("failed to create address: %w", err)
+		}
+
+		if count == 0 {
+			address.IsDefault = true
+		}
+		if address.IsDefault {
+			if err := clearDefault(tx, address.UserID, address.AddressType, address.ID); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Create(address).Error; err != nil {
+			return fmt.Errorf("failed to create address: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (r *PostgresRepository) UpdateAddress(ctx context.Context, userID string, id string, updates map[string]interface{}) (*models.UserAddress, error) {
+	if len(updates) == 0 {
+		return nil, apperr.Validation("no fields to update")
+	}
+
+	var address *models.UserAddress
+
+	err := r.Transaction(ctx, func(tx *gorm.DB) error {
+		if err := lockAddressBook(tx, userID); err != nil {
+			return err
+		}
+
+		current, err := getAddress(tx, userID, id)
+		if err != nil {
+			return err
+		}
+
+		addressType := current.AddressType
+		if value, ok := updates["address_type"].(string); ok {
+			addressType = value
+		}
+		isDefault := current.IsDefault
+		if value, ok := updates["is_default"].(bool); ok {
+			isDefault = value
+		}
+
+		// The one-default index is checked per statement, so the previous
+		// default has to go before this address takes its place
+		if isDefault {
+			if err := clearDefault(tx, userID, addressType, id); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(current).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update address: %w", err)
+		}
+
+		// Moving the address or unsetting its default must not leave its
+		// previous type without one
+		if current.IsDefault && (!isDefault || addressType != current.AddressType) {
+			if err := promoteDefault(tx, userID, current.AddressType, id); err != nil {
+				return err
+			}
+		}
+
+		address, err = getAddress(tx, userID, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return address, nil
+}
+
+// DeactivateAddress removes an address from the user's book. The row is kept
+// because past orders still reference it.
+func (r *PostgresRepository) DeactivateAddress(ctx context.Context, userID string, id string) error {
+	return r.Transaction(ctx, func(tx *gorm.DB) error {
+		if err := lockAddressBook(tx, userID); err != nil {
+			return err
+		}
+
+		address, err := getAddress(tx, userID, id)
+		if err != nil {
+			return err
+		}
+
+		var openOrders int64
+		err = tx.Table("orders").
+			Where("(shipping_address_id = ? OR billing_address_id = ?) AND status NOT IN ?", id, id, closedOrderStatuses).
+			Count(&openOrders).Error
+		if err != nil {
+			return fmt.Errorf("failed to check orders for address: %w", err)
+		}
+		if openOrders > 0 {
+			return apperr.Conflict("address is in use: %d open orders deliver to address %s", openOrders, id)
+		}
+
+		var subscriptions int64
+		err = tx.Table("subscriptions").
+			Where("shipping_address_id = ? AND status <> ?", id, "cancelled").
+			Count(&subscriptions).Error
+		if err != nil {
+			return fmt.Errorf("failed to check subscriptions for address: %w", err)
+		}
+		if subscriptions > 0 {
+			return apperr.Conflict("address is in use: %d subscriptions deliver to address %s", subscriptions, id)
+		}
+
+		result := tx.Model(address).Updates(map[string]interface{}{"is_active": false, "is_default": false})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete address: %w", result.Error)
+		}
+
+		if address.IsDefault {
+			return promoteDefault(tx, userID, address.AddressType, id)
+		}
+
+		return nil
+	})
+}
+
+func getAddress(tx *gorm.DB, userID string, id string) (*models.UserAddress, error) {
+	var address models.UserAddress
+	result := tx.Where("id = ? AND user_id = ? AND is_active = ?", id, userID, true).First(&address)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, apperr.NotFound("address not found")
+	}
+
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get address: %w", result.Error)
+	}
+
+	return &address, nil
+}
+
+// lockAddressBook serializes address book writes for a user by locking the
+// user's row, so two requests cannot both install a default
+func lockAddressBook(tx *gorm.DB, userID string) error {
+	var user models.User
+	result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		Where("id = ? AND is_active = ?", userID, true).
+		First(&user)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return apperr.NotFound("user not found")
+	}
+
+	if result.Error != nil {
+		return fmt.Errorf("failed to lock address book: %w", result.Error)
+	}
+
+	return nil
+}
+
+// clearDefault unsets the default of an address type, except for keepID
+func clearDefault(tx *gorm.DB, userID string, addressType string, keepID string) error {
+	result := tx.Model(&models.UserAddress{}).
+		Where("user_id = ? AND address_type = ? AND is_default = ? AND id <> ?", userID, addressType, true, keepID).
+		Update("is_default", false)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update default address: %w", result.Error)
+	}
+
+	return nil
+}
+
+// promoteDefault makes the most recent address of a type, other than
+// excludeID, its default when none of them is the default
+func promoteDefault(tx *gorm.DB, userID string, addressType string, excludeID string) error {
+	var addresses []models.UserAddress
+	result := tx.Where("user_id = ? AND address_type = ? AND is_active = ? AND id <> ?", userID, addressType, true, excludeID).
+		Order("is_default DESC").
+		Order("created_at DESC").
+		Limit(1).
+		Find(&addresses)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update default address: %w", result.Error)
+	}
+
+	if len(addresses) == 0 || addresses[0].IsDefault {
+		return nil
+	}
+
+	if err := tx.Model(&addresses[0]).Update("is_default", true).Error; err != nil {
+		return fmt.Errorf("failed to update default address: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"shared/apperr"
+	"user-service/internal/models"
+)
+
+func (s *UserService) ListAddresses(ctx context.Context, callerID string, userID string) ([]models.UserAddress, error) {
+	if err := authorize(callerID, userID); err != nil {
+		return nil, err
+	}
+
+	addresses, err := s.repo.ListAddresses(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list addresses: %w", err)
+	}
+
+	return addresses, nil
+}
+
+func (s *UserService) GetAddress(ctx context.Context, callerID string, userID string, id string) (*models.UserAddress, error) {
+	if err := authorize(callerID, userID); err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, apperr.InvalidField("id", "address ID is required")
+	}
+
+	address, err := s.repo.GetAddress(ctx, userID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get address: %w", err)
+	}
+
+	return address, nil
+}
+
+// CreateAddress adds an address to the user's book. Marking it as default
+// replaces the previous default of its type.
+func (s *UserService) CreateAddress(ctx context.Context, callerID string, userID string, request *models.CreateAddressRequest) (*models.UserAddress, error) {
+	if err := authorize(callerID, userID); err != nil {
+		return nil, err
+	}
+
+	address := &models.UserAddress{
+		UserID:        userID,
+		AddressType:   request.AddressType,
+		StreetAddress: strings.TrimSpace(request.StreetAddress),
+		City:          strings.TrimSpace(request.City),
+		State:         strings.TrimSpace(request.State),
+		PostalCode:    strings.TrimSpace(request.PostalCode),
+		Country:       strings.TrimSpace(request.Country),
+		IsDefault:     request.IsDefault,
+	}
+	if address.AddressType == "" {
+		address.AddressType = models.AddressTypeShipping
+	}
+
+	if err := validateAddress(address); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateAddress(ctx, address); err != nil {
+		return nil, fmt.Errorf("failed to create address: %w", err)
+	}
+
+	return address, nil
+}
+
+func (s *UserService) UpdateAddress(ctx context.Context, callerID string, userID string, id string, request *models.UpdateAddressRequest) (*models.UserAddress, error) {
+	if err := authorize(callerID, userID); err != nil {
+		return nil, err
+	}
+	if id == "" {
+		return nil, apperr.InvalidField("id", "address ID is required")
+	}
+
+	// Apply updates
+	updateFields := make(map[string]interface{})
+	required := []struct {
+		column string
+		value  *string
+	}{
+		{"street_address", request.StreetAddress},
+		{"city", request.City},
+		{"country", request.Country},
+	}
+
+	for _, field := range required {
+		if field.value == nil {
+			continue
+		}
+		trimmed := strings.TrimSpace(*field.value)
+		if trimmed == "" {
+			return nil, apperr.InvalidField(field.column, fmt.Sprintf("invalid address: %s is required", field.column))
+		}
+		updateFields[field.column] = trimmed
+	}
+
+	if request.AddressType != nil {
+		updateFields["address_type"] = *request.AddressType
+	}
+	if request.State != nil {
+		updateFields["state"] = strings.TrimSpace(*request.State)
+	}
+	if request.PostalCode != nil {
+		updateFields["postal_code"] = strings.TrimSpace(*request.PostalCode)
+	}
+	if request.IsDefault != nil {
+		updateFields["is_default"] = *request.IsDefault
+	}
+
+	address, err := s.repo.UpdateAddress(ctx, userID, id, updateFields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update address: %w", err)
+	}
+
+	return address, nil
+}
+
+// SetDefaultAddress makes an address the default of its type
+func (s *UserService) SetDefaultAddress(ctx context.Context, callerID string, userID string, id string) (*models.UserAddress, error) {
+	isDefault := true
+	return s.UpdateAddress(ctx, callerID, userID, id, &models.UpdateAddressRequest{IsDefault: &isDefault})
+}
+
+// DeleteAddress removes an address from the user's book unless an open order
+// or active subscription still delivers to it. If it was the default, the
+// most recent remaining address of its type takes over.
+func (s *UserService) DeleteAddress(ctx context.Context, callerID string, userID string, id string) error {
+	if err := authorize(callerID, userID); err != nil {
+		return err
+	}
+	if id == "" {
+		return apperr.InvalidField("id", "address ID is required")
+	}
+
+	if err := s.repo.DeactivateAddress(ctx, userID, id); err != nil {
+		return fmt.Errorf("failed to delete address: %w", err)
+	}
+
+	return nil
+}
+
+func validateAddress(address *models.UserAddress) error {
+	switch {
+	case address.StreetAddress == "":
+		return apperr.InvalidField("street_address", "invalid address: street_address is required")
+	case address.City == "":
+		return apperr.InvalidField("city", "invalid address: city is required")
+	case address.Country == "":
+		return apperr.InvalidField("country", "invalid address: country is required")
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"shared/apperr"
+	"shared/auth"
+	"user-service/internal/models"
+	"user-service/internal/repository"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidCredentials is returned for any failed login, without telling
+// whether the email exists
+var ErrInvalidCredentials = apperr.Unauthorized("invalid email or password")
+
+// ErrForbidden is returned when a token's user may not act on another user
+var ErrForbidden = apperr.Forbidden("not allowed to access this user")
+
+const passwordHashCost = 12
+
+// dummyPasswordHash is compared against when no user matches a login so the
+// response takes as long as a real password check
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), passwordHashCost)
+
+type UserService struct {
+	repo     repository.UserRepository
+	signer   *auth.Signer
+	verifier *auth.Verifier
+}
+
+func NewUserService(repo repository.UserRepository, signer *auth.Signer, verifier *auth.Verifier) *UserService {
+	return &UserService{
+		repo:     repo,
+		signer:   signer,
+		verifier: verifier,
+	}
+}
+
+func (s *UserService) Signup(ctx context.Context, request *models.SignupRequest) (*models.AuthResponse, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(request.Password), passwordHashCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := &models.User{
+		Email:        normalizeEmail(request.Email),
+		PasswordHash: string(hash),
+		FirstName:    strings.TrimSpace(request.FirstName),
+		LastName:     strings.TrimSpace(request.LastName),
+		Phone:        strings.TrimSpace(request.Phone),
+		Role:         auth.RoleCustomer,
+	}
+
+	if request.DateOfBirth != nil {
+		dateOfBirth, err := time.Parse("2006-01-02", *request.DateOfBirth)
+		if err != nil {
+			return nil, apperr.InvalidField("date_of_birth", "invalid date of birth, expected YYYY-MM-DD")
+		}
+		user.DateOfBirth = &dateOfBirth
+	}
+
+	if err := s.repo.CreateUser(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to sign up: %w", err)
+	}
+
+	return s.authResponse(user)
+}
+
+func (s *UserService) Login(ctx context.Context, request *models.LoginRequest) (*models.AuthResponse, error) {
+	user, err := s.repo.GetUserByEmail(ctx, normalizeEmail(request.Email))
+	if err != nil {
+		if !errors.Is(err, apperr.ErrNotFound) {
+			return nil, fmt.Errorf("failed to log in: %w", err)
+		}
+		_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(request.Password))
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(request.Password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return s.authResponse(user)
+}
+
+// Authenticate verifies a bearer token and returns the ID of its user
+func (s *UserService) Authenticate(token string) (string, error) {
+	claims, err := s.verifier.Verify(token)
+	if err != nil {
+		return "", apperr.Unauthorized("%v", err)
+	}
+	return claims.Subject, nil
+}
+
+func (s *UserService) GetUser(ctx context.Context, callerID string, id string) (*models.User, error) {
+	if err := authorize(callerID, id); err != nil {
+		return nil, err
+	}
+
+	user, err := s.repo.GetUser(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (s *UserService) UpdateUser(ctx context.Context, callerID string, id string, request *models.UpdateUserRequest) (*models.User, error) {
+	if err := authorize(callerID, id); err != nil {
+		return nil, err
+	}
+
+	// Apply updates
+	updateFields := make(map[string]interface{})
+
+	if request.FirstName != nil {
+		updateFields["first_name"] = strings.TrimSpace(*request.FirstName)
+	}
+	if request.LastName != nil {
+		updateFields["last_name"] = strings.TrimSpace(*request.LastName)
+	}
+	if request.Phone != nil {
+		updateFields["phone"] = strings.TrimSpace(*request.Phone)
+	}
+	if request.DateOfBirth != nil {
+		// An empty date clears it
+		if *request.DateOfBirth == "" {
+			updateFields["date_of_birth"] = nil
+		} else {
+			dateOfBirth, err := time.Parse("2006-01-02", *request.DateOfBirth)
+			if err != nil {
+				return nil, apperr.InvalidField("date_of_birth", "invalid date of birth, expected YYYY-MM-DD")
+			}
+			updateFields["date_of_birth"] = dateOfBirth
+		}
+	}
+
+	user, err := s.repo.UpdateUser(ctx, id, updateFields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return user, nil
+}
+
+// DeactivateUser soft deletes the user; tokens already issued stay valid
+// until they expire but the user can no longer log in or be loaded
+func (s *UserService) DeactivateUser(ctx context.Context, callerID string, id string) error {
+	if err := authorize(callerID, id); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeactivateUser(ctx, id); err != nil {
+		return fmt.Errorf("failed to deactivate user: %w", err)
+	}
+
+	return nil
+}
+
+func (s *UserService) authResponse(user *models.User) (*models.AuthResponse, error) {
+	token, expiresAt, err := s.signer.Sign(user.ID, user.Email, user.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthResponse{
+		User:      user,
+		Token:     token,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// authorize only lets users act on themselves
+func authorize(callerID string, id string) error {
+	if id == "" {
+		return apperr.InvalidField("id", "user ID is required")
+	}
+	if callerID != id {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,13 @@
+// Package schema lists the models user-service keeps in Postgres, for the
+// startup schema check and the schema diff of tools/migrate
+package schema
+
+import "user-service/internal/models"
+
+// Models returns a model of every table the service reads and writes
+func Models() []interface{} {
+	return []interface{}{
+		&models.User{},
+		&models.UserAddress{},
+	}
+}
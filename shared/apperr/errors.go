@@ -0,0 +1,139 @@
+// Package apperr holds the domain errors services share, so that handlers
+// decide HTTP statuses from what an error is rather than from its text
+package apperr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Code identifies a kind of error in API responses. Codes are part of the
+// API: clients match on them, so existing codes must not change.
+type Code string
+
+const (
+	CodeNotFound          Code = "not_found"
+	CodeUnauthorized      Code = "unauthorized"
+	CodeForbidden         Code = "forbidden"
+	CodeConflict          Code = "conflict"
+	CodeValidation        Code = "validation_failed"
+	CodeInsufficientStock Code = "insufficient_stock"
+	CodeUnavailable       Code = "unavailable"
+	CodeTimeout           Code = "timeout"
+	CodeInternal          Code = "internal"
+)
+
+// Sentinels to match with errors.Is. Any Error with the same code matches
+// them; other package-level Errors match only themselves.
+var (
+	ErrNotFound          = &Error{Code: CodeNotFound, Message: "not found"}
+	ErrUnauthorized      = &Error{Code: CodeUnauthorized, Message: "unauthorized"}
+	ErrForbidden         = &Error{Code: CodeForbidden, Message: "forbidden"}
+	ErrConflict          = &Error{Code: CodeConflict, Message: "conflict"}
+	ErrValidation        = &Error{Code: CodeValidation, Message: "validation failed"}
+	ErrInsufficientStock = &Error{Code: CodeInsufficientStock, Message: "insufficient stock"}
+	ErrUnavailable       = &Error{Code: CodeUnavailable, Message: "service unavailable"}
+)
+
+// FieldError describes why one request field is invalid
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Error is a domain error. Message is safe to show to API clients; Err is the
+// cause, kept for logs and errors.Is.
+type Error struct {
+	Code    Code
+	Message string
+	Fields  []FieldError
+	Err     error
+}
+
+func (e *Error) Error() string {
+	message := e.Message
+	if len(e.Fields) > 0 {
+		details := make([]string, len(e.Fields))
+		for i, field := range e.Fields {
+			details[i] = field.Field + ": " + field.Message
+		}
+		message += " (" + strings.Join(details, ", ") + ")"
+	}
+	if e.Err != nil {
+		return message + ": " + e.Err.Error()
+	}
+	return message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+var sentinels = map[Code]*Error{
+	CodeNotFound:          ErrNotFound,
+	CodeUnauthorized:      ErrUnauthorized,
+	CodeForbidden:         ErrForbidden,
+	CodeConflict:          ErrConflict,
+	CodeValidation:        ErrValidation,
+	CodeInsufficientStock: ErrInsufficientStock,
+	CodeUnavailable:       ErrUnavailable,
+}
+
+// Is matches the sentinel of the same code, so errors.Is(err, ErrNotFound)
+// holds for every not found error
+func (e *Error) Is(target error) bool {
+	sentinel, ok := sentinels[e.Code]
+	return ok && target == error(sentinel)
+}
+
+// NotFound reports a missing resource, e.g. NotFound("product not found")
+func NotFound(format string, args ...interface{}) *Error {
+	return &Error{Code: CodeNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+// Unauthorized reports a request without valid credentials for the caller
+func Unauthorized(format string, args ...interface{}) *Error {
+	return &Error{Code: CodeUnauthorized, Message: fmt.Sprintf(format, args...)}
+}
+
+// Forbidden reports a caller whose credentials do not allow the request
+func Forbidden(format string, args ...interface{}) *Error {
+	return &Error{Code: CodeForbidden, Message: fmt.Sprintf(format, args...)}
+}
+
+// Conflict reports a request that clashes with the current state, like a
+// duplicate slug or a reservation that is no longer active
+func Conflict(format string, args ...interface{}) *Error {
+	return &Error{Code: CodeConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+// Validation reports an invalid request, with the offending fields if known
+func Validation(message string, fields ...FieldError) *Error {
+	return &Error{Code: CodeValidation, Message: message, Fields: fields}
+}
+
+// InvalidField reports a single invalid field
+func InvalidField(field, message string) *Error {
+	return Validation(message, FieldError{Field: field, Message: message})
+}
+
+// InsufficientStock reports a stock change that would overdraw a product
+func InsufficientStock(format string, args ...interface{}) *Error {
+	return &Error{Code: CodeInsufficientStock, Message: fmt.Sprintf(format, args...)}
+}
+
+// Unavailable reports a dependency that cannot serve the request right now
+func Unavailable(message string, err error) *Error {
+	return &Error{Code: CodeUnavailable, Message: message, Err: err}
+}
+
+// FromContext returns err as the error of ctx when ctx has ended, for
+// clients like the DynamoDB SDK that do not wrap context errors
+func FromContext(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil || errors.Is(err, ctx.Err()) {
+		return err
+	}
+	return fmt.Errorf("%w: %v", ctx.Err(), err)
+}
@@ -0,0 +1,3 @@
+module shared/apperr
+
+go 1.21
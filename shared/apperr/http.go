@@ -0,0 +1,49 @@
+package apperr
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+)
+
+// Body is the JSON body of every error response
+type Body struct {
+	Error  string       `json:"error"`
+	Code   Code         `json:"code"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// statuses maps codes to HTTP statuses. Insufficient stock stays a 400, which
+// clients relied on before codes existed.
+var statuses = map[Code]int{
+	CodeNotFound:          http.StatusNotFound,
+	CodeUnauthorized:      http.StatusUnauthorized,
+	CodeForbidden:         http.StatusForbidden,
+	CodeConflict:          http.StatusConflict,
+	CodeValidation:        http.StatusBadRequest,
+	CodeInsufficientStock: http.StatusBadRequest,
+	CodeUnavailable:       http.StatusServiceUnavailable,
+	CodeTimeout:           http.StatusGatewayTimeout,
+	CodeInternal:          http.StatusInternalServerError,
+}
+
+// HTTPResponse maps an error to its HTTP status and response body. Context
+// errors map to 504 when the deadline passed and 503 when cancelled; errors
+// that are not domain errors are internal. Their text may hold queries or
+// hostnames, so it is logged rather than sent to the client.
+func HTTPResponse(err error) (int, Body) {
+	var appErr *Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		appErr = &Error{Code: CodeTimeout, Message: "request timed out"}
+	case errors.Is(err, context.Canceled):
+		appErr = &Error{Code: CodeUnavailable, Message: "request was cancelled"}
+	case errors.As(err, &appErr):
+	default:
+		log.Printf("internal error: %v", err)
+		appErr = &Error{Code: CodeInternal, Message: "internal server error"}
+	}
+
+	return statuses[appErr.Code], Body{Error: appErr.Message, Code: appErr.Code, Fields: appErr.Fields}
+}
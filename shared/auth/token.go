@@ -0,0 +1,259 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Tokens are compact JWTs signed with Ed25519 ("EdDSA"). user-service holds
+// the private key and issues them; any other service can verify them with
+// only the public key, so verifying services cannot mint tokens.
+//
+// Services calling each other sign their own short-lived tokens with a key of
+// their own, issued as the service with the service role. The called service
+// verifies them with the caller's public key, so user tokens never stand in
+// for a service and a service key cannot mint user tokens.
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+)
+
+const (
+	tokenIssuer     = "user-service"
+	defaultTokenTTL = time.Hour
+
+	// serviceTokenTTL bounds the tokens services sign for each call
+	serviceTokenTTL = 5 * time.Minute
+)
+
+// Roles carried by tokens. Customers are the default; staff and admins are
+// granted in the users table, and only service tokens carry the service role.
+const (
+	RoleCustomer = "customer"
+	RoleStaff    = "staff"
+	RoleAdmin    = "admin"
+	RoleService  = "service"
+)
+
+// tokenHeader is the fixed, pre-encoded JWT header for every token
+var tokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"EdDSA","typ":"JWT"}`))
+
+type Claims struct {
+	Subject   string `json:"sub"`
+	Email     string `json:"email,omitempty"`
+	Role      string `json:"role"`
+	Issuer    string `json:"iss"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// HasRole reports whether the claims carry one of roles
+func (c *Claims) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if c.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+type Signer struct {
+	key    ed25519.PrivateKey
+	ttl    time.Duration
+	issuer string
+}
+
+// NewSigner creates a signer from a 32-byte Ed25519 seed
+func NewSigner(seed []byte, ttl time.Duration) (*Signer, error) {
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("signing key must be %d bytes, got %d", ed25519.SeedSize, len(seed))
+	}
+	if ttl <= 0 {
+		ttl = defaultTokenTTL
+	}
+
+	return &Signer{key: ed25519.NewKeyFromSeed(seed), ttl: ttl, issuer: tokenIssuer}, nil
+}
+
+// NewSignerFromEnv creates a signer from AUTH_SIGNING_KEY (base64 seed) and
+// the optional AUTH_TOKEN_TTL duration
+func NewSignerFromEnv() (*Signer, error) {
+	seed, err := base64.StdEncoding.DecodeString(os.Getenv("AUTH_SIGNING_KEY"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode AUTH_SIGNING_KEY: %w", err)
+	}
+
+	ttl := defaultTokenTTL
+	if value := os.Getenv("AUTH_TOKEN_TTL"); value != "" {
+		if ttl, err = time.ParseDuration(value); err != nil {
+			return nil, fmt.Errorf("failed to parse AUTH_TOKEN_TTL: %w", err)
+		}
+	}
+
+	return NewSigner(seed, ttl)
+}
+
+// NewServiceSigner creates the signer service calls other services with,
+// from a 32-byte Ed25519 seed of its own
+func NewServiceSigner(service string, seed []byte) (*Signer, error) {
+	signer, err := NewSigner(seed, serviceTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	signer.issuer = service
+	return signer, nil
+}
+
+// NewServiceSignerFromEnv creates the signer of service from
+// SERVICE_SIGNING_KEY (base64 seed)
+func NewServiceSignerFromEnv(service string) (*Signer, error) {
+	seed, err := base64.StdEncoding.DecodeString(os.Getenv("SERVICE_SIGNING_KEY"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode SERVICE_SIGNING_KEY: %w", err)
+	}
+
+	return NewServiceSigner(service, seed)
+}
+
+// PublicKey returns the key verifiers need, base64 encoded for AUTH_PUBLIC_KEY
+// or, for a service signer, ServicePublicKeyEnv
+func (s *Signer) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey))
+}
+
+// Verifier returns a verifier for the tokens this signer issues
+func (s *Signer) Verifier() *Verifier {
+	return &Verifier{key: s.key.Public().(ed25519.PublicKey), issuer: s.issuer}
+}
+
+// Sign issues a token for the user with their role, returning it with its
+// expiry
+func (s *Signer) Sign(userID string, email string, role string) (string, time.Time, error) {
+	return s.sign(Claims{Subject: userID, Email: email, Role: role})
+}
+
+// SignService issues a token for a call the signer's service makes
+func (s *Signer) SignService() (string, error) {
+	token, _, err := s.sign(Claims{Subject: s.issuer, Role: RoleService})
+	return token, err
+}
+
+func (s *Signer) sign(claims Claims) (string, time.Time, error) {
+	now := time.Now().UTC()
+	expiresAt := now.Add(s.ttl)
+
+	claims.Issuer = s.issuer
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = expiresAt.Unix()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to encode token claims: %w", err)
+	}
+
+	signingInput := tokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature := ed25519.Sign(s.key, []byte(signingInput))
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), expiresAt, nil
+}
+
+type Verifier struct {
+	key    ed25519.PublicKey
+	issuer string
+}
+
+// NewVerifier creates a verifier from a 32-byte Ed25519 public key
+func NewVerifier(publicKey []byte) (*Verifier, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key must be %d bytes, got %d", ed25519.PublicKeySize, len(publicKey))
+	}
+
+	return &Verifier{key: ed25519.PublicKey(publicKey), issuer: tokenIssuer}, nil
+}
+
+// NewVerifierFromEnv creates a verifier from AUTH_PUBLIC_KEY (base64)
+func NewVerifierFromEnv() (*Verifier, error) {
+	publicKey, err := base64.StdEncoding.DecodeString(os.Getenv("AUTH_PUBLIC_KEY"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode AUTH_PUBLIC_KEY: %w", err)
+	}
+
+	return NewVerifier(publicKey)
+}
+
+// ServicePublicKeyEnv names the variable holding the public key of service,
+// e.g. ORDER_SERVICE_PUBLIC_KEY for order-service
+func ServicePublicKeyEnv(service string) string {
+	return strings.ToUpper(strings.ReplaceAll(service, "-", "_")) + "_PUBLIC_KEY"
+}
+
+// NewServiceVerifierFromEnv creates a verifier for the tokens service signs
+// its calls with, from the base64 key in ServicePublicKeyEnv(service). It
+// accepts only service tokens issued as service.
+func NewServiceVerifierFromEnv(service string) (*Verifier, error) {
+	variable := ServicePublicKeyEnv(service)
+	publicKey, err := base64.StdEncoding.DecodeString(os.Getenv(variable))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", variable, err)
+	}
+
+	verifier, err := NewVerifier(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	verifier.issuer = service
+	return verifier, nil
+}
+
+// Verify checks the token's signature, issuer and expiry and returns its claims
+func (v *Verifier) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != tokenHeader {
+		return nil, ErrInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !ed25519.Verify(v.key, []byte(parts[0]+"."+parts[1]), signature) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Issuer != v.issuer || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	// Only a service's own key issues service tokens
+	if (claims.Role == RoleService) != (v.issuer != tokenIssuer) {
+		return nil, ErrInvalidToken
+	}
+
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+
+	return &claims, nil
+}
+
+// BearerToken extracts the token from an Authorization header in a map of
+// request headers, matching the header name case-insensitively
+func BearerToken(headers map[string]string) string {
+	for name, value := range headers {
+		if strings.EqualFold(name, "Authorization") {
+			token := strings.TrimSpace(value)
+			if len(token) > 7 && strings.EqualFold(token[:7], "Bearer ") {
+				return strings.TrimSpace(token[7:])
+			}
+		}
+	}
+	return ""
+}
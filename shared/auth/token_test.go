@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSigner(t *testing.T, service string, fill string) *Signer {
+	t.Helper()
+	seed := []byte(strings.Repeat(fill, 32))
+
+	var signer *Signer
+	var err error
+	if service == "" {
+		signer, err = NewSigner(seed, time.Hour)
+	} else {
+		signer, err = NewServiceSigner(service, seed)
+	}
+	if err != nil {
+		t.Fatalf("failed to create signer: %v", err)
+	}
+	return signer
+}
+
+func TestVerifyRoles(t *testing.T) {
+	users := newTestSigner(t, "", "u")
+	orders := newTestSigner(t, "order-service", "o")
+
+	token, _, err := users.Sign("user-1", "staff@example.com", RoleStaff)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	claims, err := users.Verifier().Verify(token)
+	if err != nil {
+		t.Fatalf("failed to verify a user token: %v", err)
+	}
+	if !claims.HasRole(RoleStaff, RoleAdmin) || claims.HasRole(RoleService) {
+		t.Errorf("user token has role %q, want %q", claims.Role, RoleStaff)
+	}
+
+	serviceToken, err := orders.SignService()
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	claims, err = orders.Verifier().Verify(serviceToken)
+	if err != nil {
+		t.Fatalf("failed to verify a service token: %v", err)
+	}
+	if claims.Subject != "order-service" || !claims.HasRole(RoleService) {
+		t.Errorf("service token is %s with role %q, want order-service with role %q", claims.Subject, claims.Role, RoleService)
+	}
+
+	cases := []struct {
+		name     string
+		verifier *Verifier
+		token    string
+	}{
+		{"service token to the user verifier", users.Verifier(), serviceToken},
+		{"user token to the service verifier", orders.Verifier(), token},
+		{"service role from the user key", users.Verifier(), mustSign(t, users, "order-service", RoleService)},
+		{"user role from the service key", orders.Verifier(), mustSign(t, orders, "user-1", RoleAdmin)},
+	}
+	for _, c := range cases {
+		if _, err := c.verifier.Verify(c.token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: verified with %v, want %v", c.name, err, ErrInvalidToken)
+		}
+	}
+}
+
+func mustSign(t *testing.T, signer *Signer, subject, role string) string {
+	t.Helper()
+	token, _, err := signer.Sign(subject, "", role)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	return token
+}
@@ -1,9 +1,12 @@
 package db
 
 import (
+	"context"
 	"errors"
 	"fmt"
 
+	"shared/apperr"
+
 	"gorm.io/gorm"
 )
 
@@ -18,8 +21,8 @@ func NewBaseRepository(db *gorm.DB) *BaseRepository {
 }
 
 // Create inserts a new record
-func (r *BaseRepository) Create(model interface{}) error {
-	result := r.DB.Create(model)
+func (r *BaseRepository) Create(ctx context.Context, model interface{}) error {
+	result := r.DB.WithContext(ctx).Create(model)
 	if result.Error != nil {
 		return fmt.Errorf("failed to create record: %w", result.Error)
 	}
@@ -27,10 +30,10 @@ func (r *BaseRepository) Create(model interface{}) error {
 }
 
 // GetByID retrieves a record by ID
-func (r *BaseRepository) GetByID(model interface{}, id string) error {
-	result := r.DB.Where("id = ?", id).First(model)
+func (r *BaseRepository) GetByID(ctx context.Context, model interface{}, id string) error {
+	result := r.DB.WithContext(ctx).Where("id = ?", id).First(model)
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-		return errors.New("record not found")
+		return apperr.NotFound("record not found")
 	}
 	if result.Error != nil {
 		return fmt.Errorf("failed to get record: %w", result.Error)
@@ -39,32 +42,32 @@ func (r *BaseRepository) GetByID(model interface{}, id string) error {
 }
 
 // Update updates a record
-func (r *BaseRepository) Update(model interface{}, updates interface{}) error {
-	result := r.DB.Model(model).Updates(updates)
+func (r *BaseRepository) Update(ctx context.Context, model interface{}, updates interface{}) error {
+	result := r.DB.WithContext(ctx).Model(model).Updates(updates)
 	if result.Error != nil {
 		return fmt.Errorf("failed to update record: %w", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return errors.New("record not found")
+		return apperr.NotFound("record not found")
 	}
 	return nil
 }
 
 // Delete soft deletes a record by ID
-func (r *BaseRepository) Delete(model interface{}, id string) error {
-	result := r.DB.Where("id = ?", id).Delete(model)
+func (r *BaseRepository) Delete(ctx context.Context, model interface{}, id string) error {
+	result := r.DB.WithContext(ctx).Where("id = ?", id).Delete(model)
 	if result.Error != nil {
 		return fmt.Errorf("failed to delete record: %w", result.Error)
 	}
 	if result.RowsAffected == 0 {
-		return errors.New("record not found")
+		return apperr.NotFound("record not found")
 	}
 	return nil
 }
 
 // List retrieves records with optional filters
-func (r *BaseRepository) List(models interface{}, conditions ...interface{}) error {
-	query := r.DB
+func (r *BaseRepository) List(ctx context.Context, models interface{}, conditions ...interface{}) error {
+	query := r.DB.WithContext(ctx)
 	
 	if len(conditions) > 0 {
 		query = query.Where(conditions[0], conditions[1:]...)
@@ -78,9 +81,9 @@ func (r *BaseRepository) List(models interface{}, conditions ...interface{}) err
 }
 
 // Count returns the number of records matching the conditions
-func (r *BaseRepository) Count(model interface{}, conditions ...interface{}) (int64, error) {
+func (r *BaseRepository) Count(ctx context.Context, model interface{}, conditions ...interface{}) (int64, error) {
 	var count int64
-	query := r.DB.Model(model)
+	query := r.DB.WithContext(ctx).Model(model)
 	
 	if len(conditions) > 0 {
 		query = query.Where(conditions[0], conditions[1:]...)
@@ -94,9 +97,9 @@ func (r *BaseRepository) Count(model interface{}, conditions ...interface{}) (in
 }
 
 // Exists checks if a record exists with the given conditions
-func (r *BaseRepository) Exists(model interface{}, conditions ...interface{}) (bool, error) {
+func (r *BaseRepository) Exists(ctx context.Context, model interface{}, conditions ...interface{}) (bool, error) {
 	var count int64
-	query := r.DB.Model(model)
+	query := r.DB.WithContext(ctx).Model(model)
 	
 	if len(conditions) > 0 {
 		query = query.Where(conditions[0], conditions[1:]...)
@@ -109,7 +112,8 @@ func (r *BaseRepository) Exists(model interface{}, conditions ...interface{}) (b
 	return count > 0, nil
 }
 
-// Transaction executes a function within a database transaction
-func (r *BaseRepository) Transaction(fn func(*gorm.DB) error) error {
-	return r.DB.Transaction(fn)
+// Transaction executes a function within a database transaction. The
+// transaction handed to fn carries ctx.
+func (r *BaseRepository) Transaction(ctx context.Context, fn func(*gorm.DB) error) error {
+	return r.DB.WithContext(ctx).Transaction(fn)
 }
\ No newline at end of file
@@ -3,13 +3,11 @@ package db
 import (
 	"database/sql"
 	"fmt"
-	"io/ioutil"
-	"path/filepath"
 
 	_ "github.com/lib/pq"
 )
 
-// RunMigrations executes SQL migration files
+// RunMigrations applies every pending migration of the migrations directory
 func RunMigrations(dbURL string, migrationsPath string) error {
 	db, err := sql.Open("postgres", dbURL)
 	if err != nil {
@@ -22,17 +20,22 @@ func RunMigrations(dbURL string, migrationsPath string) error {
 		return fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	// Read migration file
-	migrationFile := filepath.Join(migrationsPath, "migrations.sql")
-	content, err := ioutil.ReadFile(migrationFile)
+	migrator, err := NewMigrator(db, migrationsPath)
 	if err != nil {
-		return fmt.Errorf("failed to read migration file: %w", err)
+		return err
 	}
 
-	// Execute migration
-	_, err = db.Exec(string(content))
+	applied, err := migrator.Up(0)
+	for _, migration := range applied {
+		fmt.Printf("⬆️  Applied %d_%s\n", migration.Version, migration.Name)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to execute migration: %w", err)
+		return err
+	}
+
+	if len(applied) == 0 {
+		fmt.Println("✅ Database is up to date")
+		return nil
 	}
 
 	fmt.Println("✅ Migrations executed successfully!")
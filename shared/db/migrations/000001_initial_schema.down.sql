@@ -0,0 +1,14 @@
+-- Drop the initial schema, dependents first
+DROP TABLE IF EXISTS subscription_runs;
+DROP TABLE IF EXISTS order_status_history;
+DROP TABLE IF EXISTS order_items;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS subscription_items;
+DROP TABLE IF EXISTS subscriptions;
+DROP TABLE IF EXISTS user_addresses;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS stock_movements;
+DROP TABLE IF EXISTS stock_reservations;
+DROP TABLE IF EXISTS products;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS departments;
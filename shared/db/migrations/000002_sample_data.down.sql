@@ -0,0 +1,16 @@
+-- Remove the sample catalog; fails if orders already reference it
+DELETE FROM stock_movements
+WHERE reference_id = 'opening-balance'
+  AND product_id IN (SELECT id FROM products WHERE sku LIKE 'PROD-%' AND brand = 'Sample Brand');
+
+DELETE FROM products WHERE sku LIKE 'PROD-%' AND brand = 'Sample Brand';
+
+DELETE FROM categories WHERE slug IN (
+    'fruits', 'vegetables', 'organic-produce', 'milk-cream', 'cheese',
+    'yogurt', 'beef', 'chicken', 'seafood'
+);
+
+DELETE FROM departments WHERE slug IN (
+    'produce', 'dairy', 'meat-seafood', 'bakery', 'pantry',
+    'frozen', 'beverages', 'health-beauty'
+);
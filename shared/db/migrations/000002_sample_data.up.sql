@@ -0,0 +1,50 @@
+-- Sample catalog for development and test environments
+
+-- Insert sample departments
+INSERT INTO departments (name, description, slug, icon) VALUES
+('Produce', 'Fresh fruits and vegetables', 'produce', '🥬'),
+('Dairy', 'Milk, cheese, yogurt and dairy products', 'dairy', '🥛'),
+('Meat & Seafood', 'Fresh meat, poultry and seafood', 'meat-seafood', '🥩'),
+('Bakery', 'Fresh bread, pastries and baked goods', 'bakery', '🍞'),
+('Pantry', 'Canned goods, grains, pasta and staples', 'pantry', '🥫'),
+('Frozen', 'Frozen foods and ice cream', 'frozen', '🧊'),
+('Beverages', 'Soft drinks, juices, water and beverages', 'beverages', '🥤'),
+('Health & Beauty', 'Personal care and health products', 'health-beauty', '🧴')
+ON CONFLICT (slug) DO NOTHING;
+
+-- Insert sample categories (level is maintained by product-service from parent_id; roots are level 0)
+INSERT INTO categories (name, slug, description) VALUES
+('Fruits', 'fruits', 'Fresh seasonal fruits'),
+('Vegetables', 'vegetables', 'Fresh vegetables and greens'),
+('Organic Produce', 'organic-produce', 'Certified organic fruits and vegetables'),
+('Milk & Cream', 'milk-cream', 'Various types of milk and cream'),
+('Cheese', 'cheese', 'Domestic and imported cheeses'),
+('Yogurt', 'yogurt', 'Greek, regular and specialty yogurts'),
+('Beef', 'beef', 'Fresh beef cuts'),
+('Chicken', 'chicken', 'Fresh chicken and poultry'),
+('Seafood', 'seafood', 'Fresh fish and seafood')
+ON CONFLICT (slug) DO NOTHING;
+
+-- Insert sample products
+INSERT INTO products (sku, slug, name, description, price, category_id, department_id, brand, stock, min_stock, unit)
+SELECT 
+    'PROD-' || LPAD(generate_series::text, 6, '0'),
+    'product-' || generate_series,
+    'Sample Product ' || generate_series,
+    'This is a sample product for testing purposes',
+    (random() * 50 + 5)::DECIMAL(10,2),
+    (SELECT id FROM categories ORDER BY random() LIMIT 1),
+    (SELECT id FROM departments ORDER BY random() LIMIT 1),
+    'Sample Brand',
+    (random() * 100 + 10)::INTEGER,
+    5,
+    'each'
+FROM generate_series(1, 20)
+ON CONFLICT (sku) DO NOTHING;
+
+-- Open the ledger of the sample products so their history sums to their stock
+INSERT INTO stock_movements (product_id, delta, reason, reference_id, actor)
+SELECT p.id, p.stock, 'adjustment', 'opening-balance', 'migration'
+FROM products p
+WHERE p.stock <> 0
+  AND NOT EXISTS (SELECT 1 FROM stock_movements m WHERE m.product_id = p.id);
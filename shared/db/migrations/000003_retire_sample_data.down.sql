@@ -0,0 +1 @@
+-- Sample data is seeded from fixtures now; nothing to restore
@@ -0,0 +1,14 @@
+-- Sample data moved to per-environment fixtures loaded by tools/migrate -action=seed.
+-- 000002 stays, unchanged, so databases that ran it keep a matching history;
+-- this removes the random sample products it inserted that nothing refers to yet.
+CREATE TEMPORARY TABLE retired_sample_products ON COMMIT DROP AS
+SELECT p.id
+FROM products p
+WHERE p.sku LIKE 'PROD-%'
+  AND p.brand = 'Sample Brand'
+  AND NOT EXISTS (SELECT 1 FROM order_items i WHERE i.product_id = p.id)
+  AND NOT EXISTS (SELECT 1 FROM subscription_items s WHERE s.product_id = p.id)
+  AND NOT EXISTS (SELECT 1 FROM stock_reservations r WHERE r.product_id = p.id);
+
+DELETE FROM stock_movements WHERE product_id IN (SELECT id FROM retired_sample_products);
+DELETE FROM products WHERE id IN (SELECT id FROM retired_sample_products);
@@ -0,0 +1,2 @@
+-- GIN index for tag filters: tags @> (all of) and tags && (any of)
+CREATE INDEX IF NOT EXISTS idx_products_tags ON products USING GIN (tags);
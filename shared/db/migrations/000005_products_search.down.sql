@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_products_search;
+DROP FUNCTION IF EXISTS products_search_vector(TEXT, TEXT, TEXT, TEXT[], TEXT);
@@ -0,0 +1,21 @@
+-- Full-text search over products in Spanish and English. Names and SKUs
+-- weigh most, then brands and tags, then descriptions.
+-- The vector is built by an immutable function so it can be indexed; the
+-- search queries call it with the same arguments to use the index.
+CREATE OR REPLACE FUNCTION products_search_vector(sku TEXT, name TEXT, brand TEXT, tags TEXT[], description TEXT)
+RETURNS tsvector
+LANGUAGE sql IMMUTABLE PARALLEL SAFE
+AS $$
+    SELECT setweight(to_tsvector('simple', coalesce(sku, '')), 'A')
+        || setweight(to_tsvector('spanish', coalesce(name, '')), 'A')
+        || setweight(to_tsvector('english', coalesce(name, '')), 'A')
+        || setweight(to_tsvector('spanish', coalesce(brand, '')), 'B')
+        || setweight(to_tsvector('english', coalesce(brand, '')), 'B')
+        || setweight(to_tsvector('spanish', coalesce(array_to_string(tags, ' '), '')), 'B')
+        || setweight(to_tsvector('english', coalesce(array_to_string(tags, ' '), '')), 'B')
+        || setweight(to_tsvector('spanish', coalesce(description, '')), 'C')
+        || setweight(to_tsvector('english', coalesce(description, '')), 'C')
+$$;
+
+CREATE INDEX IF NOT EXISTS idx_products_search
+    ON products USING GIN (products_search_vector(sku, name, brand, tags, description));
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS synonym_groups;
+DROP INDEX IF EXISTS idx_products_name_trgm;
+-- pg_trgm is left installed, other objects may use it
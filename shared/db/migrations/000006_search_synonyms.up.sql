@@ -0,0 +1,16 @@
+-- Typo-tolerant search: product names are matched by trigram word
+-- similarity and misspelled words respelled after the closest known word
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (lower(name) gin_trgm_ops);
+
+-- Synonym groups, edited by admins: a search for any term of a group also
+-- finds the others
+CREATE TABLE IF NOT EXISTS synonym_groups (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    terms TEXT[] NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_synonym_groups_terms ON synonym_groups USING GIN (terms);
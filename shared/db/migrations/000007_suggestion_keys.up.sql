@@ -0,0 +1,15 @@
+-- Search box suggestions: product names, brands and category names, stored
+-- under a key per word and ranked by units sold. The suggestions job
+-- rebuilds the table from the catalog and the stock movement ledger.
+CREATE TABLE IF NOT EXISTS suggestion_keys (
+    id BIGSERIAL PRIMARY KEY,
+    search_key TEXT NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    text TEXT NOT NULL,
+    suggestion_id VARCHAR(36) NOT NULL DEFAULT '',
+    slug VARCHAR(100) NOT NULL DEFAULT '',
+    popularity BIGINT NOT NULL DEFAULT 0
+);
+
+-- Prefix matches with LIKE 'prefix%' whatever the collation
+CREATE INDEX IF NOT EXISTS idx_suggestion_keys_search_key ON suggestion_keys (search_key text_pattern_ops);
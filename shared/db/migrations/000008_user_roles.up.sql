@@ -0,0 +1,3 @@
+-- Roles carried by user tokens. Every user is a customer until staff and
+-- admin roles are granted here; no endpoint grants them.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'customer';
@@ -0,0 +1,10 @@
+-- One return of stock per order and product, written with the return's
+-- ledger entry, so that order-service can retry a return that failed partway
+-- without putting units back twice
+CREATE TABLE IF NOT EXISTS stock_returns (
+    order_id VARCHAR(100) NOT NULL,
+    product_id UUID NOT NULL REFERENCES products(id),
+    movement_id UUID NOT NULL REFERENCES stock_movements(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (order_id, product_id)
+);
@@ -0,0 +1,495 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrChecksumMismatch is returned when a migration file was edited after it
+// was applied
+var ErrChecksumMismatch = errors.New("applied migration was modified")
+
+// ErrMissingMigration is returned when rolling back a migration whose files
+// are gone
+var ErrMissingMigration = errors.New("applied migration file is missing")
+
+// migrationsTable records every applied migration
+const migrationsTable = "schema_migrations"
+
+// migrationLockKey identifies the advisory lock that keeps two migrators from
+// running at the same time
+const migrationLockKey int64 = 0x5375706572 // "Super"
+
+// nonSlugChars are replaced by underscores in migration names
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// migrationFilePattern matches {version}_{name}.up.sql and .down.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a numbered schema change read from a pair of up and down files
+type Migration struct {
+	Version  int64
+	Name     string
+	UpSQL    string
+	DownSQL  string
+	Checksum string // sha256 of UpSQL
+}
+
+// AppliedMigration is a row of schema_migrations
+type AppliedMigration struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// MigrationStatus pairs a migration file with its applied record. Migration
+// is nil when an applied migration's files were removed.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Migration *Migration
+	Applied   *AppliedMigration
+}
+
+// State is one of pending, applied, modified or missing
+func (s MigrationStatus) State() string {
+	switch {
+	case s.Applied == nil:
+		return "pending"
+	case s.Migration == nil:
+		return "missing"
+	case s.Migration.Checksum != s.Applied.Checksum:
+		return "modified"
+	default:
+		return "applied"
+	}
+}
+
+// Migrator applies and rolls back the migrations of a directory
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, migrationsPath string) (*Migrator, error) {
+	migrations, err := LoadMigrations(migrationsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// LoadMigrations reads the migration files of a directory sorted by version.
+// Every version needs an up file; the down file is optional.
+func LoadMigrations(migrationsPath string) ([]Migration, error) {
+	entries, err := os.ReadDir(migrationsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s, expected {version}_{name}.up.sql or .down.sql", entry.Name())
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		content, err := os.ReadFile(filepath.Join(migrationsPath, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration file: %w", err)
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.UpSQL = string(content)
+			migration.Checksum = checksum(content)
+		} else {
+			migration.DownSQL = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Checksum == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// CreateMigration scaffolds empty up and down files for a new migration
+// versioned by the current UTC time and returns their paths
+func CreateMigration(migrationsPath string, name string) (string, string, error) {
+	slug := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if slug == "" {
+		return "", "", fmt.Errorf("invalid migration name %q", name)
+	}
+
+	base := fmt.Sprintf("%s_%s", time.Now().UTC().Format("20060102150405"), slug)
+	upPath := filepath.Join(migrationsPath, base+".up.sql")
+	downPath := filepath.Join(migrationsPath, base+".down.sql")
+
+	files := []struct{ path, content string }{
+		{upPath, fmt.Sprintf("-- %s: apply\n", slug)},
+		{downPath, fmt.Sprintf("-- %s: roll back\n", slug)},
+	}
+	for _, f := range files {
+		// O_EXCL keeps a second create in the same second from overwriting
+		file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to create migration file: %w", err)
+		}
+		_, err = file.WriteString(f.content)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return "", "", fmt.Errorf("failed to write migration file: %w", err)
+		}
+	}
+
+	return upPath, downPath, nil
+}
+
+// Migrations returns the migrations read from the directory
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Status lists every known version, from files or from schema_migrations,
+// with its state
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied(m.db)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.status(applied), nil
+}
+
+// Up applies pending migrations up to and including target, or all of them
+// when target is 0. Each migration runs in its own transaction; it stops at
+// the first failure, leaving earlier migrations applied.
+func (m *Migrator) Up(target int64) ([]Migration, error) {
+	var done []Migration
+
+	err := m.withLock(func(conn *sql.Conn, applied map[int64]AppliedMigration) error {
+		for _, migration := range m.planUp(applied, target) {
+			err := inTransaction(conn, func(tx *sql.Tx) error {
+				if _, err := tx.Exec(migration.UpSQL); err != nil {
+					return err
+				}
+				_, err := tx.Exec(
+					"INSERT INTO "+migrationsTable+" (version, name, checksum) VALUES ($1, $2, $3)",
+					migration.Version, migration.Name, migration.Checksum,
+				)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			done = append(done, migration)
+		}
+		return nil
+	})
+
+	return done, err
+}
+
+// Down rolls back applied migrations newer than target, newest first, so
+// that target is the last one left applied. A target of 0 rolls back all.
+func (m *Migrator) Down(target int64) ([]Migration, error) {
+	var done []Migration
+
+	err := m.withLock(func(conn *sql.Conn, applied map[int64]AppliedMigration) error {
+		plan, err := m.planDown(applied, target)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range plan {
+			err := inTransaction(conn, func(tx *sql.Tx) error {
+				if strings.TrimSpace(migration.DownSQL) != "" {
+					if _, err := tx.Exec(migration.DownSQL); err != nil {
+						return err
+					}
+				}
+				_, err := tx.Exec("DELETE FROM "+migrationsTable+" WHERE version = $1", migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to roll back migration %d_%s: %w", migration.Version, migration.Name, err)
+			}
+
+			done = append(done, migration)
+		}
+		return nil
+	})
+
+	return done, err
+}
+
+// PlanUp returns the migrations Up(target) would apply, without running them
+func (m *Migrator) PlanUp(target int64) ([]Migration, error) {
+	applied, err := m.verified()
+	if err != nil {
+		return nil, err
+	}
+	return m.planUp(applied, target), nil
+}
+
+// PlanDown returns the migrations Down(target) would roll back, in order,
+// without running them
+func (m *Migrator) PlanDown(target int64) ([]Migration, error) {
+	applied, err := m.verified()
+	if err != nil {
+		return nil, err
+	}
+	return m.planDown(applied, target)
+}
+
+// TargetForSteps returns the version Down has to roll back to in order to
+// undo the last steps applied migrations
+func (m *Migrator) TargetForSteps(steps int) (int64, error) {
+	if steps <= 0 {
+		return 0, fmt.Errorf("steps must be positive, got %d", steps)
+	}
+
+	applied, err := m.applied(m.db)
+	if err != nil {
+		return 0, err
+	}
+
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+	if steps >= len(versions) {
+		return 0, nil
+	}
+	return versions[steps], nil
+}
+
+func (m *Migrator) planUp(applied map[int64]AppliedMigration, target int64) []Migration {
+	var plan []Migration
+	for _, migration := range m.migrations {
+		if target > 0 && migration.Version > target {
+			break
+		}
+		if _, ok := applied[migration.Version]; !ok {
+			plan = append(plan, migration)
+		}
+	}
+	return plan
+}
+
+func (m *Migrator) planDown(applied map[int64]AppliedMigration, target int64) ([]Migration, error) {
+	var plan []Migration
+	statuses := m.status(applied)
+
+	for i := len(statuses) - 1; i >= 0; i-- {
+		status := statuses[i]
+		if status.Applied == nil || status.Version <= target {
+			continue
+		}
+		if status.Migration == nil {
+			return nil, fmt.Errorf("%w: cannot roll back %d_%s", ErrMissingMigration, status.Version, status.Name)
+		}
+		plan = append(plan, *status.Migration)
+	}
+	return plan, nil
+}
+
+// withLock runs fn on a single connection holding the migration advisory
+// lock, after making sure schema_migrations exists and that no applied
+// migration was edited since it ran
+func (m *Migrator) withLock(fn func(conn *sql.Conn, applied map[int64]AppliedMigration) error) error {
+	ctx := context.Background()
+
+	// Advisory locks belong to a session, so everything has to run on the
+	// connection that took it
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get database connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockKey)
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+migrationsTable+` (
+    version BIGINT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    checksum VARCHAR(64) NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`)
+	if err != nil {
+		return fmt.Errorf("failed to create %s table: %w", migrationsTable, err)
+	}
+
+	applied, err := m.applied(conn)
+	if err != nil {
+		return err
+	}
+	if err := m.verify(applied); err != nil {
+		return err
+	}
+
+	return fn(conn, applied)
+}
+
+// verified reads the applied migrations outside the lock and checks them
+func (m *Migrator) verified() (map[int64]AppliedMigration, error) {
+	applied, err := m.applied(m.db)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(applied); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// verify fails when an applied migration's file was edited since it ran
+func (m *Migrator) verify(applied map[int64]AppliedMigration) error {
+	var modified []string
+	for _, status := range m.status(applied) {
+		if status.State() == "modified" {
+			modified = append(modified, fmt.Sprintf("%d_%s", status.Version, status.Name))
+		}
+	}
+	if len(modified) > 0 {
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, strings.Join(modified, ", "))
+	}
+	return nil
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// applied reads schema_migrations, which is empty before the first run
+func (m *Migrator) applied(q queryer) (map[int64]AppliedMigration, error) {
+	ctx := context.Background()
+	applied := make(map[int64]AppliedMigration)
+
+	var exists bool
+	err := q.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", migrationsTable).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	if !exists {
+		return applied, nil
+	}
+
+	rows, err := q.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM "+migrationsTable+" ORDER BY version")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record AppliedMigration
+		if err := rows.Scan(&record.Version, &record.Name, &record.Checksum, &record.AppliedAt); err != nil {
+			return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+		applied[record.Version] = record
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	return applied, nil
+}
+
+func (m *Migrator) status(applied map[int64]AppliedMigration) []MigrationStatus {
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	seen := make(map[int64]bool, len(m.migrations))
+
+	for i := range m.migrations {
+		migration := &m.migrations[i]
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name, Migration: migration}
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied = &record
+		}
+		statuses = append(statuses, status)
+		seen[migration.Version] = true
+	}
+
+	for version, record := range applied {
+		if !seen[version] {
+			record := record
+			statuses = append(statuses, MigrationStatus{Version: version, Name: record.Name, Applied: &record})
+		}
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses
+}
+
+func inTransaction(conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -1,10 +1,12 @@
 package db
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -84,6 +86,29 @@ func AutoMigrate(db *gorm.DB, models ...interface{}) error {
 	return db.AutoMigrate(models...)
 }
 
+// uniqueViolation is the SQLSTATE Postgres reports when an insert or update
+// breaks a unique constraint
+const uniqueViolation = "23505"
+
+// IsUniqueViolation tells whether err was caused by a unique constraint, as
+// when two concurrent writes claim the same slug. Given constraints, it only
+// tells so for a violation of one of them.
+func IsUniqueViolation(err error, constraints ...string) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolation {
+		return false
+	}
+	if len(constraints) == 0 {
+		return true
+	}
+	for _, constraint := range constraints {
+		if pgErr.ConstraintName == constraint {
+			return true
+		}
+	}
+	return false
+}
+
 // getEnvOrDefault returns environment variable value or default if not set
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
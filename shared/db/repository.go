@@ -0,0 +1,273 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"shared/apperr"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// softDeleteColumn marks models that are deactivated instead of deleted
+const softDeleteColumn = "is_active"
+
+// Repository provides typed operations on the records of model T. For models
+// with an is_active column it reads active records only and Delete
+// deactivates; Unscoped lifts both.
+type Repository[T any] struct {
+	DB       *gorm.DB
+	unscoped bool
+}
+
+// NewRepository creates a repository of T on database
+func NewRepository[T any](database *gorm.DB) *Repository[T] {
+	return &Repository[T]{DB: database}
+}
+
+// WithTx returns the repository bound to a transaction, as handed out by
+// Transaction
+func (r *Repository[T]) WithTx(tx *gorm.DB) *Repository[T] {
+	return &Repository[T]{DB: tx, unscoped: r.unscoped}
+}
+
+// Unscoped returns the repository seeing inactive records too, whose Delete
+// removes records for good
+func (r *Repository[T]) Unscoped() *Repository[T] {
+	return &Repository[T]{DB: r.DB, unscoped: true}
+}
+
+// Transaction runs fn in a transaction with the repository bound to it. Other
+// repositories join it through WithTx(tx.DB).
+func (r *Repository[T]) Transaction(ctx context.Context, fn func(tx *Repository[T]) error) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(r.WithTx(tx))
+	})
+}
+
+// Get retrieves a record by ID
+func (r *Repository[T]) Get(ctx context.Context, id string) (*T, error) {
+	return r.First(ctx, NewSpec().Where(Eq("id", id)))
+}
+
+// First retrieves the first record matching spec
+func (r *Repository[T]) First(ctx context.Context, spec Spec) (*T, error) {
+	query, model, err := r.query(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	var record T
+	result := query.First(&record)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return nil, apperr.NotFound("%s not found", humanName(model))
+	}
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", humanName(model), result.Error)
+	}
+
+	return &record, nil
+}
+
+// List retrieves the records matching spec
+func (r *Repository[T]) List(ctx context.Context, spec Spec) ([]T, error) {
+	query, model, err := r.query(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []T
+	result := query.Find(&records)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list %s records: %w", humanName(model), result.Error)
+	}
+
+	return records, nil
+}
+
+// ListPage retrieves one page of the records matching spec, with the number
+// of records on all pages
+func (r *Repository[T]) ListPage(ctx context.Context, spec Spec) ([]T, int64, error) {
+	total, err := r.Count(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	records, err := r.List(ctx, spec)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return records, total, nil
+}
+
+// Count returns the number of records matching the filters of spec, ignoring
+// its page
+func (r *Repository[T]) Count(ctx context.Context, spec Spec) (int64, error) {
+	query, model, err := r.query(ctx, Spec{Filters: spec.Filters})
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	result := query.Count(&count)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to count %s records: %w", humanName(model), result.Error)
+	}
+
+	return count, nil
+}
+
+// Exists checks if any record matches the filters of spec
+func (r *Repository[T]) Exists(ctx context.Context, spec Spec) (bool, error) {
+	query, model, err := r.query(ctx, Spec{Filters: spec.Filters})
+	if err != nil {
+		return false, err
+	}
+
+	var count int64
+	result := query.Limit(1).Count(&count)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to check %s existence: %w", humanName(model), result.Error)
+	}
+
+	return count > 0, nil
+}
+
+// Create inserts a record
+func (r *Repository[T]) Create(ctx context.Context, record *T) error {
+	result := r.DB.WithContext(ctx).Create(record)
+	if result.Error != nil {
+		return fmt.Errorf("failed to create record: %w", result.Error)
+	}
+	return nil
+}
+
+// Update sets the given columns of a record. Inactive records can be updated,
+// so that they can be reactivated.
+func (r *Repository[T]) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+	model, err := r.schema()
+	if err != nil {
+		return err
+	}
+	if len(updates) == 0 {
+		return apperr.Validation("no fields to update")
+	}
+
+	columns := make(map[string]interface{}, len(updates))
+	for field, value := range updates {
+		col, err := column(model, field)
+		if err != nil {
+			return err
+		}
+		columns[col.Name] = value
+	}
+
+	result := r.DB.WithContext(ctx).Model(new(T)).Where(clause.Eq{Column: clause.PrimaryColumn, Value: id}).Updates(columns)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update %s: %w", humanName(model), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return apperr.NotFound("%s not found", humanName(model))
+	}
+
+	return nil
+}
+
+// Delete deactivates a record of a soft-deleted model and removes any other
+func (r *Repository[T]) Delete(ctx context.Context, id string) error {
+	model, err := r.schema()
+	if err != nil {
+		return err
+	}
+
+	query := r.DB.WithContext(ctx).Model(new(T)).Where(clause.Eq{Column: clause.PrimaryColumn, Value: id})
+
+	var result *gorm.DB
+	if r.softDeleted(model) {
+		result = query.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: softDeleteColumn}, Value: true}).
+			Update(softDeleteColumn, false)
+	} else {
+		result = query.Delete(new(T))
+	}
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete %s: %w", humanName(model), result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return apperr.NotFound("%s not found", humanName(model))
+	}
+
+	return nil
+}
+
+// softDeleted tells whether the repository hides and deactivates records
+// instead of deleting them
+func (r *Repository[T]) softDeleted(model *schema.Schema) bool {
+	if r.unscoped {
+		return false
+	}
+	_, ok := model.FieldsByDBName[softDeleteColumn]
+	return ok
+}
+
+func (r *Repository[T]) schema() (*schema.Schema, error) {
+	stmt := &gorm.Statement{DB: r.DB}
+	if err := stmt.Parse(new(T)); err != nil {
+		return nil, fmt.Errorf("failed to parse model %T: %w", new(T), err)
+	}
+	return stmt.Schema, nil
+}
+
+// query applies spec to a query of T
+func (r *Repository[T]) query(ctx context.Context, spec Spec) (*gorm.DB, *schema.Schema, error) {
+	model, err := r.schema()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	query := r.DB.WithContext(ctx).Model(new(T))
+	if r.softDeleted(model) {
+		query = query.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: softDeleteColumn}, Value: true})
+	}
+
+	for _, filter := range spec.Filters {
+		expression, err := filter.expression(model)
+		if err != nil {
+			return nil, nil, err
+		}
+		query = query.Where(expression)
+	}
+
+	for _, order := range spec.Orders {
+		col, err := column(model, order.Field)
+		if err != nil {
+			return nil, nil, err
+		}
+		query = query.Order(clause.OrderByColumn{Column: col, Desc: order.Desc})
+	}
+
+	for _, association := range spec.Preloads {
+		query = query.Preload(association)
+	}
+
+	if spec.Limit > 0 {
+		query = query.Limit(spec.Limit)
+	}
+	if spec.Offset > 0 {
+		query = query.Offset(spec.Offset)
+	}
+
+	return query, model, nil
+}
+
+var wordStart = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+// humanName names a model in messages, e.g. "subscription run"
+func humanName(model *schema.Schema) string {
+	return strings.ToLower(wordStart.ReplaceAllString(model.Name, "$1 $2"))
+}
@@ -0,0 +1,296 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrSchemaDrift is returned when the database schema does not match the
+// models closely enough to run against it
+var ErrSchemaDrift = errors.New("database schema has drifted from the models")
+
+// Schema modes, chosen with DB_SCHEMA_MODE
+const (
+	SchemaModeAuto   = "auto"   // AutoMigrate the models at startup, for local development
+	SchemaModeVerify = "verify" // refuse to start when the schema has drifted (default)
+	SchemaModeOff    = "off"    // trust the schema without checking it
+)
+
+// Kinds of drift
+const (
+	DriftMissingTable  = "missing_table"
+	DriftMissingColumn = "missing_column"
+	DriftExtraColumn   = "extra_column"
+	DriftType          = "type_mismatch"
+	DriftNullability   = "nullability_mismatch"
+)
+
+// Drift severities. Errors break queries the models issue; warnings are
+// differences the models tolerate, like a text field stored as varchar(100).
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// SchemaDrift is one difference between a model and its table
+type SchemaDrift struct {
+	Table    string `json:"table"`
+	Column   string `json:"column,omitempty"`
+	Kind     string `json:"kind"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	Severity string `json:"severity"`
+}
+
+func (d SchemaDrift) String() string {
+	name := d.Table
+	if d.Column != "" {
+		name += "." + d.Column
+	}
+	if d.Expected == "" && d.Actual == "" {
+		return fmt.Sprintf("%s: %s", name, d.Kind)
+	}
+	return fmt.Sprintf("%s: %s (model %s, database %s)", name, d.Kind, d.Expected, d.Actual)
+}
+
+type liveColumn struct {
+	Name                   string
+	DataType               string
+	UdtName                string
+	CharacterMaximumLength *int
+	NumericPrecision       *int
+	NumericScale           *int
+	IsNullable             string
+}
+
+// PrepareSchema readies the database for the models according to
+// DB_SCHEMA_MODE. By default it only verifies the schema, so a service
+// without the setting, like product-service, never migrates at startup;
+// migrations belong to tools/migrate. Local setups opt into auto.
+func PrepareSchema(database *gorm.DB, models ...interface{}) error {
+	switch mode := getEnvOrDefault("DB_SCHEMA_MODE", SchemaModeVerify); mode {
+	case SchemaModeAuto:
+		return AutoMigrate(database, models...)
+	case SchemaModeVerify:
+		return VerifySchema(database, models...)
+	case SchemaModeOff:
+		return nil
+	default:
+		return fmt.Errorf("unknown DB_SCHEMA_MODE %q, expected %s, %s or %s", mode, SchemaModeAuto, SchemaModeVerify, SchemaModeOff)
+	}
+}
+
+// VerifySchema fails with ErrSchemaDrift when DiffSchema finds any error
+func VerifySchema(database *gorm.DB, models ...interface{}) error {
+	drifts, err := DiffSchema(database, models...)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for _, drift := range drifts {
+		if drift.Severity == SeverityError {
+			problems = append(problems, drift.String())
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrSchemaDrift, strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// DiffSchema compares the tables of the models with the live database
+func DiffSchema(database *gorm.DB, models ...interface{}) ([]SchemaDrift, error) {
+	return diffSchema(database, liveColumns, models...)
+}
+
+func diffSchema(database *gorm.DB, columnsOf func(*gorm.DB, string) (map[string]liveColumn, error), models ...interface{}) ([]SchemaDrift, error) {
+	var drifts []SchemaDrift
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: database}
+		if err := stmt.Parse(model); err != nil {
+			return nil, fmt.Errorf("failed to parse model %T: %w", model, err)
+		}
+		table := stmt.Schema.Table
+
+		columns, err := columnsOf(database, table)
+		if err != nil {
+			return nil, err
+		}
+		if len(columns) == 0 {
+			drifts = append(drifts, SchemaDrift{Table: table, Kind: DriftMissingTable, Severity: SeverityError})
+			continue
+		}
+
+		seen := make(map[string]bool, len(columns))
+		for _, dbName := range stmt.Schema.DBNames {
+			field := stmt.Schema.FieldsByDBName[dbName]
+			if field.IgnoreMigration {
+				continue
+			}
+			seen[dbName] = true
+
+			column, ok := columns[dbName]
+			if !ok {
+				drifts = append(drifts, SchemaDrift{Table: table, Column: dbName, Kind: DriftMissingColumn, Severity: SeverityError})
+				continue
+			}
+
+			expected := canonicalType(database.Dialector.DataTypeOf(field))
+			actual := column.canonicalType()
+			if expected != actual {
+				severity := SeverityWarning
+				if typeFamily(expected) != typeFamily(actual) {
+					severity = SeverityError
+				}
+				drifts = append(drifts, SchemaDrift{Table: table, Column: dbName, Kind: DriftType, Expected: expected, Actual: actual, Severity: severity})
+			}
+
+			notNull := field.NotNull || field.PrimaryKey
+			if nullable := column.IsNullable == "YES"; notNull == nullable {
+				drifts = append(drifts, SchemaDrift{
+					Table: table, Column: dbName, Kind: DriftNullability,
+					Expected: nullability(!notNull), Actual: nullability(nullable), Severity: SeverityWarning,
+				})
+			}
+		}
+
+		for name := range columns {
+			if !seen[name] {
+				drifts = append(drifts, SchemaDrift{Table: table, Column: name, Kind: DriftExtraColumn, Severity: SeverityWarning})
+			}
+		}
+	}
+
+	sort.SliceStable(drifts, func(i, j int) bool {
+		if drifts[i].Table != drifts[j].Table {
+			return drifts[i].Table < drifts[j].Table
+		}
+		return drifts[i].Column < drifts[j].Column
+	})
+
+	return drifts, nil
+}
+
+func liveColumns(database *gorm.DB, table string) (map[string]liveColumn, error) {
+	var rows []liveColumn
+	result := database.Raw(`
+SELECT column_name AS name, data_type, udt_name, character_maximum_length,
+    numeric_precision, numeric_scale, is_nullable
+FROM information_schema.columns
+WHERE table_schema = current_schema() AND table_name = ?`, table).Scan(&rows)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, result.Error)
+	}
+
+	columns := make(map[string]liveColumn, len(rows))
+	for _, row := range rows {
+		columns[row.Name] = row
+	}
+	return columns, nil
+}
+
+// canonicalType spells a live column type the way canonicalType spells
+// model types
+func (c liveColumn) canonicalType() string {
+	switch c.DataType {
+	case "character varying", "character":
+		if c.CharacterMaximumLength != nil {
+			return canonicalType(fmt.Sprintf("%s(%d)", c.DataType, *c.CharacterMaximumLength))
+		}
+	case "numeric":
+		if c.NumericPrecision != nil && c.NumericScale != nil {
+			return fmt.Sprintf("numeric(%d,%d)", *c.NumericPrecision, *c.NumericScale)
+		}
+	case "ARRAY":
+		return canonicalType(strings.TrimPrefix(c.UdtName, "_")) + "[]"
+	case "USER-DEFINED":
+		return c.UdtName
+	}
+	return canonicalType(c.DataType)
+}
+
+var typeSize = regexp.MustCompile(`\s*\(.*\)`)
+
+// typeAliases maps the spellings of a type, in DDL, GORM and
+// information_schema, to one name
+var typeAliases = map[string]string{
+	"character varying":           "varchar",
+	"character":                   "char",
+	"bpchar":                      "char",
+	"decimal":                     "numeric",
+	"int":                         "integer",
+	"int4":                        "integer",
+	"serial":                      "integer",
+	"int8":                        "bigint",
+	"bigserial":                   "bigint",
+	"int2":                        "smallint",
+	"smallserial":                 "smallint",
+	"bool":                        "boolean",
+	"float4":                      "real",
+	"float8":                      "double precision",
+	"timestamp without time zone": "timestamp",
+	"timestamp with time zone":    "timestamptz",
+	"time without time zone":      "time",
+	"time with time zone":         "timetz",
+}
+
+func canonicalType(dataType string) string {
+	dataType = strings.ToLower(strings.TrimSpace(dataType))
+
+	array := strings.HasSuffix(dataType, "[]")
+	dataType = strings.TrimSuffix(dataType, "[]")
+
+	name := typeSize.ReplaceAllString(dataType, "")
+	size := strings.ReplaceAll(strings.TrimPrefix(dataType, name), " ", "")
+	if alias, ok := typeAliases[name]; ok {
+		name = alias
+	}
+
+	// Timestamps differ in precision only when it is set explicitly
+	if name == "timestamp" || name == "timestamptz" {
+		size = ""
+	}
+
+	if array {
+		return name + size + "[]"
+	}
+	return name + size
+}
+
+// typeFamily groups types a model field can read and write alike
+func typeFamily(dataType string) string {
+	name := typeSize.ReplaceAllString(dataType, "")
+	if strings.HasSuffix(name, "[]") {
+		return "array of " + typeFamily(strings.TrimSuffix(name, "[]"))
+	}
+
+	switch name {
+	case "text", "varchar", "char":
+		return "text"
+	case "smallint", "integer", "bigint":
+		return "integer"
+	case "numeric", "real", "double precision":
+		return "number"
+	case "timestamp", "timestamptz":
+		return "timestamp"
+	case "json", "jsonb":
+		return "json"
+	default:
+		return name
+	}
+}
+
+func nullability(nullable bool) string {
+	if nullable {
+		return "null"
+	}
+	return "not null"
+}
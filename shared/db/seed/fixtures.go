@@ -0,0 +1,353 @@
+package seed
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNoFixtures is returned when an environment has no fixture directory, so
+// that environments like production cannot be seeded by mistake
+var ErrNoFixtures = errors.New("no fixtures for environment")
+
+type Department struct {
+	Slug        string `json:"slug" yaml:"slug"`
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+	Icon        string `json:"icon" yaml:"icon"`
+	Image       string `json:"image" yaml:"image"`
+}
+
+// Category refers to its parent by slug; roots have no parent
+type Category struct {
+	Slug        string `json:"slug" yaml:"slug"`
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+	Parent      string `json:"parent" yaml:"parent"`
+}
+
+// Product refers to its category and department by slug. Stock only applies
+// when the product is created; later runs leave stock to the ledger.
+type Product struct {
+	SKU           string   `json:"sku" yaml:"sku"`
+	Slug          string   `json:"slug" yaml:"slug"`
+	Name          string   `json:"name" yaml:"name"`
+	Description   string   `json:"description" yaml:"description"`
+	Price         float64  `json:"price" yaml:"price"`
+	OriginalPrice *float64 `json:"original_price" yaml:"original_price"`
+	Category      string   `json:"category" yaml:"category"`
+	Department    string   `json:"department" yaml:"department"`
+	Brand         string   `json:"brand" yaml:"brand"`
+	Unit          string   `json:"unit" yaml:"unit"`
+	Stock         int      `json:"stock" yaml:"stock"`
+	MinStock      int      `json:"min_stock" yaml:"min_stock"`
+	Weight        float64  `json:"weight" yaml:"weight"` // grams
+	WeightUnit    string   `json:"weight_unit" yaml:"weight_unit"`
+	IsOnSale      bool     `json:"is_on_sale" yaml:"is_on_sale"`
+	Discount      *float64 `json:"discount" yaml:"discount"`
+	Tags          []string `json:"tags" yaml:"tags"`
+}
+
+// Fixtures is a catalog to seed. A fixture file may hold any subset of it.
+type Fixtures struct {
+	Departments []Department `json:"departments" yaml:"departments"`
+	Categories  []Category   `json:"categories" yaml:"categories"`
+	Products    []Product    `json:"products" yaml:"products"`
+}
+
+// Merge appends other to f. Departments and categories whose slug f already
+// has are kept as they are in f, so several files can share a taxonomy.
+func (f *Fixtures) Merge(other Fixtures) {
+	departments := make(map[string]bool, len(f.Departments))
+	for _, department := range f.Departments {
+		departments[department.Slug] = true
+	}
+	for _, department := range other.Departments {
+		if !departments[department.Slug] {
+			f.Departments = append(f.Departments, department)
+			departments[department.Slug] = true
+		}
+	}
+
+	categories := make(map[string]bool, len(f.Categories))
+	for _, category := range f.Categories {
+		categories[category.Slug] = true
+	}
+	for _, category := range other.Categories {
+		if !categories[category.Slug] {
+			f.Categories = append(f.Categories, category)
+			categories[category.Slug] = true
+		}
+	}
+
+	f.Products = append(f.Products, other.Products...)
+}
+
+// Validate checks required fields, duplicates and that every slug reference
+// points to a fixture
+func (f *Fixtures) Validate() error {
+	departments := make(map[string]bool, len(f.Departments))
+	for _, department := range f.Departments {
+		if department.Slug == "" || department.Name == "" {
+			return fmt.Errorf("department %q needs a slug and a name", department.Name)
+		}
+		if departments[department.Slug] {
+			return fmt.Errorf("duplicate department slug %s", department.Slug)
+		}
+		departments[department.Slug] = true
+	}
+
+	categories := make(map[string]bool, len(f.Categories))
+	for _, category := range f.Categories {
+		if category.Slug == "" || category.Name == "" {
+			return fmt.Errorf("category %q needs a slug and a name", category.Name)
+		}
+		if categories[category.Slug] {
+			return fmt.Errorf("duplicate category slug %s", category.Slug)
+		}
+		categories[category.Slug] = true
+	}
+	parents := make(map[string]string, len(f.Categories))
+	for _, category := range f.Categories {
+		if category.Parent != "" && !categories[category.Parent] {
+			return fmt.Errorf("category %s has unknown parent %s", category.Slug, category.Parent)
+		}
+		parents[category.Slug] = category.Parent
+	}
+	for _, category := range f.Categories {
+		// A chain longer than the number of categories loops
+		slug := category.Slug
+		for depth := 0; slug != ""; depth++ {
+			if depth > len(f.Categories) {
+				return fmt.Errorf("category %s is its own ancestor", category.Slug)
+			}
+			slug = parents[slug]
+		}
+	}
+
+	skus := make(map[string]bool, len(f.Products))
+	slugs := make(map[string]bool, len(f.Products))
+	for _, product := range f.Products {
+		if product.SKU == "" || product.Slug == "" || product.Name == "" {
+			return fmt.Errorf("product %q needs a sku, a slug and a name", product.Name)
+		}
+		if skus[product.SKU] {
+			return fmt.Errorf("duplicate product sku %s", product.SKU)
+		}
+		if slugs[product.Slug] {
+			return fmt.Errorf("duplicate product slug %s", product.Slug)
+		}
+		skus[product.SKU] = true
+		slugs[product.Slug] = true
+
+		if product.Price < 0 || product.Stock < 0 || product.MinStock < 0 {
+			return fmt.Errorf("product %s has a negative price or stock", product.SKU)
+		}
+		if !categories[product.Category] {
+			return fmt.Errorf("product %s has unknown category %s", product.SKU, product.Category)
+		}
+		if !departments[product.Department] {
+			return fmt.Errorf("product %s has unknown department %s", product.SKU, product.Department)
+		}
+	}
+
+	return nil
+}
+
+// LoadFixtures reads every fixture file of {fixturesPath}/{env} in name order.
+// YAML and JSON files hold a Fixtures document; CSV files are named after
+// what they hold: departments.csv, categories.csv or products.csv.
+func LoadFixtures(fixturesPath string, env string) (*Fixtures, error) {
+	dir := filepath.Join(fixturesPath, env)
+
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w %s in %s", ErrNoFixtures, env, fixturesPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures directory: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	fixtures := &Fixtures{}
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+
+		var loaded Fixtures
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".yaml", ".yml":
+			err = decodeFile(path, func(r io.Reader) error { return yaml.NewDecoder(r).Decode(&loaded) })
+		case ".json":
+			err = decodeFile(path, func(r io.Reader) error { return json.NewDecoder(r).Decode(&loaded) })
+		case ".csv":
+			err = decodeFile(path, func(r io.Reader) error { return decodeCSV(r, name, &loaded) })
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load fixture %s: %w", name, err)
+		}
+
+		fixtures.Merge(loaded)
+	}
+
+	if err := fixtures.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid %s fixtures: %w", env, err)
+	}
+
+	return fixtures, nil
+}
+
+func decodeFile(path string, decode func(r io.Reader) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	err = decode(file)
+	if errors.Is(err, io.EOF) {
+		// Empty file
+		return nil
+	}
+	return err
+}
+
+// decodeCSV reads a CSV file with a header row of field names. Tags are
+// separated by "|".
+func decodeCSV(r io.Reader, name string, fixtures *Fixtures) error {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	header := records[0]
+	for i, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for j, column := range header {
+			row[strings.TrimSpace(column)] = strings.TrimSpace(record[j])
+		}
+
+		line := i + 2
+		switch strings.TrimSuffix(strings.ToLower(name), ".csv") {
+		case "departments":
+			fixtures.Departments = append(fixtures.Departments, Department{
+				Slug:        row["slug"],
+				Name:        row["name"],
+				Description: row["description"],
+				Icon:        row["icon"],
+				Image:       row["image"],
+			})
+		case "categories":
+			fixtures.Categories = append(fixtures.Categories, Category{
+				Slug:        row["slug"],
+				Name:        row["name"],
+				Description: row["description"],
+				Parent:      row["parent"],
+			})
+		case "products":
+			product, err := productFromRow(row)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			fixtures.Products = append(fixtures.Products, product)
+		default:
+			return fmt.Errorf("CSV fixtures must be named departments.csv, categories.csv or products.csv")
+		}
+	}
+
+	return nil
+}
+
+func productFromRow(row map[string]string) (Product, error) {
+	product := Product{
+		SKU:         row["sku"],
+		Slug:        row["slug"],
+		Name:        row["name"],
+		Description: row["description"],
+		Category:    row["category"],
+		Department:  row["department"],
+		Brand:       row["brand"],
+		Unit:        row["unit"],
+		WeightUnit:  row["weight_unit"],
+	}
+
+	var err error
+	if product.Price, err = parseFloat(row, "price"); err != nil {
+		return product, err
+	}
+	if product.Weight, err = parseFloat(row, "weight"); err != nil {
+		return product, err
+	}
+	if product.Stock, err = parseInt(row, "stock"); err != nil {
+		return product, err
+	}
+	if product.MinStock, err = parseInt(row, "min_stock"); err != nil {
+		return product, err
+	}
+	if row["original_price"] != "" {
+		originalPrice, err := parseFloat(row, "original_price")
+		if err != nil {
+			return product, err
+		}
+		product.OriginalPrice = &originalPrice
+	}
+	if row["discount"] != "" {
+		discount, err := parseFloat(row, "discount")
+		if err != nil {
+			return product, err
+		}
+		product.Discount = &discount
+	}
+	if row["is_on_sale"] != "" {
+		if product.IsOnSale, err = strconv.ParseBool(row["is_on_sale"]); err != nil {
+			return product, fmt.Errorf("invalid is_on_sale: %w", err)
+		}
+	}
+	if row["tags"] != "" {
+		product.Tags = strings.Split(row["tags"], "|")
+	}
+
+	return product, nil
+}
+
+func parseFloat(row map[string]string, column string) (float64, error) {
+	if row[column] == "" {
+		return 0, nil
+	}
+	value, err := strconv.ParseFloat(row[column], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", column, err)
+	}
+	return value, nil
+}
+
+func parseInt(row map[string]string, column string) (int, error) {
+	if row[column] == "" {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(row[column])
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", column, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,160 @@
+package seed
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"strings"
+)
+
+// generatedSKUPrefix marks products made by Generate so they never collide
+// with fixture SKUs
+const generatedSKUPrefix = "GEN-"
+
+type catalogCategory struct {
+	slug     string
+	name     string
+	nouns    []string
+	sizes    []string
+	unit     string
+	minPrice float64
+	maxPrice float64
+	tags     []string
+}
+
+type catalogDepartment struct {
+	slug       string
+	name       string
+	icon       string
+	categories []catalogCategory
+}
+
+// syntheticCatalog is the taxonomy generated products are drawn from
+var syntheticCatalog = []catalogDepartment{
+	{"produce", "Produce", "🥬", []catalogCategory{
+		{"fruits", "Fruits", []string{"Apples", "Bananas", "Oranges", "Mangoes", "Strawberries", "Grapes", "Pears", "Pineapple"}, []string{"500 g", "1 kg", "2 kg"}, "kg", 1.5, 8, []string{"fresh", "fruit"}},
+		{"vegetables", "Vegetables", []string{"Tomatoes", "Carrots", "Onions", "Potatoes", "Spinach", "Broccoli", "Peppers", "Zucchini"}, []string{"500 g", "1 kg"}, "kg", 0.8, 6, []string{"fresh", "vegetable"}},
+	}},
+	{"dairy", "Dairy", "🥛", []catalogCategory{
+		{"milk-cream", "Milk & Cream", []string{"Whole Milk", "Skim Milk", "Lactose Free Milk", "Heavy Cream", "Half and Half"}, []string{"1 L", "2 L", "500 ml"}, "each", 0.9, 5, []string{"dairy", "refrigerated"}},
+		{"cheese", "Cheese", []string{"Cheddar", "Mozzarella", "Parmesan", "Gouda", "Cream Cheese", "Feta"}, []string{"200 g", "400 g"}, "each", 2.5, 12, []string{"dairy", "cheese"}},
+		{"yogurt", "Yogurt", []string{"Greek Yogurt", "Plain Yogurt", "Strawberry Yogurt", "Drinkable Yogurt"}, []string{"150 g", "1 kg"}, "each", 0.7, 6, []string{"dairy", "breakfast"}},
+	}},
+	{"meat-seafood", "Meat & Seafood", "🥩", []catalogCategory{
+		{"beef", "Beef", []string{"Ground Beef", "Sirloin Steak", "Ribeye", "Beef Brisket"}, []string{"500 g", "1 kg"}, "kg", 6, 30, []string{"meat", "protein"}},
+		{"chicken", "Chicken", []string{"Chicken Breast", "Chicken Thighs", "Whole Chicken", "Chicken Wings"}, []string{"500 g", "1 kg", "2 kg"}, "kg", 4, 15, []string{"meat", "protein"}},
+		{"seafood", "Seafood", []string{"Salmon Fillet", "Shrimp", "Tilapia", "Tuna Steak"}, []string{"300 g", "500 g"}, "kg", 7, 35, []string{"seafood", "protein"}},
+	}},
+	{"bakery", "Bakery", "🍞", []catalogCategory{
+		{"bread", "Bread", []string{"Sourdough Loaf", "Whole Wheat Bread", "Baguette", "Rye Bread", "Bagels"}, []string{"400 g", "680 g"}, "each", 1.5, 6, []string{"bakery", "bread"}},
+		{"pastries", "Pastries", []string{"Croissants", "Muffins", "Cinnamon Rolls", "Donuts"}, []string{"4 pack", "6 pack"}, "pack", 2.5, 9, []string{"bakery", "sweet"}},
+	}},
+	{"pantry", "Pantry", "🥫", []catalogCategory{
+		{"pasta-rice", "Pasta & Rice", []string{"Spaghetti", "Penne", "Basmati Rice", "Jasmine Rice", "Brown Rice"}, []string{"500 g", "1 kg", "2 kg"}, "each", 1, 7, []string{"pantry", "grains"}},
+		{"canned-goods", "Canned Goods", []string{"Black Beans", "Chickpeas", "Diced Tomatoes", "Tuna", "Sweet Corn"}, []string{"400 g", "800 g"}, "each", 0.8, 4, []string{"pantry", "canned"}},
+		{"snacks", "Snacks", []string{"Tortilla Chips", "Pretzels", "Granola Bars", "Mixed Nuts", "Crackers"}, []string{"150 g", "300 g"}, "each", 1.5, 8, []string{"snack"}},
+	}},
+	{"frozen", "Frozen", "🧊", []catalogCategory{
+		{"frozen-meals", "Frozen Meals", []string{"Cheese Pizza", "Lasagna", "Burritos", "Chicken Nuggets"}, []string{"350 g", "1 kg"}, "each", 3, 12, []string{"frozen", "ready-meal"}},
+		{"ice-cream", "Ice Cream", []string{"Vanilla Ice Cream", "Chocolate Ice Cream", "Fruit Sorbet", "Ice Cream Bars"}, []string{"500 ml", "1 L"}, "each", 2.5, 9, []string{"frozen", "dessert"}},
+	}},
+	{"beverages", "Beverages", "🥤", []catalogCategory{
+		{"water-juice", "Water & Juice", []string{"Spring Water", "Sparkling Water", "Orange Juice", "Apple Juice"}, []string{"500 ml", "1 L", "6 pack"}, "each", 0.5, 6, []string{"beverage"}},
+		{"coffee-tea", "Coffee & Tea", []string{"Ground Coffee", "Coffee Beans", "Green Tea", "Black Tea"}, []string{"250 g", "500 g", "20 bags"}, "each", 3, 18, []string{"beverage", "breakfast"}},
+	}},
+	{"health-beauty", "Health & Beauty", "🧴", []catalogCategory{
+		{"personal-care", "Personal Care", []string{"Shampoo", "Conditioner", "Body Wash", "Toothpaste", "Deodorant"}, []string{"250 ml", "500 ml"}, "each", 2, 12, []string{"personal-care"}},
+	}},
+}
+
+var syntheticBrands = []string{
+	"Green Valley", "Sunrise Farms", "Blue Harbor", "Golden Field", "Mountain Peak",
+	"Casa Fresca", "Daily Choice", "Nature's Table", "Urban Pantry", "Red Barn",
+}
+
+var syntheticQualifiers = []string{"", "", "Organic", "Premium", "Family Size", "Light", "Classic", "Select"}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Generate builds a synthetic catalog of count products spread over the
+// synthetic taxonomy. The same seed always yields the same catalog, so load
+// tests are repeatable.
+func Generate(count int, seed int64) *Fixtures {
+	random := rand.New(rand.NewSource(seed))
+	fixtures := &Fixtures{}
+
+	type slot struct {
+		department string
+		category   catalogCategory
+	}
+	var slots []slot
+
+	for _, department := range syntheticCatalog {
+		fixtures.Departments = append(fixtures.Departments, Department{
+			Slug: department.slug,
+			Name: department.name,
+			Icon: department.icon,
+		})
+		for _, category := range department.categories {
+			fixtures.Categories = append(fixtures.Categories, Category{
+				Slug: category.slug,
+				Name: category.name,
+			})
+			slots = append(slots, slot{department.slug, category})
+		}
+	}
+
+	for i := 1; i <= count; i++ {
+		slot := slots[random.Intn(len(slots))]
+		category := slot.category
+
+		brand := syntheticBrands[random.Intn(len(syntheticBrands))]
+		noun := category.nouns[random.Intn(len(category.nouns))]
+		size := category.sizes[random.Intn(len(category.sizes))]
+		qualifier := syntheticQualifiers[random.Intn(len(syntheticQualifiers))]
+
+		name := strings.Join(strings.Fields(fmt.Sprintf("%s %s %s %s", brand, qualifier, noun, size)), " ")
+		sku := fmt.Sprintf("%s%07d", generatedSKUPrefix, i)
+
+		product := Product{
+			SKU:         sku,
+			Slug:        fmt.Sprintf("%s-%d", slugify(name), i),
+			Name:        name,
+			Description: fmt.Sprintf("%s %s by %s", size, strings.ToLower(noun), brand),
+			Price:       roundPrice(category.minPrice + random.Float64()*(category.maxPrice-category.minPrice)),
+			Category:    category.slug,
+			Department:  slot.department,
+			Brand:       brand,
+			Unit:        category.unit,
+			Stock:       random.Intn(200),
+			MinStock:    5 + random.Intn(10),
+			Tags:        append([]string{}, category.tags...),
+		}
+		if qualifier == "Organic" {
+			product.Tags = append(product.Tags, "organic")
+		}
+
+		// About one product in eight is on sale
+		if random.Intn(8) == 0 {
+			discount := float64(5 * (1 + random.Intn(6)))
+			originalPrice := product.Price
+			product.IsOnSale = true
+			product.Discount = &discount
+			product.OriginalPrice = &originalPrice
+			product.Price = roundPrice(originalPrice * (1 - discount/100))
+		}
+
+		fixtures.Products = append(fixtures.Products, product)
+	}
+
+	return fixtures
+}
+
+func slugify(name string) string {
+	return strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
+
+func roundPrice(price float64) float64 {
+	return math.Round(price*100) / 100
+}
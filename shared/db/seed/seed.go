@@ -0,0 +1,251 @@
+package seed
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// seedActor is recorded on the stock movements of seeded products
+const seedActor = "seed"
+
+// Counts tallies what seeding did with one kind of fixture. Unchanged rows
+// already matched their fixture.
+type Counts struct {
+	Inserted  int `json:"inserted"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+}
+
+type Result struct {
+	Departments Counts `json:"departments"`
+	Categories  Counts `json:"categories"`
+	Products    Counts `json:"products"`
+}
+
+// Seed upserts fixtures in one transaction: departments and categories by
+// slug, products by SKU. Running it again with the same fixtures changes
+// nothing. New products open their stock ledger with their initial stock.
+func Seed(db *sql.DB, fixtures *Fixtures) (*Result, error) {
+	if err := fixtures.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin seed transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &Result{}
+
+	departmentIDs, err := seedDepartments(tx, fixtures.Departments, &result.Departments)
+	if err != nil {
+		return nil, err
+	}
+
+	categoryIDs, err := seedCategories(tx, fixtures.Categories, &result.Categories)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := seedProducts(tx, fixtures.Products, departmentIDs, categoryIDs, &result.Products); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit seed transaction: %w", err)
+	}
+
+	return result, nil
+}
+
+func seedDepartments(tx *sql.Tx, departments []Department, counts *Counts) (map[string]string, error) {
+	ids := make(map[string]string, len(departments))
+
+	for _, department := range departments {
+		row := tx.QueryRow(`
+INSERT INTO departments (slug, name, description, icon, image)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (slug) DO UPDATE SET
+    name = EXCLUDED.name,
+    description = EXCLUDED.description,
+    icon = EXCLUDED.icon,
+    image = EXCLUDED.image,
+    updated_at = CURRENT_TIMESTAMP
+WHERE (departments.name, departments.description, departments.icon, departments.image)
+    IS DISTINCT FROM (EXCLUDED.name, EXCLUDED.description, EXCLUDED.icon, EXCLUDED.image)
+RETURNING id, (xmax = 0)`,
+			department.Slug, department.Name, department.Description, department.Icon, department.Image)
+
+		id, err := upserted(tx, row, counts, "departments", department.Slug)
+		if err != nil {
+			return nil, fmt.Errorf("failed to seed department %s: %w", department.Slug, err)
+		}
+		ids[department.Slug] = id
+	}
+
+	return ids, nil
+}
+
+// seedCategories inserts parents before their children and derives levels
+// from the hierarchy, as product-service does
+func seedCategories(tx *sql.Tx, categories []Category, counts *Counts) (map[string]string, error) {
+	ids := make(map[string]string, len(categories))
+	levels := make(map[string]int, len(categories))
+
+	for _, category := range parentsFirst(categories) {
+		var parentID *string
+		level := 0
+		if category.Parent != "" {
+			id := ids[category.Parent]
+			parentID = &id
+			level = levels[category.Parent] + 1
+		}
+
+		row := tx.QueryRow(`
+INSERT INTO categories (slug, name, description, parent_id, level)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (slug) DO UPDATE SET
+    name = EXCLUDED.name,
+    description = EXCLUDED.description,
+    parent_id = EXCLUDED.parent_id,
+    level = EXCLUDED.level,
+    updated_at = CURRENT_TIMESTAMP
+WHERE (categories.name, categories.description, categories.parent_id, categories.level)
+    IS DISTINCT FROM (EXCLUDED.name, EXCLUDED.description, EXCLUDED.parent_id, EXCLUDED.level)
+RETURNING id, (xmax = 0)`,
+			category.Slug, category.Name, category.Description, parentID, level)
+
+		id, err := upserted(tx, row, counts, "categories", category.Slug)
+		if err != nil {
+			return nil, fmt.Errorf("failed to seed category %s: %w", category.Slug, err)
+		}
+		ids[category.Slug] = id
+		levels[category.Slug] = level
+	}
+
+	return ids, nil
+}
+
+func seedProducts(tx *sql.Tx, products []Product, departmentIDs map[string]string, categoryIDs map[string]string, counts *Counts) error {
+	for _, product := range products {
+		var id string
+		var inserted bool
+
+		err := tx.QueryRow(`
+INSERT INTO products (sku, slug, name, description, price, original_price, category_id, department_id,
+    brand, unit, stock, min_stock, weight, weight_unit, is_on_sale, discount, tags)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+ON CONFLICT (sku) DO UPDATE SET
+    slug = EXCLUDED.slug,
+    name = EXCLUDED.name,
+    description = EXCLUDED.description,
+    price = EXCLUDED.price,
+    original_price = EXCLUDED.original_price,
+    category_id = EXCLUDED.category_id,
+    department_id = EXCLUDED.department_id,
+    brand = EXCLUDED.brand,
+    unit = EXCLUDED.unit,
+    min_stock = EXCLUDED.min_stock,
+    weight = EXCLUDED.weight,
+    weight_unit = EXCLUDED.weight_unit,
+    is_on_sale = EXCLUDED.is_on_sale,
+    discount = EXCLUDED.discount,
+    tags = EXCLUDED.tags,
+    updated_at = CURRENT_TIMESTAMP
+WHERE (products.slug, products.name, products.description, products.price, products.original_price,
+    products.category_id, products.department_id, products.brand, products.unit, products.min_stock,
+    products.weight, products.weight_unit, products.is_on_sale, products.discount, products.tags)
+    IS DISTINCT FROM (EXCLUDED.slug, EXCLUDED.name, EXCLUDED.description, EXCLUDED.price, EXCLUDED.original_price,
+    EXCLUDED.category_id, EXCLUDED.department_id, EXCLUDED.brand, EXCLUDED.unit, EXCLUDED.min_stock,
+    EXCLUDED.weight, EXCLUDED.weight_unit, EXCLUDED.is_on_sale, EXCLUDED.discount, EXCLUDED.tags)
+RETURNING id, (xmax = 0)`,
+			product.SKU, product.Slug, product.Name, product.Description, product.Price, product.OriginalPrice,
+			categoryIDs[product.Category], departmentIDs[product.Department],
+			product.Brand, product.Unit, product.Stock, product.MinStock, product.Weight, product.WeightUnit,
+			product.IsOnSale, product.Discount, pq.Array(product.Tags),
+		).Scan(&id, &inserted)
+
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			counts.Unchanged++
+			continue
+		case err != nil:
+			return fmt.Errorf("failed to seed product %s: %w", product.SKU, err)
+		case !inserted:
+			counts.Updated++
+			continue
+		}
+		counts.Inserted++
+
+		// Stock is only set on insert, so the ledger opens with it
+		if product.Stock == 0 {
+			continue
+		}
+		_, err = tx.Exec(`
+INSERT INTO stock_movements (product_id, delta, reason, reference_id, actor)
+VALUES ($1, $2, 'adjustment', 'opening-balance', $3)`,
+			id, product.Stock, seedActor)
+		if err != nil {
+			return fmt.Errorf("failed to open stock ledger of product %s: %w", product.SKU, err)
+		}
+	}
+
+	return nil
+}
+
+// upserted reads the id of an upserted row and counts it. When the row
+// already matched, the upsert returns nothing and the id is looked up.
+func upserted(tx *sql.Tx, row *sql.Row, counts *Counts, table string, slug string) (string, error) {
+	var id string
+	var inserted bool
+
+	err := row.Scan(&id, &inserted)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		counts.Unchanged++
+		err = tx.QueryRow("SELECT id FROM "+table+" WHERE slug = $1", slug).Scan(&id)
+		return id, err
+	case err != nil:
+		return "", err
+	case inserted:
+		counts.Inserted++
+	default:
+		counts.Updated++
+	}
+
+	return id, nil
+}
+
+// parentsFirst orders categories so every parent comes before its children.
+// Fixtures are validated first, so every parent exists.
+func parentsFirst(categories []Category) []Category {
+	bySlug := make(map[string]Category, len(categories))
+	for _, category := range categories {
+		bySlug[category.Slug] = category
+	}
+
+	ordered := make([]Category, 0, len(categories))
+	visited := make(map[string]bool, len(categories))
+
+	var visit func(category Category)
+	visit = func(category Category) {
+		if visited[category.Slug] {
+			return
+		}
+		visited[category.Slug] = true
+		if category.Parent != "" {
+			visit(bySlug[category.Parent])
+		}
+		ordered = append(ordered, category)
+	}
+
+	for _, category := range categories {
+		visit(category)
+	}
+
+	return ordered
+}
@@ -0,0 +1,216 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrUnknownField is returned when a spec names a field its model does not have
+var ErrUnknownField = errors.New("unknown field")
+
+// Operator compares a field with the value of a filter
+type Operator string
+
+const (
+	OpEq      Operator = "="
+	OpNe      Operator = "<>"
+	OpGt      Operator = ">"
+	OpGte     Operator = ">="
+	OpLt      Operator = "<"
+	OpLte     Operator = "<="
+	OpIn      Operator = "in"
+	OpNotIn   Operator = "not in"
+	OpLike    Operator = "like"
+	OpILike   Operator = "ilike"
+	OpIsNull  Operator = "is null"
+	OpNotNull Operator = "is not null"
+	OpOr      Operator = "or"
+)
+
+// Filter is one condition of a spec. Field is a column or Go field name of
+// the model, checked against its schema, so callers cannot inject SQL.
+type Filter struct {
+	Field string
+	Op    Operator
+	Value interface{}
+	Any   []Filter // the alternatives of an OpOr filter
+}
+
+// Eq matches records whose field equals value
+func Eq(field string, value interface{}) Filter {
+	return Filter{Field: field, Op: OpEq, Value: value}
+}
+
+// Ne matches records whose field differs from value
+func Ne(field string, value interface{}) Filter {
+	return Filter{Field: field, Op: OpNe, Value: value}
+}
+
+// Gt, Gte, Lt and Lte compare the field with value
+func Gt(field string, value interface{}) Filter {
+	return Filter{Field: field, Op: OpGt, Value: value}
+}
+
+func Gte(field string, value interface{}) Filter {
+	return Filter{Field: field, Op: OpGte, Value: value}
+}
+
+func Lt(field string, value interface{}) Filter {
+	return Filter{Field: field, Op: OpLt, Value: value}
+}
+
+func Lte(field string, value interface{}) Filter {
+	return Filter{Field: field, Op: OpLte, Value: value}
+}
+
+// In matches any of values, which must be a slice
+func In(field string, values interface{}) Filter {
+	return Filter{Field: field, Op: OpIn, Value: values}
+}
+
+func NotIn(field string, values interface{}) Filter {
+	return Filter{Field: field, Op: OpNotIn, Value: values}
+}
+
+// Like matches a LIKE pattern; ILike ignores case
+func Like(field string, pattern string) Filter {
+	return Filter{Field: field, Op: OpLike, Value: pattern}
+}
+
+func ILike(field string, pattern string) Filter {
+	return Filter{Field: field, Op: OpILike, Value: pattern}
+}
+
+// IsNull matches records whose field is NULL; NotNull the others
+func IsNull(field string) Filter {
+	return Filter{Field: field, Op: OpIsNull}
+}
+
+func NotNull(field string) Filter {
+	return Filter{Field: field, Op: OpNotNull}
+}
+
+// Or holds when any of filters does
+func Or(filters ...Filter) Filter {
+	return Filter{Op: OpOr, Any: filters}
+}
+
+// Order sorts by one field
+type Order struct {
+	Field string
+	Desc  bool
+}
+
+// Asc sorts by field in ascending order
+func Asc(field string) Order {
+	return Order{Field: field}
+}
+
+// Desc sorts by field in descending order
+func Desc(field string) Order {
+	return Order{Field: field, Desc: true}
+}
+
+// Spec selects, sorts and pages the records of a Repository. Its methods
+// return a new spec, so a base spec can be shared and refined:
+//
+//	spec := db.NewSpec().Where(db.Eq("user_id", userID)).OrderBy(db.Desc("created_at"))
+//	runs, err := repo.List(ctx, spec.Page(20, 0))
+type Spec struct {
+	Filters  []Filter
+	Orders   []Order
+	Preloads []string
+	Limit    int
+	Offset   int
+}
+
+// NewSpec creates a spec matching every record
+func NewSpec() Spec {
+	return Spec{}
+}
+
+// Where adds filters that must all hold
+func (s Spec) Where(filters ...Filter) Spec {
+	s.Filters = append(append([]Filter{}, s.Filters...), filters...)
+	return s
+}
+
+// OrderBy adds sort orders after the ones already set
+func (s Spec) OrderBy(orders ...Order) Spec {
+	s.Orders = append(append([]Order{}, s.Orders...), orders...)
+	return s
+}
+
+// Preload loads associations of the model, like an order's Items
+func (s Spec) Preload(associations ...string) Spec {
+	s.Preloads = append(append([]string{}, s.Preloads...), associations...)
+	return s
+}
+
+// Page limits the spec to limit records after offset. A limit of 0 means no
+// limit.
+func (s Spec) Page(limit, offset int) Spec {
+	s.Limit = limit
+	s.Offset = offset
+	return s
+}
+
+// column resolves a field to its column in the model schema
+func column(model *schema.Schema, field string) (clause.Column, error) {
+	if f := model.LookUpField(field); f != nil && f.DBName != "" {
+		return clause.Column{Table: clause.CurrentTable, Name: f.DBName}, nil
+	}
+	return clause.Column{}, fmt.Errorf("%w %q of %s", ErrUnknownField, field, model.Name)
+}
+
+// expression builds the condition of a filter
+func (f Filter) expression(model *schema.Schema) (clause.Expression, error) {
+	if f.Op == OpOr {
+		alternatives := make([]clause.Expression, 0, len(f.Any))
+		for _, filter := range f.Any {
+			expression, err := filter.expression(model)
+			if err != nil {
+				return nil, err
+			}
+			alternatives = append(alternatives, expression)
+		}
+		return clause.Or(alternatives...), nil
+	}
+
+	col, err := column(model, f.Field)
+	if err != nil {
+		return nil, err
+	}
+
+	switch f.Op {
+	case OpEq:
+		return clause.Eq{Column: col, Value: f.Value}, nil
+	case OpNe:
+		return clause.Neq{Column: col, Value: f.Value}, nil
+	case OpGt:
+		return clause.Gt{Column: col, Value: f.Value}, nil
+	case OpGte:
+		return clause.Gte{Column: col, Value: f.Value}, nil
+	case OpLt:
+		return clause.Lt{Column: col, Value: f.Value}, nil
+	case OpLte:
+		return clause.Lte{Column: col, Value: f.Value}, nil
+	case OpIn:
+		return clause.Expr{SQL: "? IN ?", Vars: []interface{}{col, f.Value}}, nil
+	case OpNotIn:
+		return clause.Expr{SQL: "? NOT IN ?", Vars: []interface{}{col, f.Value}}, nil
+	case OpLike:
+		return clause.Like{Column: col, Value: f.Value}, nil
+	case OpILike:
+		return clause.Expr{SQL: "? ILIKE ?", Vars: []interface{}{col, f.Value}}, nil
+	case OpIsNull:
+		return clause.Expr{SQL: "? IS NULL", Vars: []interface{}{col}}, nil
+	case OpNotNull:
+		return clause.Expr{SQL: "? IS NOT NULL", Vars: []interface{}{col}}, nil
+	default:
+		return nil, fmt.Errorf("unknown operator %q", f.Op)
+	}
+}
@@ -2,6 +2,35 @@ module migrate
 
 go 1.21
 
-require shared/db v0.0.0
+require (
+	order-service v0.0.0
+	product-service v0.0.0
+	shared/db v0.0.0
+	user-service v0.0.0
+)
 
-replace shared/db => ../../shared/db
\ No newline at end of file
+require (
+	github.com/jackc/pgpassfile v1.0.0 // indirect
+	github.com/jackc/pgservicefile v0.0.0-20221227161230-091c0ba34f0a // indirect
+	github.com/jackc/pgx/v5 v5.3.1 // indirect
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/lib/pq v1.10.9 // indirect
+	github.com/rogpeppe/go-internal v1.9.0 // indirect
+	golang.org/x/crypto v0.8.0 // indirect
+	golang.org/x/text v0.20.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+	gorm.io/driver/postgres v1.5.2 // indirect
+	gorm.io/gorm v1.30.3 // indirect
+	shared/apperr v0.0.0 // indirect
+)
+
+replace (
+	order-service => ../../services/order-service
+	product-service => ../../services/product-service
+	shared/apperr => ../../shared/apperr
+	shared/auth => ../../shared/auth
+	shared/db => ../../shared/db
+	user-service => ../../services/user-service
+)
@@ -1,46 +1,398 @@
 package main
 
 import (
+	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"text/tabwriter"
 
+	order "order-service/schema"
+	product "product-service/schema"
 	"shared/db"
+	"shared/db/seed"
+	user "user-service/schema"
+)
+
+// Exit codes deploy scripts can rely on
+const (
+	exitOK      = 0
+	exitFailure = 1 // connection, file or SQL error
+	exitUsage   = 2 // unknown action or invalid flags
+	exitDrift   = 3 // an applied migration was edited or its files are gone, or the schema drifted from the models
+	exitPending = 4 // status found migrations waiting to be applied
 )
 
 func main() {
 	var (
-		action = flag.String("action", "migrate", "Action to perform: migrate, check")
-		path   = flag.String("path", "../../shared/db", "Path to migrations directory")
+		action       = flag.String("action", "migrate", "Action to perform: check, migrate, status, up, down, redo, create, seed, diff")
+		path         = flag.String("path", "../../shared/db/migrations", "Path to migrations directory")
+		to           = flag.Int64("to", 0, "Last version to apply with up/migrate; 0 applies all")
+		steps        = flag.Int("steps", 1, "Number of migrations to roll back with down")
+		dryRun       = flag.Bool("dry-run", false, "Print what would run without executing it: SQL for migrations, fixture counts for seed")
+		env          = flag.String("env", getEnvOrDefault("APP_ENV", "dev"), "Fixture set to seed: dev, test or demo")
+		fixtures     = flag.String("fixtures", "../../shared/db/seeds", "Path to the seed fixtures directory")
+		generate     = flag.Int("generate", 0, "Number of synthetic products to seed on top of the fixtures")
+		generateSeed = flag.Int64("generate-seed", 1, "Random seed of the synthetic catalog")
+		service      = flag.String("service", "all", "Service whose models diff compares: product, order, user or all")
+		strict       = flag.Bool("strict", false, "Make diff exit with drift on warnings too")
 	)
-	flag.Parse()
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: migrate [-action=]<action> [flags] [name]\n\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(flag.CommandLine.Output(), "\nExit codes: 0 ok, 1 failure, 2 usage, 3 modified or missing migrations or schema drift (diff), 4 pending migrations (status)\n")
+	}
+	args := parseArgs()
+
+	// The action may also be given as the first argument: migrate up -to 5
+	if len(args) > 0 && !flagSet("action") {
+		*action = args[0]
+		args = args[1:]
+	}
+
+	migrationsPath, err := filepath.Abs(*path)
+	if err != nil {
+		fail(exitFailure, "❌ Failed to resolve migrations path: %v", err)
+	}
 
 	switch *action {
 	case "check":
 		if err := db.CheckConnection(); err != nil {
-			log.Fatalf("❌ Database connection failed: %v", err)
+			fail(exitFailure, "❌ Database connection failed: %v", err)
 		}
-		
-	case "migrate":
-		fmt.Println("🚀 Running database migrations...")
-		
-		migrationsPath, err := filepath.Abs(*path)
+
+	case "create":
+		name := strings.Join(args, "_")
+		if name == "" {
+			fail(exitUsage, "❌ Usage: migrate -action=create <name>")
+		}
+
+		upPath, downPath, err := db.CreateMigration(migrationsPath, name)
 		if err != nil {
-			log.Fatalf("❌ Failed to resolve migrations path: %v", err)
+			fail(exitFailure, "❌ %v", err)
 		}
 
-		dbURL := db.GetConnectionURL()
-		if err := db.RunMigrations(dbURL, migrationsPath); err != nil {
-			log.Fatalf("❌ Migration failed: %v", err)
+		fmt.Printf("📝 Created %s\n", upPath)
+		fmt.Printf("📝 Created %s\n", downPath)
+
+	case "status":
+		migrator, closeDB := openMigrator(migrationsPath)
+		defer closeDB()
+		os.Exit(printStatus(migrator))
+
+	case "migrate", "up":
+		migrator, closeDB := openMigrator(migrationsPath)
+		defer closeDB()
+
+		if *dryRun {
+			plan, err := migrator.PlanUp(*to)
+			check(err)
+			printPlan(plan, true)
+			return
 		}
 
+		fmt.Println("🚀 Running database migrations...")
+		applied, err := migrator.Up(*to)
+		report(applied, "⬆️  Applied")
+		check(err)
+
 		fmt.Println("✅ All migrations completed successfully!")
-		
+
+	case "down":
+		migrator, closeDB := openMigrator(migrationsPath)
+		defer closeDB()
+
+		target, err := migrator.TargetForSteps(*steps)
+		if err != nil {
+			fail(exitUsage, "❌ %v", err)
+		}
+
+		if *dryRun {
+			plan, err := migrator.PlanDown(target)
+			check(err)
+			printPlan(plan, false)
+			return
+		}
+
+		rolledBack, err := migrator.Down(target)
+		report(rolledBack, "⬇️  Rolled back")
+		check(err)
+
+		fmt.Printf("✅ Rolled back to version %d\n", target)
+
+	case "redo":
+		migrator, closeDB := openMigrator(migrationsPath)
+		defer closeDB()
+
+		target, err := migrator.TargetForSteps(1)
+		check(err)
+
+		plan, err := migrator.PlanDown(target)
+		check(err)
+		if len(plan) == 0 {
+			fail(exitFailure, "❌ No applied migration to redo")
+		}
+		version := plan[0].Version
+
+		if *dryRun {
+			printPlan(plan, false)
+			printPlan(plan, true)
+			return
+		}
+
+		rolledBack, err := migrator.Down(target)
+		report(rolledBack, "⬇️  Rolled back")
+		check(err)
+
+		applied, err := migrator.Up(version)
+		report(applied, "⬆️  Applied")
+		check(err)
+
+		fmt.Printf("✅ Redid migration %d\n", version)
+
+	case "seed":
+		catalog, err := seed.LoadFixtures(*fixtures, *env)
+		if err != nil {
+			fail(exitFailure, "❌ %v", err)
+		}
+		if *generate > 0 {
+			catalog.Merge(*seed.Generate(*generate, *generateSeed))
+		}
+
+		fmt.Printf("🌱 Seeding %s: %d departments, %d categories, %d products\n",
+			*env, len(catalog.Departments), len(catalog.Categories), len(catalog.Products))
+		if *dryRun {
+			if err := catalog.Validate(); err != nil {
+				fail(exitFailure, "❌ %v", err)
+			}
+			return
+		}
+
+		conn, err := sql.Open("postgres", db.GetConnectionURL())
+		if err != nil {
+			fail(exitFailure, "❌ Failed to connect to database: %v", err)
+		}
+		defer conn.Close()
+
+		result, err := seed.Seed(conn, catalog)
+		if err != nil {
+			fail(exitFailure, "❌ Seeding failed: %v", err)
+		}
+
+		for _, line := range []struct {
+			kind   string
+			counts seed.Counts
+		}{
+			{"departments", result.Departments},
+			{"categories", result.Categories},
+			{"products", result.Products},
+		} {
+			fmt.Printf("   %-12s %d inserted, %d updated, %d unchanged\n", line.kind, line.counts.Inserted, line.counts.Updated, line.counts.Unchanged)
+		}
+		fmt.Println("✅ Seeding completed successfully!")
+
+	case "diff":
+		models, ok := serviceModels(*service)
+		if !ok {
+			fail(exitUsage, "❌ Unknown service: %s", *service)
+		}
+
+		database, err := db.NewPostgresConnectionFromEnv()
+		if err != nil {
+			fail(exitFailure, "❌ Failed to connect to database: %v", err)
+		}
+
+		drifts, err := db.DiffSchema(database, models...)
+		if err != nil {
+			fail(exitFailure, "❌ Schema diff failed: %v", err)
+		}
+		os.Exit(printDrift(drifts, *strict))
+
 	default:
 		fmt.Printf("❌ Unknown action: %s\n", *action)
-		fmt.Println("Available actions: check, migrate")
-		os.Exit(1)
+		fmt.Println("Available actions: check, migrate, status, up, down, redo, create, seed, diff")
+		os.Exit(exitUsage)
+	}
+}
+
+func openMigrator(migrationsPath string) (*db.Migrator, func()) {
+	conn, err := sql.Open("postgres", db.GetConnectionURL())
+	if err != nil {
+		fail(exitFailure, "❌ Failed to connect to database: %v", err)
+	}
+	if err := conn.Ping(); err != nil {
+		fail(exitFailure, "❌ Failed to ping database: %v", err)
+	}
+
+	migrator, err := db.NewMigrator(conn, migrationsPath)
+	if err != nil {
+		conn.Close()
+		fail(exitFailure, "❌ %v", err)
+	}
+
+	return migrator, func() { conn.Close() }
+}
+
+// printStatus prints every migration with its state and returns the exit
+// code for it
+func printStatus(migrator *db.Migrator) int {
+	statuses, err := migrator.Status()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return exitFailure
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+
+	counts := make(map[string]int)
+	for _, status := range statuses {
+		appliedAt := "-"
+		if status.Applied != nil {
+			appliedAt = status.Applied.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, status.Name, status.State(), appliedAt)
+		counts[status.State()]++
+	}
+	w.Flush()
+
+	fmt.Printf("\n%d applied, %d pending, %d modified, %d missing\n",
+		counts["applied"], counts["pending"], counts["modified"], counts["missing"])
+
+	switch {
+	case counts["modified"] > 0 || counts["missing"] > 0:
+		return exitDrift
+	case counts["pending"] > 0:
+		return exitPending
+	default:
+		return exitOK
+	}
+}
+
+// serviceModels returns the models of a service, or of every service for "all"
+func serviceModels(service string) ([]interface{}, bool) {
+	switch service {
+	case "product":
+		return product.Models(), true
+	case "order":
+		return order.Models(), true
+	case "user":
+		return user.Models(), true
+	case "all":
+		return append(append(product.Models(), order.Models()...), user.Models()...), true
+	default:
+		return nil, false
+	}
+}
+
+// printDrift prints every difference between the models and the database
+// and returns the exit code for them. Warnings only fail in strict mode.
+func printDrift(drifts []db.SchemaDrift, strict bool) int {
+	if len(drifts) == 0 {
+		fmt.Println("✅ Database schema matches the models")
+		return exitOK
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SEVERITY\tTABLE\tCOLUMN\tDRIFT\tMODEL\tDATABASE")
+
+	counts := make(map[string]int)
+	for _, drift := range drifts {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", drift.Severity, drift.Table, orDash(drift.Column),
+			drift.Kind, orDash(drift.Expected), orDash(drift.Actual))
+		counts[drift.Severity]++
+	}
+	w.Flush()
+
+	fmt.Printf("\n%d errors, %d warnings\n", counts[db.SeverityError], counts[db.SeverityWarning])
+
+	if counts[db.SeverityError] > 0 || strict {
+		return exitDrift
+	}
+	return exitOK
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// printPlan prints the SQL of each migration in the order it would run
+func printPlan(plan []db.Migration, up bool) {
+	if len(plan) == 0 {
+		fmt.Println("-- nothing to run")
+		return
+	}
+
+	for _, migration := range plan {
+		direction, content := "up", migration.UpSQL
+		if !up {
+			direction, content = "down", migration.DownSQL
+		}
+		fmt.Printf("-- %d_%s (%s)\n%s\n", migration.Version, migration.Name, direction, strings.TrimRight(content, "\n"))
+		fmt.Println()
+	}
+}
+
+func report(migrations []db.Migration, verb string) {
+	for _, migration := range migrations {
+		fmt.Printf("%s %d_%s\n", verb, migration.Version, migration.Name)
+	}
+}
+
+// check exits when a migration command failed, with exitDrift when the
+// migrations on disk no longer match what was applied
+func check(err error) {
+	if err == nil {
+		return
+	}
+	if errors.Is(err, db.ErrChecksumMismatch) || errors.Is(err, db.ErrMissingMigration) {
+		fail(exitDrift, "❌ Migration failed: %v", err)
 	}
-}
\ No newline at end of file
+	fail(exitFailure, "❌ Migration failed: %v", err)
+}
+
+func fail(code int, format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(code)
+}
+
+// parseArgs parses flags wherever they appear and returns the other
+// arguments, so "create add_index -path dir" works like "-path dir create add_index"
+func parseArgs() []string {
+	var positional []string
+	args := os.Args[1:]
+
+	for {
+		// The default flag set exits with exitUsage on invalid flags
+		flag.CommandLine.Parse(args)
+		args = flag.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func flagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}